@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/digest"
+	"github.com/ytnobody/ghostio/internal/history"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+func runDigest(args []string) {
+	flags := newFlagSet("digest", "digest [flags] owner/repo")
+	period := flags.String("period", "week", "period to report on: day, week, or a duration such as 14d")
+	format := flags.String("format", "markdown", "output format: markdown, html or json")
+	source := flags.String("source", "api", "where events come from: api, or history for the local history store")
+	historyDir := flags.String("history-dir", history.DefaultDir(), "directory of the event history")
+	stale := flags.String("stale", "3d", "list open pull requests waiting this long for a requested review")
+	top := flags.Int("top", digest.DefaultTop, "number of top commenters to list")
+	positional := parseArgs(flags, args)
+
+	if len(positional) != 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+	repo := positional[0]
+	length, err := parsePeriod(*period)
+	if err != nil {
+		fatal(fmt.Errorf("--period: %w", err))
+	}
+	staleAge, err := parseAge(*stale)
+	if err != nil {
+		fatal(fmt.Errorf("--stale: %w", err))
+	}
+	if *format != "markdown" && *format != "html" && *format != "json" {
+		fatal(fmt.Errorf("invalid --format %q: want markdown, html or json", *format))
+	}
+
+	now := time.Now()
+	config := digest.Config{Repo: repo, From: now.Add(-length), To: now, Stale: staleAge, Top: *top}
+	events, err := activity(*source, *historyDir, repo, config.From)
+	if err != nil {
+		fatal(err)
+	}
+	var pending []digest.Pending
+	if *source == "api" {
+		pending, err = pendingFromAPI(repo)
+	} else {
+		// Review requests are known from enriched events of any age
+		var recorded []watcher.Event
+		recorded, err = searchHistory(*historyDir, history.Query{Repo: repo})
+		pending = digest.PendingFromEvents(repo, recorded)
+	}
+	if err != nil {
+		fatal(err)
+	}
+
+	report := digest.Build(config, events, pending)
+	switch *format {
+	case "markdown":
+		fmt.Print(report.Markdown())
+	case "html":
+		data, err := report.HTML()
+		if err != nil {
+			fatal(err)
+		}
+		os.Stdout.Write(data)
+	case "json":
+		data, err := report.JSON()
+		if err != nil {
+			fatal(err)
+		}
+		os.Stdout.Write(data)
+	}
+}
+
+// pendingFromAPI returns the open pull requests of repo waiting for review, each since
+// its last review request
+func pendingFromAPI(repo string) ([]digest.Pending, error) {
+	f := watcher.NewFetcher(repo)
+	prs, err := f.FetchOpenPullRequests()
+	if err != nil {
+		return nil, err
+	}
+	requested := map[int]time.Time{}
+	for _, pr := range prs {
+		if pr.Draft || len(pr.RequestedReviewers)+len(pr.RequestedTeams) == 0 {
+			continue
+		}
+		events, err := f.FetchIssueEvents(pr.Number)
+		if err != nil {
+			return nil, err
+		}
+		if at := digest.ReviewRequestedAt(events); !at.IsZero() {
+			requested[pr.Number] = at
+		}
+	}
+	return digest.PendingFromAPI(prs, requested), nil
+}
+
+// activity returns the events of repo since a time from the API or, with source "history",
+// from the history
+func activity(source, historyDir, repo string, since time.Time) ([]watcher.Event, error) {
+	switch source {
+	case "api":
+		return watcher.NewFetcher(repo).FetchEvents(since)
+	case "history":
+		return searchHistory(historyDir, history.Query{Repo: repo, Since: since})
+	default:
+		return nil, fmt.Errorf("invalid --source %q: want api or history", source)
+	}
+}
+
+// searchHistory returns the recorded events matching q
+func searchHistory(dir string, q history.Query) ([]watcher.Event, error) {
+	store, err := history.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	results, err := store.Search(q)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]watcher.Event, len(results))
+	for i, r := range results {
+		events[i] = r.Event
+	}
+	return events, nil
+}
+
+// parsePeriod parses a report period: day, week, or a duration accepted by parseAge
+func parsePeriod(s string) (time.Duration, error) {
+	switch s {
+	case "day":
+		return 24 * time.Hour, nil
+	case "week":
+		return 7 * 24 * time.Hour, nil
+	}
+	d, err := parseAge(s)
+	if err == nil && d == 0 {
+		err = fmt.Errorf("invalid period %q", s)
+	}
+	return d, err
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/control"
+	"github.com/ytnobody/ghostio/internal/feed"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+func runFeed(args []string) {
+	flags := newFlagSet("feed", "feed (--http addr | --out dir) [flags] owner/repo...")
+	addr := flags.String("http", "", "address to serve feeds on")
+	outDir := flags.String("out", "", "directory to write feed files to")
+	retention := flags.Duration("retention", feed.DefaultRetention, "how long events stay in the feeds")
+	maxEntries := flags.Int("max-entries", feed.DefaultMaxEntries, "maximum entries per feed")
+	var sinkOpts sinkOptions
+	sinkOpts.register(flags)
+	controlPath := flags.String("control", control.DefaultSocketPath(), "path of the control socket")
+	metricsAddr := flags.String("metrics", "", "address to also serve Prometheus metrics and health checks on")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 || (*addr == "" && *outDir == "") {
+		flags.Usage()
+		os.Exit(1)
+	}
+	repos := flags.Args()
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	// Setup metrics and health checks
+	inst := newInstrumentation()
+	pollers := newPollGroup(repos, watcher.PollerConfig{EmitInitial: true, Metrics: inst.poller})
+	serveOps(ctx, *metricsAddr, inst.registry, pollers)
+
+	// Setup sinks; the store backs the feeds
+	store := feed.NewStore(*retention, *maxEntries)
+	if *outDir != "" {
+		write := func(repos ...string) {
+			if err := feed.WriteFiles(*outDir, store, repos); err != nil {
+				log.Printf("feed error: %v", err)
+			}
+		}
+		write(repos...)
+		// Only the changed repository's feeds and the combined ones are rewritten
+		store.OnChange(func(repo string) { write(repo) })
+	}
+	sinkOpts.repos = repos
+	out, err := sinkOpts.build(ctx, "feed", store, inst)
+	if err != nil {
+		fatal(err)
+	}
+	defer out.close()
+	out.stop = cancel
+	sinkOpts.reloadOnHangup(ctx, out)
+
+	if ctl := startControl(*controlPath, out, pollers); ctl != nil {
+		defer ctl.Close()
+	}
+
+	// Setup HTTP server
+	var server *http.Server
+	serveErr := make(chan error, 1)
+	if *addr != "" {
+		mux := http.NewServeMux()
+		feed.NewHandler(store).Register(mux)
+		registerOps(mux, inst.registry, pollers)
+		server = &http.Server{Addr: *addr, Handler: mux}
+		go func() {
+			if err := server.ListenAndServe(); err != http.ErrServerClosed {
+				serveErr <- err
+				cancel()
+			}
+		}()
+		fmt.Fprintf(os.Stderr, "Serving feeds for %d repositories on %s\n", len(repos), *addr)
+	}
+	if *outDir != "" {
+		fmt.Fprintf(os.Stderr, "Writing feeds for %d repositories to %s\n", len(repos), *outDir)
+	}
+
+	// The first page of events seeds the feeds
+	pollErr := pollers.run(ctx, out.sinks, out.enrich)
+	out.close()
+	out.reportSuppressed()
+
+	if server != nil {
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer shutdownCancel()
+		server.Shutdown(shutdownCtx)
+	}
+
+	out.exit()
+
+	select {
+	case err := <-serveErr:
+		fatal(err)
+	default:
+	}
+	if pollErr != nil {
+		fatal(pollErr)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/filter"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+func runLog(args []string) {
+	flags := newFlagSet("log", "log [flags] owner/repo")
+	since := flags.String("since", "24h", "show events this recent, e.g. 24h, 7d or 2w")
+	limit := flags.Int("limit", 0, "show only the most recent events (0 shows all)")
+	types := flags.String("type", "", "only events of these comma-separated types, e.g. PushEvent (default issue, pull request, comment and release events)")
+	var sinkOpts sinkOptions
+	sinkOpts.register(flags)
+	bodyLines := flags.Int("body-lines", watcher.DefaultBodyLines, "truncate bodies on a terminal to this many lines (0 shows all)")
+	groupBy := flags.String("group-by", "", "group output: thread shows a header per issue or pull request and indents comments")
+	// A log shows every event on its own unless asked to merge them
+	flags.Lookup("coalesce").DefValue = "0s"
+	flags.Set("coalesce", "0s")
+	positional := parseArgs(flags, args)
+
+	if len(positional) != 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+	// A log exits once the events are printed, so sinks that deliver later, and state kept
+	// for later runs, cannot work
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "smtp", "history-dir", "sla-dir":
+			fatal(fmt.Errorf("--%s cannot be used with log", f.Name))
+		}
+	})
+	repo := positional[0]
+	age, err := parseAge(*since)
+	if err != nil {
+		fatal(fmt.Errorf("--since: %w", err))
+	}
+	var wanted []string
+	for _, t := range strings.Split(*types, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			wanted = append(wanted, t)
+		}
+	}
+	stdout, err := newStdout(*groupBy, *bodyLines)
+	if err != nil {
+		fatal(err)
+	}
+
+	// Fetch the window
+	fetcher := watcher.NewFetcher(repo)
+	var events []watcher.Event
+	if len(wanted) == 0 {
+		events, err = fetcher.FetchTargetEvents(time.Now().Add(-age))
+	} else {
+		events, err = fetcher.FetchEvents(time.Now().Add(-age))
+		events = slices.DeleteFunc(events, func(e watcher.Event) bool { return !slices.Contains(wanted, e.Type) })
+		if sinkOpts.filter == "" {
+			// The default filter would hide the types asked for
+			sinkOpts.filter = filter.Types(wanted...).String()
+		}
+	}
+	if err != nil {
+		fatal(err)
+	}
+	if *limit > 0 && len(events) > *limit {
+		events = events[len(events)-*limit:]
+	}
+
+	// Past events are already in the history, and do not start SLA clocks
+	sinkOpts.noHistory = true
+	sinkOpts.noSLA = true
+	ctx, cancel := signalContext()
+	defer cancel()
+	out, err := sinkOpts.build(ctx, "stdout", stdout, newInstrumentation())
+	if err != nil {
+		fatal(err)
+	}
+	out.stop = cancel
+
+	in := make(chan watcher.Event)
+	delivered := (<-chan watcher.Event)(in)
+	if out.enrich != nil {
+		enriched := make(chan watcher.Event)
+		go out.enrich.Run(in, enriched)
+		delivered = enriched
+	}
+	go func() {
+		defer close(in)
+		for _, e := range events {
+			select {
+			case in <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for e := range delivered {
+		if err := out.sinks.Send(e); err != nil {
+			log.Printf("sink error: %v", err)
+		}
+	}
+
+	out.exit()
+	if err := out.sinks.Close(); err != nil {
+		fatal(err)
+	}
+	out.reportSuppressed()
+}
@@ -2,59 +2,430 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"log"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
+	"text/tabwriter"
+	"time"
 
+	"github.com/ytnobody/ghostio/internal/control"
+	"github.com/ytnobody/ghostio/internal/enrich"
+	"github.com/ytnobody/ghostio/internal/noise"
+	"github.com/ytnobody/ghostio/internal/sink"
 	"github.com/ytnobody/ghostio/internal/watcher"
 )
 
+const usage = `Usage:
+  ghostio watch [flags] owner/repo
+  ghostio serve --http addr [flags] owner/repo...
+  ghostio feed (--http addr | --out dir) [flags] owner/repo...
+  ghostio send-now [--control path]
+  ghostio status [--control path]
+  ghostio thread owner/repo#number
+  ghostio log [flags] owner/repo
+  ghostio search [flags] query
+  ghostio digest [flags] owner/repo
+  ghostio stats [flags] owner/repo
+  ghostio record --out file [flags] owner/repo...
+  ghostio replay [flags] file
+  ghostio tui [flags] owner/repo...`
+
 func main() {
-	if len(os.Args) < 3 || os.Args[1] != "watch" {
-		fmt.Fprintln(os.Stderr, "Usage: ghostio watch owner/repo")
+	// Warnings that come up while running are logged without timestamps, to stderr or the TUI
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	switch os.Args[1] {
+	case "watch":
+		runWatch(os.Args[2:])
+	case "serve":
+		runServe(os.Args[2:])
+	case "feed":
+		runFeed(os.Args[2:])
+	case "send-now":
+		runSendNow(os.Args[2:])
+	case "status":
+		runStatus(os.Args[2:])
+	case "thread":
+		runThread(os.Args[2:])
+	case "tui":
+		runTUI(os.Args[2:])
+	case "log":
+		runLog(os.Args[2:])
+	case "search":
+		runSearch(os.Args[2:])
+	case "digest":
+		runDigest(os.Args[2:])
+	case "stats":
+		runStats(os.Args[2:])
+	case "record":
+		runRecord(os.Args[2:])
+	case "replay":
+		runReplay(os.Args[2:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(1)
+	}
+}
+
+// fatal prints an error and exits
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	os.Exit(1)
+}
+
+// newFlagSet creates a flag set whose usage prints the command synopsis
+func newFlagSet(name, synopsis string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: ghostio "+synopsis)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseArgs parses flags given before, between or after the positional arguments and
+// returns the positional ones, so that "search 'login bug' --since 7d" works
+func parseArgs(flags *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		flags.Parse(args)
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// parseAge parses a duration that may also be given in days or weeks, such as "7d" or "2w"
+func parseAge(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			if v, err := strconv.Atoi(n); err == nil && v >= 0 {
+				return time.Duration(v) * unit, nil
+			}
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q: want e.g. 90m, 24h, 7d or 2w", s)
+	}
+	return d, nil
+}
+
+func runWatch(args []string) {
+	flags := newFlagSet("watch", "watch [flags] owner/repo")
+	var sinkOpts sinkOptions
+	sinkOpts.register(flags)
+	controlPath := flags.String("control", control.DefaultSocketPath(), "path of the control socket")
+	metricsAddr := flags.String("metrics", "", "address to serve Prometheus metrics and health checks on (e.g. :9100)")
+	bodyLines := flags.Int("body-lines", watcher.DefaultBodyLines, "truncate bodies on a terminal to this many lines (0 shows all)")
+	groupBy := flags.String("group-by", "", "group output: thread shows a header per issue or pull request and indents comments")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
 		os.Exit(1)
 	}
 
-	repo := os.Args[2]
+	stdout, err := newStdout(*groupBy, *bodyLines)
+	if err != nil {
+		fatal(err)
+	}
+
+	repo := flags.Arg(0)
 	fmt.Fprintf(os.Stderr, "Watching %s...\n", repo)
 
-	// Setup context with signal handling for graceful shutdown
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := signalContext()
 	defer cancel()
 
+	// Setup metrics and health checks
+	inst := newInstrumentation()
+	pollers := newPollGroup([]string{repo}, watcher.PollerConfig{Metrics: inst.poller})
+	serveOps(ctx, *metricsAddr, inst.registry, pollers)
+
+	// Setup sinks
+	sinkOpts.repos = []string{repo}
+	out, err := sinkOpts.build(ctx, "stdout", stdout, inst)
+	if err != nil {
+		fatal(err)
+	}
+	defer out.close()
+	out.stop = cancel
+	sinkOpts.reloadOnHangup(ctx, out)
+
+	// Setup control socket
+	if ctl := startControl(*controlPath, out, pollers); ctl != nil {
+		defer ctl.Close()
+	}
+
+	// Start polling
+	err = pollers.run(ctx, out.sinks, out.enrich)
+	out.close()
+	out.reportSuppressed()
+	out.exit()
+	if err != nil {
+		fatal(err)
+	}
+}
+
+// newStdout creates the terminal sink selected by --group-by, truncating bodies to bodyLines
+func newStdout(groupBy string, bodyLines int) (sink.Sink, error) {
+	switch groupBy {
+	case "":
+		w := sink.NewWriter(os.Stdout)
+		w.BodyLines = bodyLines
+		return w, nil
+	case "thread":
+		w := sink.NewThreadWriter(os.Stdout)
+		w.BodyLines = bodyLines
+		return w, nil
+	default:
+		return nil, fmt.Errorf("invalid --group-by %q: want thread", groupBy)
+	}
+}
+
+// signalContext returns a context that is canceled on SIGINT or SIGTERM
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
-		<-sigCh
-		fmt.Fprintln(os.Stderr, "\nShutting down...")
-		cancel()
+		select {
+		case <-sigCh:
+			fmt.Fprintln(os.Stderr, "\nShutting down...")
+			cancel()
+		case <-ctx.Done():
+		}
 	}()
+	return ctx, cancel
+}
+
+// statusResult is the reply to the status control command
+type statusResult struct {
+	Targets []watcher.PollerStatus `json:"targets"`
+	// Suppressed counts the events dropped as noise by reason
+	Suppressed map[string]int `json:"suppressed,omitempty"`
+}
 
-	// Setup poller
-	poller := watcher.NewPoller(watcher.PollerConfig{
-		Repo: repo,
+// startControl serves control commands for a running process; failures only disable the socket
+func startControl(path string, out *outputs, pollers *pollGroup) *control.Server {
+	ctl, err := control.Listen(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: control socket disabled: %v\n", err)
+		return nil
+	}
+	ctl.Handle("status", func() (any, error) {
+		result := statusResult{Targets: pollers.status()}
+		if out.quiet != nil {
+			result.Suppressed = out.quiet.Counts()
+		}
+		return result, nil
+	})
+	ctl.Handle("send-now", func() (any, error) {
+		if out.email == nil {
+			return nil, fmt.Errorf("email digest is not enabled (use --smtp)")
+		}
+		n, err := out.email.SendNow()
+		return map[string]int{"sent": n}, err
 	})
+	return ctl
+}
+
+// eventQueueSize is the number of polled events buffered ahead of the sinks
+const eventQueueSize = 100
+
+// pollGroup polls several repositories into one event queue
+type pollGroup struct {
+	pollers []*watcher.Poller
+	metrics *watcher.PollerMetrics
+}
+
+// newPollGroup creates one poller per repository with config
+func newPollGroup(repos []string, config watcher.PollerConfig) *pollGroup {
+	g := &pollGroup{metrics: config.Metrics}
+	for _, repo := range repos {
+		config.Repo = repo
+		g.pollers = append(g.pollers, watcher.NewPoller(config))
+	}
+	return g
+}
+
+// status returns the state of every poller
+func (g *pollGroup) status() []watcher.PollerStatus {
+	statuses := make([]watcher.PollerStatus, len(g.pollers))
+	for i, p := range g.pollers {
+		statuses[i] = p.Status()
+	}
+	return statuses
+}
+
+// run polls every repository until ctx is canceled and delivers every event to sinks, which do their own filtering.
+// Events pass through the enricher first when it is not nil.
+// It returns nil on cancellation and the first polling error otherwise.
+func (g *pollGroup) run(ctx context.Context, sinks sink.Sink, enricher *enrich.Enricher) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
 	// Event channel
-	eventCh := make(chan watcher.Event)
+	eventCh := make(chan watcher.Event, eventQueueSize)
+	g.metrics.TrackQueue(func() int { return len(eventCh) })
+	delivered := (<-chan watcher.Event)(eventCh)
+	if enricher != nil {
+		enriched := make(chan watcher.Event)
+		go enricher.Run(eventCh, enriched)
+		delivered = enriched
+	}
 
 	// Start event consumer
+	consumed := make(chan struct{})
 	go func() {
-		for event := range eventCh {
-			if watcher.IsTargetEvent(event.Type) {
-				fmt.Println(watcher.FormatEvent(event))
-				fmt.Println("---")
+		defer close(consumed)
+		for event := range delivered {
+			if err := sinks.Send(event); err != nil {
+				log.Printf("sink error: %v", err)
 			}
 		}
 	}()
 
-	// Start polling
-	if err := poller.Start(ctx, eventCh); err != nil {
-		if err == context.Canceled {
-			return
+	// Start the pollers
+	errCh := make(chan error, len(g.pollers))
+	var wg sync.WaitGroup
+	for _, poller := range g.pollers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := poller.Start(ctx, eventCh); err != nil && err != context.Canceled {
+				errCh <- fmt.Errorf("%s: %w", poller.Status().Repo, err)
+				cancel()
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(eventCh)
+	<-consumed
+
+	select {
+	case err := <-errCh:
+		return err
+	default:
+		return nil
+	}
+}
+
+func runSendNow(args []string) {
+	flags := newFlagSet("send-now", "send-now [--control path]")
+	controlPath := flags.String("control", control.DefaultSocketPath(), "path of the control socket")
+	flags.Parse(args)
+
+	var result struct {
+		Sent int `json:"sent"`
+	}
+	if err := control.Call(*controlPath, "send-now", &result); err != nil {
+		fatal(err)
+	}
+	if result.Sent == 0 {
+		fmt.Println("No buffered events; nothing sent")
+		return
+	}
+	fmt.Printf("Sent digest with %d events\n", result.Sent)
+}
+
+func runStatus(args []string) {
+	flags := newFlagSet("status", "status [--control path]")
+	controlPath := flags.String("control", control.DefaultSocketPath(), "path of the control socket")
+	flags.Parse(args)
+
+	var result statusResult
+	if err := control.Call(*controlPath, "status", &result); err != nil {
+		fatal(err)
+	}
+	statuses := result.Targets
+	if len(statuses) == 0 {
+		fmt.Println("No targets are being watched")
+		return
+	}
+
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TARGET\tLAST POLL\tETAG\tLAST EVENT\tERRORS\tNEXT POLL")
+	for _, s := range statuses {
+		etag, lastEvent := s.ETag, s.LastEventID
+		if etag == "" {
+			etag = "-"
 		}
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if lastEvent == "" {
+			lastEvent = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", s.Repo, ago(now, s.LastPoll), etag, lastEvent, s.ErrorStreak, until(now, s.NextPoll))
+	}
+	w.Flush()
+
+	for _, s := range statuses {
+		if s.LastError != "" {
+			fmt.Printf("\n%s: %s\n", s.Repo, strings.TrimSpace(s.LastError))
+		}
+	}
+	if summary := noise.Summary(result.Suppressed); summary != "" {
+		fmt.Printf("\nSuppressed: %s\n", summary)
+	}
+}
+
+func runThread(args []string) {
+	flags := newFlagSet("thread", "thread owner/repo#number")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
 		os.Exit(1)
 	}
+	repo, number, err := watcher.ParseThreadRef(flags.Arg(0))
+	if err != nil {
+		fatal(err)
+	}
+
+	events, err := watcher.FetchThread(repo, number)
+	if err != nil {
+		fatal(err)
+	}
+	// A single conversation is read in full
+	w := sink.NewThreadWriter(os.Stdout)
+	w.BodyLines = 0
+	for _, e := range events {
+		if err := w.Send(e); err != nil {
+			fatal(err)
+		}
+	}
+}
+
+// ago describes a past time relative to now
+func ago(now, t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return now.Sub(t).Round(time.Second).String() + " ago"
+}
+
+// until describes a future time relative to now
+func until(now, t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	if d := t.Sub(now); d > 0 {
+		return "in " + d.Round(time.Second).String()
+	}
+	return "due"
 }
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/health"
+	"github.com/ytnobody/ghostio/internal/metrics"
+	"github.com/ytnobody/ghostio/internal/noise"
+	"github.com/ytnobody/ghostio/internal/sink"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// instrumentation is the metrics registry shared by the pollers and sinks of a command
+type instrumentation struct {
+	registry *metrics.Registry
+	poller   *watcher.PollerMetrics
+	sinks    *sink.Metrics
+	noise    *noise.Metrics
+}
+
+func newInstrumentation() *instrumentation {
+	reg := metrics.NewRegistry()
+	return &instrumentation{
+		registry: reg,
+		poller:   watcher.NewPollerMetrics(reg),
+		sinks:    sink.NewMetrics(reg),
+		noise:    noise.NewMetrics(reg),
+	}
+}
+
+// registerOps adds /metrics, /healthz and /readyz to mux
+func registerOps(mux *http.ServeMux, reg *metrics.Registry, pollers *pollGroup) {
+	mux.Handle("/metrics", reg)
+	health.NewChecker(pollers.status).Register(mux)
+}
+
+// serveOps serves the metrics and health endpoints on addr until ctx is canceled; failures only disable them
+func serveOps(ctx context.Context, addr string, reg *metrics.Registry, pollers *pollGroup) {
+	if addr == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	registerOps(mux, reg, pollers)
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			log.Printf("Warning: metrics endpoint disabled: %v", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/enrich"
+	"github.com/ytnobody/ghostio/internal/replay"
+	"github.com/ytnobody/ghostio/internal/sink"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+func runRecord(args []string) {
+	flags := newFlagSet("record", "record --out file [flags] owner/repo...")
+	outPath := flags.String("out", "", "file to write the recorded responses to")
+	interval := flags.Duration("interval", watcher.DefaultInterval, "polling interval")
+	noEnrich := flags.Bool("no-enrich", false, "do not record issue and pull request details")
+	repos := parseArgs(flags, args)
+
+	if len(repos) == 0 || *outPath == "" {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	f, err := os.Create(*outPath)
+	if err != nil {
+		fatal(err)
+	}
+	defer f.Close()
+	recorder := replay.NewRecorder(f)
+	transport := recorder.Transport(watcher.GH)
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	// The first page is delivered so that its issues and pull requests are recorded too
+	pollers := newPollGroup(repos, watcher.PollerConfig{Interval: *interval, EmitInitial: true, Transport: transport})
+	var enricher *enrich.Enricher
+	if !*noEnrich {
+		enricher = enrich.New(enrich.Config{Fetch: transport.Fetch})
+	}
+	fmt.Fprintf(os.Stderr, "Recording %s to %s...\n", strings.Join(repos, ", "), *outPath)
+	start := time.Now()
+	pollErr := pollers.run(ctx, sink.NewWriter(os.Stdout), enricher)
+
+	fmt.Fprintf(os.Stderr, "Recorded %d responses in %s\n", recorder.Count(), time.Since(start).Round(time.Second))
+	if err := recorder.Err(); err != nil {
+		fatal(err)
+	}
+	if pollErr != nil {
+		fatal(pollErr)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/replay"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+func runReplay(args []string) {
+	flags := newFlagSet("replay", "replay [flags] file")
+	speed := flags.String("speed", "1x", "playback speed relative to the recording, e.g. 10x, or instant")
+	initial := flags.Bool("initial", true, "deliver the events on the first recorded page of each repository")
+	var sinkOpts sinkOptions
+	sinkOpts.register(flags)
+	bodyLines := flags.Int("body-lines", watcher.DefaultBodyLines, "truncate bodies on a terminal to this many lines (0 shows all)")
+	groupBy := flags.String("group-by", "", "group output: thread shows a header per issue or pull request and indents comments")
+	positional := parseArgs(flags, args)
+
+	if len(positional) != 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+	factor, err := replay.ParseSpeed(*speed)
+	if err != nil {
+		fatal(fmt.Errorf("--speed: %w", err))
+	}
+	f, err := os.Open(positional[0])
+	if err != nil {
+		fatal(err)
+	}
+	exchanges, err := replay.Load(f)
+	f.Close()
+	if err != nil {
+		fatal(err)
+	}
+	stdout, err := newStdout(*groupBy, *bodyLines)
+	if err != nil {
+		fatal(err)
+	}
+
+	ctx, cancel := signalContext()
+	defer cancel()
+	player, err := replay.New(ctx, exchanges, factor)
+	if err != nil {
+		fatal(err)
+	}
+
+	// Replayed events are not new activity, and their details come from the recording.
+	// Recorded time does not pass for the enricher's cache, so every event revalidates.
+	sinkOpts.noHistory = true
+	sinkOpts.noSLA = true
+	sinkOpts.transport = player.Transport
+	sinkOpts.enrichTTL = time.Nanosecond
+	// Recorded details are replayed as they were shown
+	if player.HasResources() {
+		sinkOpts.enrich = true
+	} else {
+		sinkOpts.noEnrich = true
+	}
+
+	inst := newInstrumentation()
+	pollers := newPollGroup(player.Repos(), watcher.PollerConfig{
+		Interval:    time.Millisecond,
+		EmitInitial: *initial,
+		Metrics:     inst.poller,
+		Transport:   player.Transport,
+	})
+	out, err := sinkOpts.build(ctx, "stdout", stdout, inst)
+	if err != nil {
+		fatal(err)
+	}
+	defer out.close()
+	out.stop = cancel
+
+	// Stop once the pollers have handed on the last recorded page
+	go func() {
+		select {
+		case <-player.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	fmt.Fprintf(os.Stderr, "Replaying %s at %s...\n", strings.Join(player.Repos(), ", "), *speed)
+	err = pollers.run(ctx, out.sinks, out.enrich)
+	out.close()
+	out.reportSuppressed()
+	out.exit()
+	if err != nil {
+		fatal(err)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/history"
+	"github.com/ytnobody/ghostio/internal/term"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// ANSI escapes for search results
+const (
+	searchHighlight = "\x1b[1;7m"
+	searchDim       = "\x1b[2m"
+	searchReset     = "\x1b[0m"
+)
+
+func runSearch(args []string) {
+	flags := newFlagSet("search", "search [flags] query")
+	dir := flags.String("history-dir", history.DefaultDir(), "directory of the event history")
+	repo := flags.String("repo", "", "only events of this repository (owner/repo)")
+	types := flags.String("type", "", "only events of these comma-separated types, e.g. IssueCommentEvent")
+	since := flags.String("since", "", "only events this recent, e.g. 24h or 7d")
+	limit := flags.Int("limit", 20, "maximum number of results (0 shows all)")
+	positional := parseArgs(flags, args)
+
+	if len(positional) == 0 && *repo == "" && *types == "" && *since == "" {
+		flags.Usage()
+		os.Exit(1)
+	}
+	q := history.Query{Text: strings.Join(positional, " "), Repo: *repo, Limit: *limit}
+	for _, t := range strings.Split(*types, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			q.Types = append(q.Types, t)
+		}
+	}
+	if *since != "" {
+		age, err := parseAge(*since)
+		if err != nil {
+			fatal(fmt.Errorf("--since: %w", err))
+		}
+		q.Since = time.Now().Add(-age)
+	}
+
+	store, err := history.Open(*dir)
+	if err != nil {
+		fatal(err)
+	}
+	defer store.Close()
+	results, err := store.Search(q)
+	if err != nil {
+		fatal(err)
+	}
+	if len(results) == 0 {
+		fmt.Fprintf(os.Stderr, "No matches among %d events\n", store.Len())
+		return
+	}
+
+	color := os.Getenv("NO_COLOR") == "" && term.IsTerminal(os.Stdout.Fd())
+	var mark func(string) string
+	if color {
+		mark = func(s string) string { return searchHighlight + s + searchReset }
+	}
+	for i, r := range results {
+		if i > 0 {
+			fmt.Println()
+		}
+		printResult(r.Event, q.Text, mark, color)
+	}
+}
+
+// printResult prints the headline and URL of a matching event and the part of its body
+// with the most matches, highlighting the query terms when mark is set
+func printResult(e watcher.Event, query string, mark func(string) string, color bool) {
+	headline, rest, _ := strings.Cut(watcher.FormatEvent(e), "\n")
+	url, _, _ := strings.Cut(rest, "\n")
+	if mark != nil {
+		headline = history.Highlight(headline, query, mark)
+	}
+	fmt.Println(headline)
+	if url != "" {
+		if color {
+			url = searchDim + url + searchReset
+		}
+		fmt.Println(url)
+	}
+	if snippet := history.Snippet(e, query, mark); snippet != "" {
+		fmt.Println("    " + snippet)
+	}
+}
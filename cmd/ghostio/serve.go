@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/control"
+	"github.com/ytnobody/ghostio/internal/stream"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+func runServe(args []string) {
+	flags := newFlagSet("serve", "serve --http addr [flags] owner/repo...")
+	addr := flags.String("http", ":9000", "address to serve the event stream on")
+	buffer := flags.Int("buffer", stream.DefaultBufferSize, "number of recent events kept for resuming clients")
+	heartbeat := flags.Duration("heartbeat", stream.DefaultHeartbeat, "interval between keep-alive messages")
+	var sinkOpts sinkOptions
+	sinkOpts.register(flags)
+	controlPath := flags.String("control", control.DefaultSocketPath(), "path of the control socket")
+	metricsAddr := flags.String("metrics", "", "address to also serve Prometheus metrics and health checks on")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+	repos := flags.Args()
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	// Setup metrics and health checks
+	inst := newInstrumentation()
+	pollers := newPollGroup(repos, watcher.PollerConfig{Metrics: inst.poller})
+	serveOps(ctx, *metricsAddr, inst.registry, pollers)
+
+	// Setup sinks; the hub feeds connected clients
+	hub := stream.NewHub(*buffer)
+	sinkOpts.repos = repos
+	out, err := sinkOpts.build(ctx, "stream", hub, inst)
+	if err != nil {
+		fatal(err)
+	}
+	defer out.close()
+	out.stop = cancel
+	sinkOpts.reloadOnHangup(ctx, out)
+
+	if ctl := startControl(*controlPath, out, pollers); ctl != nil {
+		defer ctl.Close()
+	}
+
+	// Setup HTTP server
+	mux := http.NewServeMux()
+	mux.Handle("/events", stream.NewHandler(hub, *heartbeat))
+	registerOps(mux, inst.registry, pollers)
+	server := &http.Server{Addr: *addr, Handler: mux}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			serveErr <- err
+			cancel()
+		}
+	}()
+	fmt.Fprintf(os.Stderr, "Serving events for %d repositories on %s\n", len(repos), *addr)
+
+	pollErr := pollers.run(ctx, out.sinks, out.enrich)
+	out.close()
+	out.reportSuppressed()
+
+	// Disconnect streaming clients before shutting the server down
+	hub.Close()
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	server.Shutdown(shutdownCtx)
+
+	out.exit()
+
+	select {
+	case err := <-serveErr:
+		fatal(err)
+	default:
+	}
+	if pollErr != nil {
+		fatal(pollErr)
+	}
+}
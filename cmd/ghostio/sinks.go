@@ -0,0 +1,591 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/coalesce"
+	"github.com/ytnobody/ghostio/internal/config"
+	"github.com/ytnobody/ghostio/internal/cron"
+	"github.com/ytnobody/ghostio/internal/enrich"
+	"github.com/ytnobody/ghostio/internal/filter"
+	"github.com/ytnobody/ghostio/internal/history"
+	"github.com/ytnobody/ghostio/internal/noise"
+	"github.com/ytnobody/ghostio/internal/rules"
+	"github.com/ytnobody/ghostio/internal/sink"
+	"github.com/ytnobody/ghostio/internal/sla"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// sinkOptions holds the command line flags that select output sinks and the events they receive
+type sinkOptions struct {
+	configPath  string
+	filter      string
+	sinkFilters sinkFilterFlag
+
+	noQuiet           bool
+	quietActors       string
+	ciActors          string
+	selfCommentWindow time.Duration
+	coalesce          time.Duration
+
+	// enrich fetches details even when no filter, rule or SLA refers to them
+	enrich            bool
+	noEnrich          bool
+	enrichTTL         time.Duration
+	enrichConcurrency int
+	// transport serves the enricher's and the SLA watchdog's requests; nil uses gh
+	transport watcher.Transport
+
+	notify   bool
+	syslog   string
+	journald bool
+
+	noHistory  bool
+	historyDir string
+
+	// noSLA leaves SLA clocks alone, for commands that show past events
+	noSLA  bool
+	slaDir string
+	// repos are the repositories the command polls, whose SLA clocks it keeps
+	repos []string
+
+	fileDir     string
+	fileRotate  string
+	fileMaxSize string
+	fileKeep    int
+	fileSync    time.Duration
+
+	smtpAddr     string
+	smtpFrom     string
+	smtpTo       string
+	smtpUser     string
+	smtpSchedule string
+	smtpStartTLS bool
+}
+
+// sinkNames are the sinks that can be given their own filter
+var sinkNames = []string{"stdout", "tui", "stream", "feed", "notify", "syslog", "journald", "file", "email", "history"}
+
+// sinkFilterFlag collects repeated --sink-filter name=expr flags
+type sinkFilterFlag map[string]string
+
+func (f sinkFilterFlag) String() string { return "" }
+
+func (f sinkFilterFlag) Set(value string) error {
+	name, expr, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("want name=expression, e.g. notify='merged'")
+	}
+	if !slices.Contains(sinkNames, name) {
+		return fmt.Errorf("unknown sink %q (have %s)", name, strings.Join(sinkNames, ", "))
+	}
+	f[name] = expr
+	return nil
+}
+
+// outputs are the sinks built from the command line
+type outputs struct {
+	sinks sink.Sink
+	// quiet is the noise suppressor, nil with --no-quiet
+	quiet *noise.Suppressor
+	// rules attaches alerts; its rules are replaced on reload
+	rules *rules.Sink
+	// sla raises alerts for missed SLA deadlines; its SLAs are replaced on reload.
+	// It is nil for commands that show past events.
+	sla *sla.Watchdog
+	// enrich attaches issue and pull request details between the pollers and the sinks,
+	// nil with --no-enrich. It only fetches while something uses the details.
+	enrich *enrich.Enricher
+	// filterDetails records whether the filters refer to enriched fields; they are not reloaded
+	filterDetails bool
+	// stop is called when a rule asks to exit; commands set it to stop polling
+	stop func()
+
+	mu        sync.Mutex
+	exitCode  *int
+	closeOnce sync.Once
+	// email is the digest sink, kept for the send-now command
+	email *sink.Email
+	// built names the sinks that were built, which rules and SLAs can route to
+	built []string
+}
+
+// register adds the sink flags to a flag set
+func (o *sinkOptions) register(flags *flag.FlagSet) {
+	o.sinkFilters = sinkFilterFlag{}
+	flags.StringVar(&o.configPath, "config", "", "configuration file (default "+config.DefaultPath()+")")
+	flags.StringVar(&o.filter, "filter", "", "only deliver events matching this expression, e.g. 'type == \"PullRequestEvent\" && !actor.endsWith(\"[bot]\")'")
+	flags.Var(o.sinkFilters, "sink-filter", "only deliver events matching an expression to one sink, as name=expression (repeatable)")
+	flags.BoolVar(&o.noQuiet, "no-quiet", false, "deliver bot activity, label churn, comment edits and self-comments too")
+	flags.StringVar(&o.quietActors, "quiet-actors", "", "comma-separated extra logins to suppress; * matches any text (e.g. renovate*)")
+	flags.StringVar(&o.ciActors, "ci-actors", "", "comma-separated CI bot logins whose comments are delivered once and edits dropped (default github-actions[bot])")
+	flags.DurationVar(&o.selfCommentWindow, "self-comment-window", noise.DefaultSelfCommentWindow, "drop comments by a pull request's author this soon after opening it")
+	flags.DurationVar(&o.coalesce, "coalesce", coalesce.DefaultWindow, "hold events this long and merge related ones into one summary (0 disables)")
+	flags.BoolVar(&o.enrich, "enrich", false, "fetch issue and pull request details for every event, to show and record them, even when no filter, rule or SLA uses them")
+	flags.BoolVar(&o.noEnrich, "no-enrich", false, "do not fetch labels, assignees, milestones, reviewers and diff stats of issues and pull requests")
+	flags.DurationVar(&o.enrichTTL, "enrich-ttl", enrich.DefaultTTL, "reuse fetched issue and pull request details this long")
+	flags.IntVar(&o.enrichConcurrency, "enrich-concurrency", enrich.DefaultConcurrency, "maximum simultaneous requests for issue and pull request details")
+	flags.BoolVar(&o.notify, "notify", false, "send events as desktop notifications")
+	flags.StringVar(&o.syslog, "syslog", "", "send events to syslog (unix:///dev/log, udp://host:514, tcp://host:601)")
+	flags.BoolVar(&o.journald, "journald", false, "send events to the systemd journal")
+	flags.BoolVar(&o.noHistory, "no-history", false, "do not record events for ghostio search")
+	flags.StringVar(&o.historyDir, "history-dir", history.DefaultDir(), "directory of the event history")
+	flags.StringVar(&o.slaDir, "sla-dir", sla.DefaultDir(), "directory keeping pending SLA deadlines across restarts")
+	flags.StringVar(&o.fileDir, "file", "", "append events as NDJSON to files in this directory")
+	flags.StringVar(&o.fileRotate, "file-rotate", "daily", "rotate event files: daily or size")
+	flags.StringVar(&o.fileMaxSize, "file-max-size", "100M", "maximum size of an event file before rotation")
+	flags.IntVar(&o.fileKeep, "file-keep", 14, "number of rotated event files to keep (0 keeps all)")
+	flags.DurationVar(&o.fileSync, "file-sync", time.Second, "how often event files are fsynced (0 syncs every event)")
+	flags.StringVar(&o.smtpAddr, "smtp", "", "send email digests through this SMTP server (host:port)")
+	flags.StringVar(&o.smtpFrom, "smtp-from", "", "sender address of email digests")
+	flags.StringVar(&o.smtpTo, "smtp-to", "", "comma-separated recipients of email digests")
+	flags.StringVar(&o.smtpUser, "smtp-user", "", "SMTP username; the password is read from GHOSTIO_SMTP_PASSWORD")
+	flags.StringVar(&o.smtpSchedule, "smtp-schedule", "0 9 * * *", "cron schedule for email digests")
+	flags.BoolVar(&o.smtpStartTLS, "smtp-starttls", true, "require STARTTLS for SMTP delivery")
+}
+
+// build creates the selected sinks, recording their deliveries in inst.
+// The command's primary sink is always included first under primaryName.
+func (o *sinkOptions) build(ctx context.Context, primaryName string, primary sink.Sink, inst *instrumentation) (*outputs, error) {
+	cfg, err := o.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	global, perSink, err := o.filters(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	engine, err := compileRules(cfg)
+	if err != nil {
+		return nil, err
+	}
+	slas, err := compileSLAs(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Requests besides polls go through one transport, so they are recorded and counted
+	api := o.transport
+	if api == nil {
+		api = watcher.GH
+	}
+	api = inst.poller.Instrument(api)
+
+	out := &outputs{quiet: o.suppressor(cfg, inst)}
+	if !o.noEnrich {
+		out.enrich = enrich.New(enrich.Config{TTL: o.enrichTTL, Concurrency: o.enrichConcurrency, Fetch: api.Fetch})
+	}
+	out.filterDetails = global.UsesDetails()
+	for _, f := range perSink {
+		out.filterDetails = out.filterDetails || f.UsesDetails()
+	}
+	o.enableEnrichment(out, engine, slas)
+	var sinks sink.Multi
+	wrap := func(name string, s sink.Sink) sink.Sink {
+		s = sink.Instrument(name, s, inst.sinks)
+		if f := perSink[name]; f != nil {
+			s = sink.Filtered(s, func(e watcher.Event) bool { return f.Match(e) || rules.Routed(e, name) })
+		}
+		return s
+	}
+	add := func(name string, s sink.Sink) {
+		sinks = append(sinks, wrap(name, s))
+		out.built = append(out.built, name)
+	}
+	// recorder keeps the history, which sees events before the global filter
+	var recorder sink.Sink
+	fail := func(err error) (*outputs, error) {
+		sinks.Close()
+		if recorder != nil {
+			recorder.Close()
+		}
+		return nil, err
+	}
+	add(primaryName, primary)
+
+	if o.notify {
+		notifier, err := sink.NewNotifier(ctx, sink.NotifyConfig{})
+		if err != nil {
+			return fail(err)
+		}
+		add("notify", notifier)
+	}
+	if o.syslog != "" {
+		config, err := sink.ParseSyslogAddress(o.syslog)
+		if err != nil {
+			return fail(err)
+		}
+		s, err := sink.NewSyslog(config)
+		if err != nil {
+			return fail(err)
+		}
+		add("syslog", s)
+	}
+	if o.journald {
+		j, err := sink.NewJournald("")
+		if err != nil {
+			return fail(err)
+		}
+		add("journald", j)
+	}
+	if o.fileDir != "" {
+		config := sink.FileConfig{
+			Dir:          o.fileDir,
+			Keep:         o.fileKeep,
+			Compress:     true,
+			SyncInterval: o.fileSync,
+		}
+		switch o.fileRotate {
+		case "daily":
+			config.Daily = true
+		case "size":
+		default:
+			return fail(fmt.Errorf("invalid --file-rotate %q: want daily or size", o.fileRotate))
+		}
+		size, err := parseSize(o.fileMaxSize)
+		if err != nil {
+			return fail(err)
+		}
+		config.MaxSize = size
+		f, err := sink.NewFile(config)
+		if err != nil {
+			return fail(err)
+		}
+		add("file", f)
+	}
+	if !o.noHistory {
+		// History is on by default, so a store that cannot be opened only disables it
+		if r, err := history.NewRecorder(o.historyDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: history disabled: %v\n", err)
+		} else {
+			recorder = wrap("history", r)
+			out.built = append(out.built, "history")
+		}
+	}
+	if o.smtpAddr != "" {
+		schedule, err := cron.Parse(o.smtpSchedule)
+		if err != nil {
+			return fail(err)
+		}
+		var to []string
+		for _, addr := range strings.Split(o.smtpTo, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				to = append(to, addr)
+			}
+		}
+		email, err := sink.NewEmail(sink.EmailConfig{
+			Addr:     o.smtpAddr,
+			From:     o.smtpFrom,
+			To:       to,
+			Username: o.smtpUser,
+			Password: os.Getenv("GHOSTIO_SMTP_PASSWORD"),
+			StartTLS: o.smtpStartTLS,
+			Schedule: schedule,
+		})
+		if err != nil {
+			return fail(err)
+		}
+		add("email", email)
+		out.email = email
+	}
+
+	warnUnbuilt(engine, slas, out.built)
+
+	// Noise is dropped first, then the SLA watchdog sees every response, then the history
+	// records what the global filter would drop too, then the filter applies and rules attach
+	// their alerts to each event, and only then are bursts merged for the sinks. Missed
+	// deadlines skip the filters and go straight to the rules.
+	out.rules = rules.NewSink(coalesce.New(sinks, o.coalesce), engine, log.Writer(), out.requestExit)
+	out.sinks = sink.Filtered(out.rules, global.Match)
+	if recorder != nil {
+		out.sinks = sink.Multi{recorder, out.sinks}
+	}
+	if !o.noSLA {
+		out.sla, err = sla.New(out.sinks, out.rules, sla.Config{
+			SLAs:    slas,
+			Repos:   o.repos,
+			Dir:     o.slaDir,
+			Recheck: api.FetchThread,
+			Bell:    log.Writer(),
+			OnExit:  out.requestExit,
+		})
+		if err != nil {
+			return fail(err)
+		}
+		out.sinks = out.sla
+	}
+	if out.quiet != nil {
+		out.sinks = sink.Filtered(out.sinks, out.quiet.Allow)
+	}
+	return out, nil
+}
+
+// compileRules compiles the configured rules and checks the sinks they route to
+func compileRules(cfg *config.Config) (*rules.Engine, error) {
+	engine, err := rules.Compile(cfg.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	for _, r := range engine.Rules() {
+		for _, name := range r.Actions.Sinks {
+			if !slices.Contains(sinkNames, name) {
+				return nil, fmt.Errorf("config: rule %s: unknown sink %q (have %s)", r.Name, name, strings.Join(sinkNames, ", "))
+			}
+		}
+	}
+	return engine, nil
+}
+
+// compileSLAs compiles the configured SLAs and checks the sinks they route to
+func compileSLAs(cfg *config.Config) ([]*sla.SLA, error) {
+	slas, err := sla.Compile(cfg.SLAs, cfg.Calendars)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	for _, s := range slas {
+		for _, name := range s.Actions.Sinks {
+			if !slices.Contains(sinkNames, name) {
+				return nil, fmt.Errorf("config: sla %s: unknown sink %q (have %s)", s.Name, name, strings.Join(sinkNames, ", "))
+			}
+		}
+	}
+	return slas, nil
+}
+
+// enableEnrichment fetches details only with --enrich or when the filters, rules or SLAs
+// refer to fields that enrichment provides, so other events cost no requests
+func (o *sinkOptions) enableEnrichment(out *outputs, engine *rules.Engine, slas []*sla.SLA) {
+	if out.enrich == nil {
+		return
+	}
+	needed := o.enrich || out.filterDetails || engine.UsesDetails()
+	for _, s := range slas {
+		needed = needed || s.UsesDetails()
+	}
+	out.enrich.SetEnabled(needed)
+}
+
+// warnUnbuilt warns about rules and SLAs that route to sinks this command did not build,
+// whose alerts would go nowhere
+func warnUnbuilt(engine *rules.Engine, slas []*sla.SLA, built []string) {
+	for _, r := range engine.Rules() {
+		for _, name := range r.Actions.Sinks {
+			if !slices.Contains(built, name) {
+				log.Printf("Warning: rule %s routes to the %s sink, which is not enabled", r.Name, name)
+			}
+		}
+	}
+	for _, s := range slas {
+		for _, name := range s.Actions.Sinks {
+			if !slices.Contains(built, name) {
+				log.Printf("Warning: sla %s routes to the %s sink, which is not enabled", s.Name, name)
+			}
+		}
+	}
+}
+
+// reload rereads the configuration file and replaces the rules and SLAs; on error the current ones stay
+func (o *sinkOptions) reload(out *outputs) error {
+	cfg, err := o.loadConfig()
+	if err != nil {
+		return err
+	}
+	engine, err := compileRules(cfg)
+	if err != nil {
+		return err
+	}
+	slas, err := compileSLAs(cfg)
+	if err != nil {
+		return err
+	}
+	warnUnbuilt(engine, slas, out.built)
+	o.enableEnrichment(out, engine, slas)
+	out.rules.SetEngine(engine)
+	if out.sla != nil {
+		if err := out.sla.SetSLAs(slas); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+	log.Printf("Reloaded %d rules and %d SLAs", len(engine.Rules()), len(slas))
+	return nil
+}
+
+// reloadOnHangup reloads the rules and SLAs whenever the process receives SIGHUP, until ctx is canceled
+func (o *sinkOptions) reloadOnHangup(ctx context.Context, out *outputs) {
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hupCh)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hupCh:
+				if err := o.reload(out); err != nil {
+					log.Printf("Warning: reload failed, keeping the current rules: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// requestExit records the exit code asked for by a rule and stops the command
+func (out *outputs) requestExit(code int) {
+	out.mu.Lock()
+	if out.exitCode == nil {
+		out.exitCode = &code
+	}
+	stop := out.stop
+	out.mu.Unlock()
+	if stop != nil {
+		stop()
+	}
+}
+
+// exit closes the sinks and exits with the code requested by a rule, if any
+func (out *outputs) exit() {
+	out.mu.Lock()
+	code := out.exitCode
+	out.mu.Unlock()
+	if code == nil {
+		return
+	}
+	out.close()
+	os.Exit(*code)
+}
+
+// close closes the sinks once, flushing what they buffer, and warns when that fails
+func (out *outputs) close() {
+	out.closeOnce.Do(func() {
+		if err := out.sinks.Close(); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	})
+}
+
+// reportSuppressed prints how many events were dropped as noise
+func (out *outputs) reportSuppressed() {
+	if out.quiet == nil {
+		return
+	}
+	if summary := noise.Summary(out.quiet.Counts()); summary != "" {
+		fmt.Fprintf(os.Stderr, "Suppressed noise: %s\n", summary)
+	}
+}
+
+// loadConfig reads --config, or the default configuration file when it exists
+func (o *sinkOptions) loadConfig() (*config.Config, error) {
+	if o.configPath != "" {
+		return config.Load(o.configPath)
+	}
+	return config.LoadDefault()
+}
+
+// suppressor creates the noise suppressor, or returns nil when suppression is turned off.
+// Flags take precedence over the configuration file.
+func (o *sinkOptions) suppressor(cfg *config.Config, inst *instrumentation) *noise.Suppressor {
+	if o.noQuiet || (cfg.Quiet.Enabled != nil && !*cfg.Quiet.Enabled) {
+		return nil
+	}
+	quiet := noise.Config{
+		Actors:            cfg.Quiet.Actors,
+		CIActors:          cfg.Quiet.CIActors,
+		SelfCommentWindow: o.selfCommentWindow,
+		Metrics:           inst.noise,
+	}
+	for _, actor := range strings.Split(o.quietActors, ",") {
+		if actor = strings.TrimSpace(actor); actor != "" {
+			quiet.Actors = append(quiet.Actors, actor)
+		}
+	}
+	if o.ciActors != "" {
+		quiet.CIActors = nil
+		for _, actor := range strings.Split(o.ciActors, ",") {
+			if actor = strings.TrimSpace(actor); actor != "" {
+				quiet.CIActors = append(quiet.CIActors, actor)
+			}
+		}
+	}
+	if o.selfCommentWindow == noise.DefaultSelfCommentWindow && cfg.Quiet.SelfCommentWindow != 0 {
+		quiet.SelfCommentWindow = time.Duration(cfg.Quiet.SelfCommentWindow)
+	}
+	return noise.New(quiet)
+}
+
+// noAlerts explains why the global filter cannot use alerts
+const noAlerts = "is only set for sink filters; the global filter runs before the rules"
+
+// filters compiles the global and per-sink filters; flags take precedence over the configuration file
+func (o *sinkOptions) filters(cfg *config.Config) (*filter.Filter, map[string]*filter.Filter, error) {
+	var err error
+	global := filter.Default()
+	switch {
+	case o.filter != "":
+		if global, err = filter.Parse(o.filter); err == nil {
+			err = global.Reject("alerts", noAlerts)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("--filter: %w", err)
+		}
+	case cfg.Filter != "":
+		if global, err = filter.Parse(cfg.Filter); err == nil {
+			err = global.Reject("alerts", noAlerts)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("config filter: %w", err)
+		}
+	}
+
+	perSink := map[string]*filter.Filter{}
+	for name, sc := range cfg.Sinks {
+		if !slices.Contains(sinkNames, name) {
+			return nil, nil, fmt.Errorf("config: unknown sink %q (have %s)", name, strings.Join(sinkNames, ", "))
+		}
+		if sc.Filter == "" {
+			continue
+		}
+		if perSink[name], err = filter.Parse(sc.Filter); err != nil {
+			return nil, nil, fmt.Errorf("config sinks.%s.filter: %w", name, err)
+		}
+	}
+	for name, expr := range o.sinkFilters {
+		if perSink[name], err = filter.Parse(expr); err != nil {
+			return nil, nil, fmt.Errorf("--sink-filter %s: %w", name, err)
+		}
+	}
+	return global, perSink, nil
+}
+
+// parseSize parses a byte size with an optional K, M or G suffix
+func parseSize(s string) (int64, error) {
+	multiplier := int64(1)
+	trimmed := strings.TrimSuffix(strings.ToUpper(s), "B")
+	switch {
+	case strings.HasSuffix(trimmed, "K"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(trimmed, "M"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(trimmed, "G"):
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		trimmed = trimmed[:len(trimmed)-1]
+	}
+	n, err := strconv.ParseInt(trimmed, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * multiplier, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/history"
+	"github.com/ytnobody/ghostio/internal/stats"
+)
+
+func runStats(args []string) {
+	flags := newFlagSet("stats", "stats [flags] owner/repo")
+	since := flags.String("since", "30d", "period to measure, ending now, e.g. 30d or 12w")
+	format := flags.String("format", "table", "output format: table or json")
+	source := flags.String("source", "api", "where events come from: api, or history for the local history store")
+	historyDir := flags.String("history-dir", history.DefaultDir(), "directory of the event history")
+	top := flags.Int("top", 10, "number of contributors to list in the table (0 lists all)")
+	positional := parseArgs(flags, args)
+
+	if len(positional) != 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+	repo := positional[0]
+	age, err := parseAge(*since)
+	if err != nil || age == 0 {
+		fatal(fmt.Errorf("--since: invalid period %q", *since))
+	}
+	if *format != "table" && *format != "json" {
+		fatal(fmt.Errorf("invalid --format %q: want table or json", *format))
+	}
+
+	now := time.Now()
+	config := stats.Config{Repo: repo, From: now.Add(-age), To: now}
+	events, err := activity(*source, *historyDir, repo, config.From)
+	if err != nil {
+		fatal(err)
+	}
+	report := stats.Build(config, events)
+	if *format == "json" {
+		data, err := report.JSON()
+		if err != nil {
+			fatal(err)
+		}
+		os.Stdout.Write(data)
+		return
+	}
+	if err := report.WriteTable(os.Stdout, *top); err != nil {
+		fatal(err)
+	}
+}
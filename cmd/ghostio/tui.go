@@ -0,0 +1,78 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/ytnobody/ghostio/internal/control"
+	"github.com/ytnobody/ghostio/internal/tui"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+func runTUI(args []string) {
+	flags := newFlagSet("tui", "tui [flags] owner/repo...")
+	var sinkOpts sinkOptions
+	sinkOpts.register(flags)
+	controlPath := flags.String("control", control.DefaultSocketPath(), "path of the control socket")
+	metricsAddr := flags.String("metrics", "", "address to serve Prometheus metrics and health checks on (e.g. :9100)")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	// Setup metrics and health checks
+	inst := newInstrumentation()
+	pollers := newPollGroup(flags.Args(), watcher.PollerConfig{Metrics: inst.poller})
+	serveOps(ctx, *metricsAddr, inst.registry, pollers)
+
+	tty, err := tui.Open()
+	if err != nil {
+		fatal(err)
+	}
+	app := tui.New(tty, pollers.status)
+	// Warnings and bells would draw over the screen; the UI shows them on its status bar
+	log.SetOutput(app)
+
+	// Setup sinks; the UI is the primary one
+	sinkOpts.repos = flags.Args()
+	out, err := sinkOpts.build(ctx, "tui", app, inst)
+	if err != nil {
+		log.SetOutput(os.Stderr)
+		tty.Close()
+		fatal(err)
+	}
+	defer out.close()
+	out.stop = cancel
+	sinkOpts.reloadOnHangup(ctx, out)
+
+	if ctl := startControl(*controlPath, out, pollers); ctl != nil {
+		defer ctl.Close()
+	}
+
+	// Poll in the background; the UI runs until the user quits, a rule exits or polling fails
+	pollErr := make(chan error, 1)
+	go func() {
+		pollErr <- pollers.run(ctx, out.sinks, out.enrich)
+		cancel()
+	}()
+	runErr := app.Run(ctx)
+	log.SetOutput(os.Stderr)
+	tty.Close()
+	cancel()
+	err = <-pollErr
+
+	out.close()
+	out.reportSuppressed()
+	out.exit()
+	if runErr != nil {
+		fatal(runErr)
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
@@ -0,0 +1,333 @@
+// Package coalesce holds events for a short window and merges bursts that belong
+// together, such as a pull request merge with the issues it closes and its branch deletion,
+// into a single summarized event.
+package coalesce
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/sink"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// DefaultWindow is how long events are held waiting for related ones
+const DefaultWindow = 5 * time.Second
+
+// group collects the events of one burst. Events that cannot be related to others
+// are held as groups without keys so they keep their place in the queue.
+type group struct {
+	repo   string
+	keys   []string
+	events []watcher.Event
+	timer  *time.Timer
+	ready  bool
+}
+
+// mergedPRBy reports whether the group holds a pull request merged or closed by actor
+func (g *group) mergedPRBy(actor string) bool {
+	for _, e := range g.events {
+		if e.Type == "PullRequestEvent" && e.Payload.Action == "closed" && e.Actor.Login == actor {
+			return true
+		}
+	}
+	return false
+}
+
+// Sink holds events for a window and delivers related ones as a single event
+type Sink struct {
+	next   sink.Sink
+	window time.Duration
+
+	mu     sync.Mutex
+	groups map[string]*group
+	// queue holds the pending groups in arrival order of their first event
+	queue []*group
+
+	// sendMu serializes deliveries from Send and the window timers
+	sendMu sync.Mutex
+}
+
+// New wraps next so events are held for window; zero or negative window delivers events immediately
+func New(next sink.Sink, window time.Duration) *Sink {
+	return &Sink{next: next, window: window, groups: make(map[string]*group)}
+}
+
+func key(repo string, number int) string {
+	return fmt.Sprintf("%s#%d", repo, number)
+}
+
+// Send holds the event until its window ends. Events that cannot be related to others,
+// such as pushes, are delivered as soon as every event that arrived before them has been.
+// Filters and rules run before coalescing, so each event is matched on its own.
+func (s *Sink) Send(e watcher.Event) error {
+	if s.window <= 0 {
+		return s.deliver(e)
+	}
+
+	s.mu.Lock()
+	number := e.Number()
+	// Events that already carry a summary, such as missed SLA deadlines, stand on their own
+	if number == 0 || e.Summary != "" {
+		if g := s.branchGroup(e); g != nil {
+			g.events = append(g.events, e)
+			s.mu.Unlock()
+			return nil
+		}
+		s.queue = append(s.queue, &group{repo: e.Repo.Name, events: []watcher.Event{e}, ready: true})
+		s.mu.Unlock()
+		return s.drain()
+	}
+
+	k := key(e.Repo.Name, number)
+	g := s.groups[k]
+	if g == nil && e.Type == "IssuesEvent" && e.Payload.Action == "closed" {
+		g = s.closingGroup(e)
+		if g != nil {
+			g.keys = append(g.keys, k)
+			s.groups[k] = g
+		}
+	}
+	if g == nil {
+		g = &group{repo: e.Repo.Name, keys: []string{k}}
+		s.groups[k] = g
+		s.queue = append(s.queue, g)
+		g.timer = time.AfterFunc(s.window, func() {
+			s.mu.Lock()
+			g.ready = true
+			s.mu.Unlock()
+			if err := s.drain(); err != nil {
+				log.Printf("sink error: %v", err)
+			}
+		})
+	}
+	g.events = append(g.events, e)
+	s.mu.Unlock()
+	return nil
+}
+
+// closingGroup finds the pending merge whose author closed the issue in e; when the author
+// merged several, the most recent one is taken
+func (s *Sink) closingGroup(e watcher.Event) *group {
+	for i := len(s.queue) - 1; i >= 0; i-- {
+		g := s.queue[i]
+		if len(g.keys) > 0 && g.repo == e.Repo.Name && g.mergedPRBy(e.Actor.Login) {
+			return g
+		}
+	}
+	return nil
+}
+
+// branchGroup finds the pending merge whose author deleted the branch in e
+func (s *Sink) branchGroup(e watcher.Event) *group {
+	if e.Type != "DeleteEvent" || e.Payload.RefType != "branch" {
+		return nil
+	}
+	return s.closingGroup(e)
+}
+
+// drain delivers the groups at the head of the queue whose window has ended, stopping
+// at the first one still pending so events leave in the order they arrived
+func (s *Sink) drain() error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	s.mu.Lock()
+	var ready []*group
+	for len(s.queue) > 0 && s.queue[0].ready {
+		g := s.queue[0]
+		s.queue = s.queue[1:]
+		for _, k := range g.keys {
+			delete(s.groups, k)
+		}
+		ready = append(ready, g)
+	}
+	s.mu.Unlock()
+
+	var errs []error
+	for _, g := range ready {
+		if err := s.next.Send(Merge(g.events)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Flush delivers every pending group now
+func (s *Sink) Flush() error {
+	s.mu.Lock()
+	for _, g := range s.queue {
+		if g.timer != nil {
+			g.timer.Stop()
+		}
+		g.ready = true
+	}
+	s.mu.Unlock()
+	return s.drain()
+}
+
+func (s *Sink) deliver(e watcher.Event) error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	return s.next.Send(e)
+}
+
+// Close delivers the pending groups and closes the wrapped sink
+func (s *Sink) Close() error {
+	return errors.Join(s.Flush(), s.next.Close())
+}
+
+// Merge combines a burst of events into the most significant one, which gets a summary,
+// the others as Coalesced and the alerts of all of them. A single event is returned unchanged.
+func Merge(events []watcher.Event) watcher.Event {
+	if len(events) == 1 {
+		return events[0]
+	}
+
+	primary := 0
+	for i, e := range events {
+		if rank(e) > rank(events[primary]) {
+			primary = i
+		}
+	}
+	merged := events[primary]
+	merged.Coalesced = nil
+	for i, e := range events {
+		if i != primary {
+			merged.Coalesced = append(merged.Coalesced, e)
+		}
+	}
+	merged.Alerts = nil
+	seen := map[string]bool{}
+	for _, e := range events {
+		for _, a := range e.Alerts {
+			if !seen[a.Rule] {
+				seen[a.Rule] = true
+				merged.Alerts = append(merged.Alerts, a)
+			}
+		}
+	}
+	merged.Summary = summarize(merged, merged.Coalesced)
+	return merged
+}
+
+// rank orders events by how well they describe a burst
+func rank(e watcher.Event) int {
+	switch e.Type {
+	case "PullRequestEvent":
+		switch e.Payload.Action {
+		case "closed":
+			return 5
+		case "opened", "reopened":
+			return 4
+		}
+		return 2
+	case "IssuesEvent":
+		switch e.Payload.Action {
+		case "opened", "closed", "reopened":
+			return 3
+		}
+		return 2
+	case "ReleaseEvent":
+		return 3
+	case "IssueCommentEvent", "PullRequestReviewCommentEvent":
+		return 1
+	}
+	return 0
+}
+
+// kind is the noun used for the event's subject
+func kind(e watcher.Event) string {
+	switch e.Type {
+	case "PullRequestEvent", "PullRequestReviewCommentEvent":
+		return "PR"
+	}
+	return "Issue"
+}
+
+// verb is the action of the event as shown in summaries
+func verb(e watcher.Event) string {
+	switch e.Type {
+	case "IssueCommentEvent", "PullRequestReviewCommentEvent":
+		return "commented"
+	case "PullRequestEvent":
+		if e.Payload.Action == "closed" && e.Payload.PullRequest != nil && e.Payload.PullRequest.Merged {
+			return "merged"
+		}
+	}
+	if e.Payload.Action == "" {
+		return e.Type
+	}
+	return e.Payload.Action
+}
+
+func times(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return fmt.Sprintf(" %d times", n)
+}
+
+// summarize describes primary and the events merged into it, e.g.
+// "PR #12 merged by @alice, closes #3, #4; branch deleted"
+func summarize(primary watcher.Event, others []watcher.Event) string {
+	var closes []string
+	var branch bool
+	var commenters []string
+	comments := 0
+	if verb(primary) == "commented" {
+		commenters = append(commenters, "@"+primary.Actor.Login)
+	}
+	repeats := 1
+	actions := map[string]int{}
+	var order []string
+	for _, e := range others {
+		switch {
+		case e.Type == "DeleteEvent":
+			branch = true
+		case e.Type == "IssuesEvent" && e.Payload.Action == "closed" && e.Number() != primary.Number():
+			closes = append(closes, fmt.Sprintf("#%d", e.Number()))
+		case verb(e) == "commented":
+			comments++
+			if login := "@" + e.Actor.Login; !slices.Contains(commenters, login) {
+				commenters = append(commenters, login)
+			}
+		case e.Type == primary.Type && verb(e) == verb(primary):
+			repeats++
+		default:
+			v := verb(e)
+			if actions[v] == 0 {
+				order = append(order, v)
+			}
+			actions[v]++
+		}
+	}
+
+	summary := fmt.Sprintf("%s #%d %s%s by @%s", kind(primary), primary.Number(), verb(primary), times(repeats), primary.Actor.Login)
+	if verb(primary) == "commented" {
+		summary = fmt.Sprintf("%d comments on #%d by %s", comments+1, primary.Number(), strings.Join(commenters, ", "))
+		comments = 0
+	}
+	if len(closes) > 0 {
+		summary += ", closes " + strings.Join(closes, ", ")
+	}
+
+	parts := []string{summary}
+	for _, v := range order {
+		parts = append(parts, v+times(actions[v]))
+	}
+	if comments == 1 {
+		parts = append(parts, "1 comment by "+commenters[0])
+	} else if comments > 1 {
+		parts = append(parts, fmt.Sprintf("%d comments by %s", comments, strings.Join(commenters, ", ")))
+	}
+	if branch {
+		parts = append(parts, "branch deleted")
+	}
+	return strings.Join(parts, "; ")
+}
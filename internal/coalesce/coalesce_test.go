@@ -0,0 +1,209 @@
+package coalesce
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/sink/sinktest"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+func event(id, typ, action, actor string) watcher.Event {
+	return watcher.Event{
+		ID:      id,
+		Type:    typ,
+		Actor:   watcher.Actor{Login: actor},
+		Repo:    watcher.Repo{Name: "owner/repo"},
+		Payload: watcher.Payload{Action: action},
+	}
+}
+
+func pr(id, action, actor string, number int, merged bool) watcher.Event {
+	e := event(id, "PullRequestEvent", action, actor)
+	e.Payload.PullRequest = &watcher.PullRequest{Number: number, Merged: merged, HTMLURL: "https://github.com/owner/repo/pull/12"}
+	return e
+}
+
+func issue(id, typ, action, actor string, number int) watcher.Event {
+	e := event(id, typ, action, actor)
+	e.Payload.Issue = &watcher.Issue{Number: number}
+	return e
+}
+
+func deleted(id, actor string) watcher.Event {
+	e := event(id, "DeleteEvent", "", actor)
+	e.Payload.Ref = "feature"
+	e.Payload.RefType = "branch"
+	return e
+}
+
+func TestMergeBurst(t *testing.T) {
+	next := &sinktest.Recorder{}
+	s := New(next, time.Hour)
+
+	s.Send(issue("1", "IssueCommentEvent", "created", "ci-bot", 12))
+	s.Send(pr("2", "closed", "alice", 12, true))
+	s.Send(issue("3", "IssuesEvent", "closed", "alice", 3))
+	s.Send(issue("4", "IssuesEvent", "closed", "alice", 4))
+	s.Send(deleted("5", "alice"))
+	if len(next.Events()) != 0 {
+		t.Fatal("events were delivered before the window ended")
+	}
+	s.Flush()
+
+	got := next.Events()
+	if len(got) != 1 {
+		t.Fatalf("expected one merged event, got %d", len(got))
+	}
+	want := "PR #12 merged by @alice, closes #3, #4; 1 comment by @ci-bot; branch deleted"
+	if got[0].Summary != want {
+		t.Errorf("summary = %q, want %q", got[0].Summary, want)
+	}
+	if got[0].ID != "2" || len(got[0].Coalesced) != 4 {
+		t.Errorf("expected the merge as primary with 4 coalesced events, got %s with %d", got[0].ID, len(got[0].Coalesced))
+	}
+	headline := watcher.FormatEvent(got[0])
+	if headline != "[0001-01-01 00:00:00] "+want+"\nhttps://github.com/owner/repo/pull/12" {
+		t.Errorf("unexpected format: %q", headline)
+	}
+}
+
+func TestRepeatedEdits(t *testing.T) {
+	next := &sinktest.Recorder{}
+	s := New(next, time.Hour)
+	for _, id := range []string{"1", "2", "3"} {
+		s.Send(issue(id, "IssuesEvent", "edited", "bob", 7))
+	}
+	s.Send(issue("4", "IssuesEvent", "labeled", "bob", 7))
+	s.Flush()
+
+	got := next.Events()
+	if len(got) != 1 {
+		t.Fatalf("expected one merged event, got %d", len(got))
+	}
+	if want := "Issue #7 edited 3 times by @bob; labeled"; got[0].Summary != want {
+		t.Errorf("summary = %q, want %q", got[0].Summary, want)
+	}
+}
+
+func TestUnrelatedEvents(t *testing.T) {
+	next := &sinktest.Recorder{}
+	s := New(next, time.Hour)
+
+	s.Send(event("1", "PushEvent", "", "alice"))
+	s.Send(deleted("2", "alice"))
+	if got := next.Events(); len(got) != 2 {
+		t.Fatalf("expected events without a subject to pass through, got %d", len(got))
+	}
+
+	s.Send(issue("3", "IssuesEvent", "opened", "bob", 1))
+	s.Send(issue("4", "IssuesEvent", "closed", "carol", 2))
+	s.Close()
+
+	got := next.Events()
+	if len(got) != 4 || !next.Closed() {
+		t.Fatalf("expected close to flush both issues and close the sink, got %d events", len(got))
+	}
+	for _, e := range got {
+		if e.Summary != "" || len(e.Coalesced) != 0 {
+			t.Errorf("single event %s was summarized: %q", e.ID, e.Summary)
+		}
+	}
+}
+
+func TestOrder(t *testing.T) {
+	next := &sinktest.Recorder{}
+	s := New(next, time.Hour)
+
+	s.Send(issue("1", "IssuesEvent", "opened", "bob", 1))
+	s.Send(event("2", "PushEvent", "", "alice"))
+	s.Send(issue("3", "IssueCommentEvent", "created", "carol", 1))
+	if got := next.Events(); len(got) != 0 {
+		t.Fatalf("push was delivered ahead of the pending issue: %+v", got)
+	}
+	s.Send(issue("4", "IssuesEvent", "opened", "dave", 2))
+	s.Flush()
+
+	got := next.Events()
+	var ids []string
+	for _, e := range got {
+		ids = append(ids, e.ID)
+	}
+	if strings.Join(ids, ",") != "1,2,4" {
+		t.Errorf("delivered %v, want the merged issue, the push and the second issue in arrival order", ids)
+	}
+}
+
+func TestMergeAlerts(t *testing.T) {
+	first := issue("1", "IssueCommentEvent", "created", "alice", 5)
+	second := issue("2", "IssueCommentEvent", "created", "bob", 5)
+	second.Alerts = []watcher.Alert{{Rule: "incident", Severity: "critical"}}
+	third := issue("3", "IssueCommentEvent", "created", "bob", 5)
+	third.Alerts = []watcher.Alert{{Rule: "incident", Severity: "critical"}}
+	merged := Merge([]watcher.Event{first, second, third})
+	if len(merged.Alerts) != 1 || merged.Alerts[0].Rule != "incident" {
+		t.Errorf("expected the alerts of every merged event once, got %+v", merged.Alerts)
+	}
+}
+
+func TestClosingGroup(t *testing.T) {
+	next := &sinktest.Recorder{}
+	s := New(next, time.Hour)
+	s.Send(pr("1", "closed", "alice", 12, true))
+	s.Send(pr("2", "closed", "alice", 13, true))
+	s.Send(issue("3", "IssuesEvent", "closed", "alice", 3))
+	s.Flush()
+
+	got := next.Events()
+	if len(got) != 2 || len(got[0].Coalesced) != 0 || len(got[1].Coalesced) != 1 {
+		t.Fatalf("expected the issue to join the most recent merge, got %+v", got)
+	}
+}
+
+func TestSummarizedPassThrough(t *testing.T) {
+	next := &sinktest.Recorder{}
+	s := New(next, time.Hour)
+	breach := issue("1", "SLABreach", "", "alice", 4)
+	breach.Summary = "SLA triage missed"
+	s.Send(breach)
+	if got := next.Events(); len(got) != 1 || got[0].Summary != "SLA triage missed" {
+		t.Errorf("expected an event with a summary to pass through, got %+v", got)
+	}
+}
+
+func TestComments(t *testing.T) {
+	merged := Merge([]watcher.Event{
+		issue("1", "IssueCommentEvent", "created", "alice", 5),
+		issue("2", "IssueCommentEvent", "created", "bob", 5),
+		issue("3", "IssueCommentEvent", "created", "alice", 5),
+	})
+	if want := "3 comments on #5 by @alice, @bob"; merged.Summary != want {
+		t.Errorf("summary = %q, want %q", merged.Summary, want)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	next := &sinktest.Recorder{}
+	s := New(next, 20*time.Millisecond)
+	s.Send(pr("1", "opened", "alice", 9, false))
+	s.Send(issue("2", "IssueCommentEvent", "created", "alice", 9))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(next.Events()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	got := next.Events()
+	if len(got) != 1 || got[0].Summary != "PR #9 opened by @alice; 1 comment by @alice" {
+		t.Fatalf("unexpected delivery after the window: %+v", got)
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	next := &sinktest.Recorder{}
+	s := New(next, 0)
+	s.Send(issue("1", "IssuesEvent", "edited", "bob", 7))
+	if len(next.Events()) != 1 {
+		t.Fatal("expected immediate delivery without a window")
+	}
+}
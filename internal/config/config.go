@@ -0,0 +1,192 @@
+// Package config loads the ghostio configuration file.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Config is the contents of the configuration file
+type Config struct {
+	// Filter selects the events delivered to any sink
+	Filter string `json:"filter,omitempty"`
+	// Sinks holds per-sink settings keyed by sink name (stdout, notify, syslog, journald, file, email, ...)
+	Sinks map[string]SinkConfig `json:"sinks,omitempty"`
+	// Quiet configures noise suppression
+	Quiet QuietConfig `json:"quiet,omitempty"`
+	// Rules are alerting rules, evaluated in order
+	Rules []RuleConfig `json:"rules,omitempty"`
+	// SLAs are deadlines for a response to issues and pull requests
+	SLAs []SLAConfig `json:"slas,omitempty"`
+	// Calendars are business-hours calendars keyed by name, used by SLAs
+	Calendars map[string]CalendarConfig `json:"calendars,omitempty"`
+}
+
+// RuleConfig is a named alerting rule. An event matches when it satisfies When and,
+// if any text conditions are given, at least one keyword, mention or pattern appears
+// in its title or body.
+type RuleConfig struct {
+	Name string `json:"name"`
+	// When is a filter expression the event must satisfy
+	When string `json:"when,omitempty"`
+	// Keywords are words or phrases matched case-insensitively on word boundaries
+	Keywords []string `json:"keywords,omitempty"`
+	// Mentions are @user or @org/team mentions
+	Mentions []string `json:"mentions,omitempty"`
+	// Patterns are regular expressions
+	Patterns []string `json:"patterns,omitempty"`
+	// Severity is info, warning or critical; the default is warning
+	Severity string      `json:"severity,omitempty"`
+	Actions  RuleActions `json:"actions,omitempty"`
+}
+
+// RuleActions are what happens when a rule matches
+type RuleActions struct {
+	// Highlight highlights the matched text in terminal output
+	Highlight bool `json:"highlight,omitempty"`
+	// Sinks routes the event to these sinks even when their own filter rejects it
+	Sinks []string `json:"sinks,omitempty"`
+	// Bell rings the terminal bell
+	Bell bool `json:"bell,omitempty"`
+	// Exit stops ghostio with this exit code once the event is delivered
+	Exit *int `json:"exit,omitempty"`
+}
+
+// SLAConfig is a named deadline: once an event satisfies Start, a later event on the same
+// issue or pull request must satisfy Stop within the given time, or an alert is raised.
+type SLAConfig struct {
+	Name string `json:"name"`
+	// Start is a filter expression for the events that start the clock
+	Start string `json:"start"`
+	// Stop is a filter expression for the events that meet the deadline; the default is a
+	// comment or review. Events by the author and by bots never stop the clock.
+	Stop string `json:"stop,omitempty"`
+	// Within is the deadline, such as "4h" or "1d"; with a calendar only business hours
+	// count and a day is one working day
+	Within string `json:"within"`
+	// Calendar names the business-hours calendar; without one the clock runs around the clock
+	Calendar string `json:"calendar,omitempty"`
+	// Severity is info, warning or critical; the default is warning
+	Severity string      `json:"severity,omitempty"`
+	Actions  RuleActions `json:"actions,omitempty"`
+}
+
+// CalendarConfig describes working hours
+type CalendarConfig struct {
+	// TimeZone is an IANA time zone such as "Europe/Berlin"; the default is local time
+	TimeZone string `json:"time_zone,omitempty"`
+	// Days are the working days, e.g. ["mon", "tue"]; the default is Monday to Friday
+	Days []string `json:"days,omitempty"`
+	// Hours are the working hours of each day; the default is "09:00-17:00"
+	Hours string `json:"hours,omitempty"`
+	// Holidays are dates without work, as YYYY-MM-DD
+	Holidays []string `json:"holidays,omitempty"`
+}
+
+// QuietConfig configures noise suppression
+type QuietConfig struct {
+	// Enabled turns suppression off when false; it is on by default
+	Enabled *bool `json:"enabled,omitempty"`
+	// Actors are extra login patterns to drop, e.g. "renovate*"
+	Actors []string `json:"actors,omitempty"`
+	// CIActors are the CI bots whose comment edits are collapsed, e.g. "github-actions[bot]"
+	CIActors []string `json:"ci_actors,omitempty"`
+	// SelfCommentWindow is how long after opening a pull request its author's comments are dropped
+	SelfCommentWindow Duration `json:"self_comment_window,omitempty"`
+}
+
+// Duration is a time.Duration written as a string such as "90s" or "2m"
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"90s\"")
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalJSON writes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// SinkConfig holds the settings of one sink
+type SinkConfig struct {
+	// Filter selects the events delivered to this sink, after the global filter
+	Filter string `json:"filter,omitempty"`
+}
+
+// DefaultPath returns $XDG_CONFIG_HOME/ghostio/config.json, falling back to ~/.config
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "ghostio", "config.json")
+}
+
+// Load reads the configuration file at path
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var c Config
+	if err := dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, describe(data, err))
+	}
+	return &c, nil
+}
+
+// LoadDefault reads the configuration file at DefaultPath; a missing file gives an empty configuration
+func LoadDefault() (*Config, error) {
+	path := DefaultPath()
+	if path == "" {
+		return &Config{}, nil
+	}
+	c, err := Load(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Config{}, nil
+	}
+	return c, err
+}
+
+// describe adds the line and column to JSON syntax and type errors
+func describe(data []byte, err error) error {
+	var offset int64
+	var syntax *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntax):
+		offset = syntax.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	default:
+		return err
+	}
+	line, col := 1, 1
+	for _, b := range data[:min(int(offset), len(data))] {
+		if b == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+	return fmt.Errorf("line %d, column %d: %w", line, col, err)
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, `{
+  "filter": "repo == \"org/api\"",
+  "sinks": {
+    "notify": {"filter": "merged"}
+  },
+  "quiet": {"actors": ["renovate*"], "self_comment_window": "2m"},
+  "slas": [{"name": "review", "start": "action == \"review_requested\"", "within": "1d", "calendar": "office"}],
+  "calendars": {"office": {"time_zone": "Europe/Berlin", "hours": "08:00-16:00"}}
+}`)
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if c.Filter != `repo == "org/api"` {
+		t.Errorf("Filter = %q", c.Filter)
+	}
+	if c.Sinks["notify"].Filter != "merged" {
+		t.Errorf("notify filter = %q", c.Sinks["notify"].Filter)
+	}
+	if len(c.Quiet.Actors) != 1 || time.Duration(c.Quiet.SelfCommentWindow) != 2*time.Minute || c.Quiet.Enabled != nil {
+		t.Errorf("unexpected quiet config: %+v", c.Quiet)
+	}
+	if len(c.SLAs) != 1 || c.SLAs[0].Within != "1d" || c.Calendars[c.SLAs[0].Calendar].Hours != "08:00-16:00" {
+		t.Errorf("unexpected SLAs: %+v, calendars %+v", c.SLAs, c.Calendars)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"syntax", "{\n  \"filter\": \"x\",\n}", "line 3"},
+		{"unknown field", `{"filtre": "x"}`, `unknown field "filtre"`},
+		{"wrong type", `{"sinks": {"notify": {"filter": 1}}}`, "line 1"},
+		{"bad duration", `{"quiet": {"self_comment_window": "soon"}}`, "invalid duration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeConfig(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load error = %v, want mention of %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestLoadDefault(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	c, err := LoadDefault()
+	if err != nil || c.Filter != "" || len(c.Sinks) != 0 {
+		t.Errorf("expected an empty config without a file, got %+v, %v", c, err)
+	}
+
+	dir := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "ghostio")
+	os.MkdirAll(dir, 0o755)
+	os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"filter": "merged"}`), 0o644)
+	c, err = LoadDefault()
+	if err != nil || c.Filter != "merged" {
+		t.Errorf("expected the default file to be read, got %+v, %v", c, err)
+	}
+}
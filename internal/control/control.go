@@ -0,0 +1,171 @@
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Handler answers a single control command
+type Handler func() (any, error)
+
+// request is a command sent by a client
+type request struct {
+	Command string `json:"command"`
+}
+
+// response is the server's answer to a request
+type response struct {
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// DefaultSocketPath returns the per-user control socket path
+func DefaultSocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "ghostio.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("ghostio-%d.sock", os.Getuid()))
+}
+
+// Server serves control commands on a unix socket
+type Server struct {
+	path string
+	ln   net.Listener
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+
+	wg sync.WaitGroup
+}
+
+// Listen creates the control socket, replacing a stale one left by a dead process
+func Listen(path string) (*Server, error) {
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("control socket %s is in use by another process", path)
+	}
+	os.Remove(path)
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to secure %s: %w", path, err)
+	}
+
+	s := &Server{
+		path:     path,
+		ln:       ln,
+		handlers: make(map[string]Handler),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Handle registers the handler for a command
+func (s *Server) Handle(command string, h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[command] = h
+}
+
+// Close stops serving and removes the socket
+func (s *Server) Close() error {
+	err := s.ln.Close()
+	s.wg.Wait()
+	os.Remove(s.path)
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	var req request
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		return
+	}
+	var resp response
+	if err := json.Unmarshal(line, &req); err != nil {
+		resp.Error = fmt.Sprintf("invalid request: %v", err)
+	} else {
+		resp = s.dispatch(req.Command)
+	}
+	json.NewEncoder(conn).Encode(resp)
+}
+
+func (s *Server) dispatch(command string) response {
+	s.mu.RLock()
+	h, ok := s.handlers[command]
+	commands := make([]string, 0, len(s.handlers))
+	for name := range s.handlers {
+		commands = append(commands, name)
+	}
+	s.mu.RUnlock()
+
+	if !ok {
+		sort.Strings(commands)
+		return response{Error: fmt.Sprintf("unknown command %q (available: %v)", command, commands)}
+	}
+	result, err := h()
+	if err != nil {
+		return response{Error: err.Error()}
+	}
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return response{Error: fmt.Sprintf("failed to encode result: %v", err)}
+	}
+	return response{Result: raw}
+}
+
+// Call sends a command to the server at path and decodes its result into out
+func Call(path, command string, out any) error {
+	conn, err := net.DialTimeout("unix", path, 5*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s (is ghostio running?): %w", path, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(60 * time.Second))
+
+	if err := json.NewEncoder(conn).Encode(request{Command: command}); err != nil {
+		return fmt.Errorf("failed to send command: %w", err)
+	}
+	var resp response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	if out == nil || resp.Result == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, out)
+}
@@ -0,0 +1,60 @@
+package control
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ghostio.sock")
+	s, err := Listen(path)
+	if err != nil {
+		t.Fatalf("Listen() error: %v", err)
+	}
+	defer s.Close()
+
+	s.Handle("send-now", func() (any, error) {
+		return map[string]int{"sent": 3}, nil
+	})
+	s.Handle("fail", func() (any, error) {
+		return nil, errors.New("smtp unavailable")
+	})
+
+	var result map[string]int
+	if err := Call(path, "send-now", &result); err != nil {
+		t.Fatalf("Call() error: %v", err)
+	}
+	if result["sent"] != 3 {
+		t.Errorf("result = %v", result)
+	}
+
+	if err := Call(path, "fail", nil); err == nil || err.Error() != "smtp unavailable" {
+		t.Errorf("expected handler error, got %v", err)
+	}
+
+	if err := Call(path, "bogus", nil); err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Errorf("expected unknown command error, got %v", err)
+	}
+}
+
+func TestListenRejectsSocketInUse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ghostio.sock")
+	s, err := Listen(path)
+	if err != nil {
+		t.Fatalf("Listen() error: %v", err)
+	}
+	defer s.Close()
+
+	if _, err := Listen(path); err == nil {
+		t.Error("expected error when socket is in use")
+	}
+}
+
+func TestCallWithoutServer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.sock")
+	if err := Call(path, "status", nil); err == nil {
+		t.Error("expected error when no server is listening")
+	}
+}
@@ -0,0 +1,443 @@
+package dbus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// ObjectPath is a D-Bus object path
+type ObjectPath string
+
+// Variant is a value tagged with its D-Bus signature
+type Variant struct {
+	Sig   string
+	Value any
+}
+
+// nextType splits the first complete type off a signature
+func nextType(sig string) (string, string, error) {
+	if sig == "" {
+		return "", "", fmt.Errorf("empty signature")
+	}
+	switch sig[0] {
+	case 'y', 'b', 'n', 'q', 'i', 'u', 'x', 't', 'd', 's', 'o', 'g', 'v', 'h':
+		return sig[:1], sig[1:], nil
+	case 'a':
+		elem, rest, err := nextType(sig[1:])
+		if err != nil {
+			return "", "", err
+		}
+		return "a" + elem, rest, nil
+	case '(', '{':
+		closing := byte(')')
+		if sig[0] == '{' {
+			closing = '}'
+		}
+		rest := sig[1:]
+		for rest != "" && rest[0] != closing {
+			var err error
+			if _, rest, err = nextType(rest); err != nil {
+				return "", "", err
+			}
+		}
+		if rest == "" {
+			return "", "", fmt.Errorf("unterminated container in signature %q", sig)
+		}
+		n := len(sig) - len(rest) + 1
+		return sig[:n], sig[n:], nil
+	}
+	return "", "", fmt.Errorf("unsupported type %q in signature", sig[0])
+}
+
+// alignment returns the wire alignment of a single complete type
+func alignment(sig string) int {
+	switch sig[0] {
+	case 'y', 'g', 'v':
+		return 1
+	case 'n', 'q':
+		return 2
+	case 'x', 't', 'd', '(', '{':
+		return 8
+	}
+	return 4
+}
+
+// encoder writes values in little-endian wire format
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) align(n int) {
+	for len(e.buf)%n != 0 {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+func (e *encoder) uint32(v uint32) {
+	e.align(4)
+	e.buf = binary.LittleEndian.AppendUint32(e.buf, v)
+}
+
+// encodeAll encodes values according to a signature of zero or more types
+func (e *encoder) encodeAll(sig string, values []any) error {
+	for i := 0; sig != ""; i++ {
+		t, rest, err := nextType(sig)
+		if err != nil {
+			return err
+		}
+		if i >= len(values) {
+			return fmt.Errorf("missing value for type %q", t)
+		}
+		if err := e.encode(t, values[i]); err != nil {
+			return err
+		}
+		sig = rest
+	}
+	return nil
+}
+
+// encode encodes a single value of a single complete type
+func (e *encoder) encode(sig string, v any) error {
+	switch sig[0] {
+	case 'y':
+		b, ok := v.(byte)
+		if !ok {
+			return typeError(sig, v)
+		}
+		e.buf = append(e.buf, b)
+	case 'b':
+		b, ok := v.(bool)
+		if !ok {
+			return typeError(sig, v)
+		}
+		var n uint32
+		if b {
+			n = 1
+		}
+		e.uint32(n)
+	case 'n', 'q':
+		var n uint16
+		switch x := v.(type) {
+		case int16:
+			n = uint16(x)
+		case uint16:
+			n = x
+		default:
+			return typeError(sig, v)
+		}
+		e.align(2)
+		e.buf = binary.LittleEndian.AppendUint16(e.buf, n)
+	case 'i', 'u', 'h':
+		var n uint32
+		switch x := v.(type) {
+		case int32:
+			n = uint32(x)
+		case uint32:
+			n = x
+		default:
+			return typeError(sig, v)
+		}
+		e.uint32(n)
+	case 'x', 't':
+		var n uint64
+		switch x := v.(type) {
+		case int64:
+			n = uint64(x)
+		case uint64:
+			n = x
+		default:
+			return typeError(sig, v)
+		}
+		e.align(8)
+		e.buf = binary.LittleEndian.AppendUint64(e.buf, n)
+	case 's', 'o':
+		var s string
+		switch x := v.(type) {
+		case string:
+			s = x
+		case ObjectPath:
+			s = string(x)
+		default:
+			return typeError(sig, v)
+		}
+		e.uint32(uint32(len(s)))
+		e.buf = append(e.buf, s...)
+		e.buf = append(e.buf, 0)
+	case 'g':
+		s, ok := v.(string)
+		if !ok {
+			return typeError(sig, v)
+		}
+		e.buf = append(e.buf, byte(len(s)))
+		e.buf = append(e.buf, s...)
+		e.buf = append(e.buf, 0)
+	case 'v':
+		variant, ok := v.(Variant)
+		if !ok {
+			return typeError(sig, v)
+		}
+		if err := e.encode("g", variant.Sig); err != nil {
+			return err
+		}
+		return e.encode(variant.Sig, variant.Value)
+	case '(', '{':
+		fields, ok := v.([]any)
+		if !ok {
+			return typeError(sig, v)
+		}
+		e.align(8)
+		return e.encodeAll(sig[1:len(sig)-1], fields)
+	case 'a':
+		return e.encodeArray(sig[1:], v)
+	default:
+		return fmt.Errorf("unsupported type %q", sig)
+	}
+	return nil
+}
+
+func (e *encoder) encodeArray(elem string, v any) error {
+	e.align(4)
+	lenPos := len(e.buf)
+	e.buf = append(e.buf, 0, 0, 0, 0)
+	e.align(alignment(elem))
+	start := len(e.buf)
+
+	switch x := v.(type) {
+	case []string:
+		for _, s := range x {
+			if err := e.encode(elem, s); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range x {
+			if err := e.encode(elem, item); err != nil {
+				return err
+			}
+		}
+	case map[string]Variant:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := e.encode(elem, []any{k, x[k]}); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := e.encode(elem, []any{k, x[k]}); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		return typeError("a"+elem, v)
+	}
+
+	binary.LittleEndian.PutUint32(e.buf[lenPos:], uint32(len(e.buf)-start))
+	return nil
+}
+
+func typeError(sig string, v any) error {
+	return fmt.Errorf("cannot encode %T as %q", v, sig)
+}
+
+type decoder struct {
+	buf   []byte
+	pos   int
+	order binary.ByteOrder
+}
+
+func (d *decoder) align(n int) error {
+	for d.pos%n != 0 {
+		d.pos++
+	}
+	if d.pos > len(d.buf) {
+		return fmt.Errorf("unexpected end of data")
+	}
+	return nil
+}
+
+func (d *decoder) take(n int) ([]byte, error) {
+	if d.pos+n > len(d.buf) {
+		return nil, fmt.Errorf("unexpected end of data")
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *decoder) uint32() (uint32, error) {
+	if err := d.align(4); err != nil {
+		return 0, err
+	}
+	b, err := d.take(4)
+	if err != nil {
+		return 0, err
+	}
+	return d.order.Uint32(b), nil
+}
+
+// decodeAll decodes values for a signature of zero or more types
+func (d *decoder) decodeAll(sig string) ([]any, error) {
+	var values []any
+	for sig != "" {
+		t, rest, err := nextType(sig)
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.decode(t)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		sig = rest
+	}
+	return values, nil
+}
+
+// decode decodes a single value of a single complete type
+func (d *decoder) decode(sig string) (any, error) {
+	switch sig[0] {
+	case 'y':
+		b, err := d.take(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0], nil
+	case 'b':
+		n, err := d.uint32()
+		return n != 0, err
+	case 'n', 'q':
+		if err := d.align(2); err != nil {
+			return nil, err
+		}
+		b, err := d.take(2)
+		if err != nil {
+			return nil, err
+		}
+		if sig[0] == 'n' {
+			return int16(d.order.Uint16(b)), nil
+		}
+		return d.order.Uint16(b), nil
+	case 'i':
+		n, err := d.uint32()
+		return int32(n), err
+	case 'u', 'h':
+		return d.uint32()
+	case 'x', 't':
+		if err := d.align(8); err != nil {
+			return nil, err
+		}
+		b, err := d.take(8)
+		if err != nil {
+			return nil, err
+		}
+		if sig[0] == 'x' {
+			return int64(d.order.Uint64(b)), nil
+		}
+		return d.order.Uint64(b), nil
+	case 's', 'o':
+		n, err := d.uint32()
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.take(int(n) + 1)
+		if err != nil {
+			return nil, err
+		}
+		if sig[0] == 'o' {
+			return ObjectPath(b[:n]), nil
+		}
+		return string(b[:n]), nil
+	case 'g':
+		l, err := d.take(1)
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.take(int(l[0]) + 1)
+		if err != nil {
+			return nil, err
+		}
+		return string(b[:l[0]]), nil
+	case 'v':
+		s, err := d.decode("g")
+		if err != nil {
+			return nil, err
+		}
+		inner := s.(string)
+		if _, rest, err := nextType(inner); err != nil || rest != "" {
+			return nil, fmt.Errorf("invalid variant signature %q", inner)
+		}
+		v, err := d.decode(inner)
+		if err != nil {
+			return nil, err
+		}
+		return Variant{Sig: inner, Value: v}, nil
+	case '(', '{':
+		if err := d.align(8); err != nil {
+			return nil, err
+		}
+		return d.decodeAll(sig[1 : len(sig)-1])
+	case 'a':
+		return d.decodeArray(sig[1:])
+	}
+	return nil, fmt.Errorf("unsupported type %q", sig)
+}
+
+func (d *decoder) decodeArray(elem string) (any, error) {
+	n, err := d.uint32()
+	if err != nil {
+		return nil, err
+	}
+	if err := d.align(alignment(elem)); err != nil {
+		return nil, err
+	}
+	end := d.pos + int(n)
+	if end > len(d.buf) {
+		return nil, fmt.Errorf("array exceeds message bounds")
+	}
+
+	switch {
+	case elem == "s":
+		items := []string{}
+		for d.pos < end {
+			v, err := d.decode(elem)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v.(string))
+		}
+		return items, nil
+	case elem[0] == '{' && elem[1] == 's':
+		items := map[string]any{}
+		for d.pos < end {
+			v, err := d.decode(elem)
+			if err != nil {
+				return nil, err
+			}
+			entry := v.([]any)
+			items[entry[0].(string)] = entry[1]
+		}
+		return items, nil
+	}
+
+	items := []any{}
+	for d.pos < end {
+		v, err := d.decode(elem)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+	}
+	return items, nil
+}
@@ -0,0 +1,303 @@
+package dbus
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned for calls on a closed connection
+var ErrClosed = errors.New("dbus: connection closed")
+
+// Error is a D-Bus error reply
+type Error struct {
+	Name    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return e.Name
+	}
+	return fmt.Sprintf("%s: %s", e.Name, e.Message)
+}
+
+// Conn is a connection to a message bus
+type Conn struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	serial  uint32
+	pending map[uint32]chan *Message
+	closed  bool
+	err     error
+
+	signals chan *Message
+	done    chan struct{}
+
+	// Name is the unique name assigned by the bus
+	Name string
+}
+
+// SessionBusAddress returns the address of the session bus
+func SessionBusAddress() (string, error) {
+	if addr := os.Getenv("DBUS_SESSION_BUS_ADDRESS"); addr != "" {
+		return addr, nil
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return "unix:path=" + dir + "/bus", nil
+	}
+	return "", errors.New("DBUS_SESSION_BUS_ADDRESS is not set")
+}
+
+// Dial connects to the bus at addr, authenticates, and registers with it
+func Dial(ctx context.Context, addr string) (*Conn, error) {
+	var lastErr error
+	for _, entry := range strings.Split(addr, ";") {
+		network, path, err := parseAddress(entry)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		var d net.Dialer
+		nc, err := d.DialContext(ctx, network, path)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		c, err := newConn(ctx, nc)
+		if err != nil {
+			nc.Close()
+			return nil, err
+		}
+		return c, nil
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no usable address in %q", addr)
+	}
+	return nil, fmt.Errorf("failed to connect to bus: %w", lastErr)
+}
+
+// parseAddress converts a unix transport address into a dialable socket path
+func parseAddress(addr string) (string, string, error) {
+	transport, params, ok := strings.Cut(addr, ":")
+	if !ok || transport != "unix" {
+		return "", "", fmt.Errorf("unsupported bus address %q", addr)
+	}
+	for _, kv := range strings.Split(params, ",") {
+		key, value, _ := strings.Cut(kv, "=")
+		switch key {
+		case "path":
+			return "unix", value, nil
+		case "abstract":
+			return "unix", "@" + value, nil
+		}
+	}
+	return "", "", fmt.Errorf("unsupported bus address %q", addr)
+}
+
+func newConn(ctx context.Context, nc net.Conn) (*Conn, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		nc.SetDeadline(deadline)
+	}
+	r := bufio.NewReader(nc)
+	if err := authenticate(nc, r); err != nil {
+		return nil, err
+	}
+	nc.SetDeadline(time.Time{})
+
+	c := &Conn{
+		conn:    nc,
+		pending: make(map[uint32]chan *Message),
+		signals: make(chan *Message, 64),
+		done:    make(chan struct{}),
+	}
+	go c.readLoop(r)
+
+	reply, err := c.Call(ctx, "org.freedesktop.DBus", "/org/freedesktop/DBus",
+		"org.freedesktop.DBus", "Hello", "")
+	if err != nil {
+		c.Close()
+		return nil, fmt.Errorf("hello failed: %w", err)
+	}
+	if len(reply) > 0 {
+		c.Name, _ = reply[0].(string)
+	}
+	return c, nil
+}
+
+// authenticate performs SASL EXTERNAL authentication
+func authenticate(nc net.Conn, r *bufio.Reader) error {
+	uid := strconv.Itoa(os.Getuid())
+	if _, err := fmt.Fprintf(nc, "\x00AUTH EXTERNAL %x\r\n", uid); err != nil {
+		return fmt.Errorf("auth failed: %w", err)
+	}
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("auth failed: %w", err)
+	}
+	if !strings.HasPrefix(line, "OK ") {
+		return fmt.Errorf("auth rejected: %s", strings.TrimSpace(line))
+	}
+	if _, err := fmt.Fprint(nc, "BEGIN\r\n"); err != nil {
+		return fmt.Errorf("auth failed: %w", err)
+	}
+	return nil
+}
+
+// Signals returns a channel that receives signals matched by AddMatch
+func (c *Conn) Signals() <-chan *Message {
+	return c.signals
+}
+
+// AddMatch asks the bus to route signals matching rule to this connection
+func (c *Conn) AddMatch(ctx context.Context, rule string) error {
+	_, err := c.Call(ctx, "org.freedesktop.DBus", "/org/freedesktop/DBus",
+		"org.freedesktop.DBus", "AddMatch", "s", rule)
+	return err
+}
+
+// Call invokes a method and waits for its reply
+func (c *Conn) Call(ctx context.Context, dest string, path ObjectPath, iface, member, sig string, args ...any) ([]any, error) {
+	ch := make(chan *Message, 1)
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrClosed
+	}
+	c.serial++
+	serial := c.serial
+	c.pending[serial] = ch
+	c.mu.Unlock()
+
+	msg := &Message{
+		Type:        TypeMethodCall,
+		Serial:      serial,
+		Path:        path,
+		Interface:   iface,
+		Member:      member,
+		Destination: dest,
+		Signature:   sig,
+		Body:        args,
+	}
+	if err := c.Send(msg); err != nil {
+		c.forget(serial)
+		return nil, err
+	}
+
+	select {
+	case reply := <-ch:
+		if reply == nil {
+			return nil, c.closeErr()
+		}
+		if reply.Type == TypeError {
+			e := &Error{Name: reply.ErrorName}
+			if len(reply.Body) > 0 {
+				e.Message, _ = reply.Body[0].(string)
+			}
+			return nil, e
+		}
+		return reply.Body, nil
+	case <-ctx.Done():
+		c.forget(serial)
+		return nil, ctx.Err()
+	}
+}
+
+// Send writes a message without waiting for a reply
+func (c *Conn) Send(msg *Message) error {
+	if msg.Serial == 0 {
+		c.mu.Lock()
+		c.serial++
+		msg.Serial = c.serial
+		c.mu.Unlock()
+	}
+	b, err := msg.Marshal()
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := c.conn.Write(b); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
+
+// Close closes the connection
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.mu.Unlock()
+	err := c.conn.Close()
+	<-c.done
+	return err
+}
+
+func (c *Conn) forget(serial uint32) {
+	c.mu.Lock()
+	delete(c.pending, serial)
+	c.mu.Unlock()
+}
+
+func (c *Conn) closeErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	return ErrClosed
+}
+
+// readLoop dispatches replies to waiting callers and signals to the signal channel
+func (c *Conn) readLoop(r *bufio.Reader) {
+	defer close(c.done)
+	defer close(c.signals)
+
+	for {
+		msg, err := ReadMessage(r)
+		if err != nil {
+			c.mu.Lock()
+			c.closed = true
+			if c.err == nil {
+				c.err = err
+			}
+			for serial, ch := range c.pending {
+				close(ch)
+				delete(c.pending, serial)
+			}
+			c.mu.Unlock()
+			return
+		}
+
+		switch msg.Type {
+		case TypeMethodReturn, TypeError:
+			c.mu.Lock()
+			ch, ok := c.pending[msg.ReplySerial]
+			delete(c.pending, msg.ReplySerial)
+			c.mu.Unlock()
+			if ok {
+				ch <- msg
+			}
+		case TypeSignal:
+			select {
+			case c.signals <- msg:
+			default:
+				// Drop signals nobody is reading
+			}
+		}
+	}
+}
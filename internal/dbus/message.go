@@ -0,0 +1,176 @@
+package dbus
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Message types
+const (
+	TypeMethodCall   byte = 1
+	TypeMethodReturn byte = 2
+	TypeError        byte = 3
+	TypeSignal       byte = 4
+)
+
+// FlagNoReplyExpected marks a method call that does not want a reply
+const FlagNoReplyExpected byte = 0x1
+
+// Header field codes
+const (
+	fieldPath        byte = 1
+	fieldInterface   byte = 2
+	fieldMember      byte = 3
+	fieldErrorName   byte = 4
+	fieldReplySerial byte = 5
+	fieldDestination byte = 6
+	fieldSender      byte = 7
+	fieldSignature   byte = 8
+)
+
+// maxMessageSize is the largest message the protocol allows
+const maxMessageSize = 128 * 1024 * 1024
+
+// Message is a single D-Bus message
+type Message struct {
+	Type        byte
+	Flags       byte
+	Serial      uint32
+	Path        ObjectPath
+	Interface   string
+	Member      string
+	ErrorName   string
+	ReplySerial uint32
+	Destination string
+	Sender      string
+	Signature   string
+	Body        []any
+}
+
+// Marshal encodes the message in little-endian wire format
+func (m *Message) Marshal() ([]byte, error) {
+	body := &encoder{}
+	if err := body.encodeAll(m.Signature, m.Body); err != nil {
+		return nil, fmt.Errorf("failed to encode body: %w", err)
+	}
+
+	var fields []any
+	addField := func(code byte, sig string, value any) {
+		fields = append(fields, []any{code, Variant{Sig: sig, Value: value}})
+	}
+	if m.Path != "" {
+		addField(fieldPath, "o", m.Path)
+	}
+	if m.Interface != "" {
+		addField(fieldInterface, "s", m.Interface)
+	}
+	if m.Member != "" {
+		addField(fieldMember, "s", m.Member)
+	}
+	if m.ErrorName != "" {
+		addField(fieldErrorName, "s", m.ErrorName)
+	}
+	if m.ReplySerial != 0 {
+		addField(fieldReplySerial, "u", m.ReplySerial)
+	}
+	if m.Destination != "" {
+		addField(fieldDestination, "s", m.Destination)
+	}
+	if m.Sender != "" {
+		addField(fieldSender, "s", m.Sender)
+	}
+	if m.Signature != "" {
+		addField(fieldSignature, "g", m.Signature)
+	}
+
+	head := &encoder{}
+	head.buf = append(head.buf, 'l', m.Type, m.Flags, 1)
+	if err := head.encode("u", uint32(len(body.buf))); err != nil {
+		return nil, err
+	}
+	if err := head.encode("u", m.Serial); err != nil {
+		return nil, err
+	}
+	if err := head.encode("a(yv)", fields); err != nil {
+		return nil, fmt.Errorf("failed to encode header: %w", err)
+	}
+	head.align(8)
+
+	return append(head.buf, body.buf...), nil
+}
+
+// ReadMessage reads a single message from r
+func ReadMessage(r io.Reader) (*Message, error) {
+	fixed := make([]byte, 16)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, err
+	}
+
+	var order binary.ByteOrder
+	switch fixed[0] {
+	case 'l':
+		order = binary.LittleEndian
+	case 'B':
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("invalid endianness marker %q", fixed[0])
+	}
+
+	bodyLen := order.Uint32(fixed[4:8])
+	fieldsLen := order.Uint32(fixed[12:16])
+	headerLen := 16 + int(fieldsLen)
+	padded := (headerLen + 7) &^ 7
+	if uint64(padded)+uint64(bodyLen) > maxMessageSize {
+		return nil, errors.New("message exceeds maximum size")
+	}
+
+	buf := make([]byte, padded+int(bodyLen))
+	copy(buf, fixed)
+	if _, err := io.ReadFull(r, buf[16:]); err != nil {
+		return nil, err
+	}
+
+	m := &Message{
+		Type:   fixed[1],
+		Flags:  fixed[2],
+		Serial: order.Uint32(fixed[8:12]),
+	}
+
+	dec := &decoder{buf: buf[:headerLen], pos: 12, order: order}
+	raw, err := dec.decode("a(yv)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode header: %w", err)
+	}
+	for _, f := range raw.([]any) {
+		field := f.([]any)
+		v := field[1].(Variant).Value
+		switch field[0].(byte) {
+		case fieldPath:
+			m.Path, _ = v.(ObjectPath)
+		case fieldInterface:
+			m.Interface, _ = v.(string)
+		case fieldMember:
+			m.Member, _ = v.(string)
+		case fieldErrorName:
+			m.ErrorName, _ = v.(string)
+		case fieldReplySerial:
+			m.ReplySerial, _ = v.(uint32)
+		case fieldDestination:
+			m.Destination, _ = v.(string)
+		case fieldSender:
+			m.Sender, _ = v.(string)
+		case fieldSignature:
+			m.Signature, _ = v.(string)
+		}
+	}
+
+	body := &decoder{buf: buf[padded:], order: order}
+	m.Body, err = body.decodeAll(m.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode body: %w", err)
+	}
+
+	return m, nil
+}
@@ -0,0 +1,104 @@
+package dbus
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestMessageRoundTrip(t *testing.T) {
+	msg := &Message{
+		Type:        TypeMethodCall,
+		Serial:      7,
+		Path:        "/org/freedesktop/Notifications",
+		Interface:   "org.freedesktop.Notifications",
+		Member:      "Notify",
+		Destination: "org.freedesktop.Notifications",
+		Signature:   "susssasa{sv}i",
+		Body: []any{
+			"ghostio", uint32(0), "", "summary", "body",
+			[]string{"default", "Open"},
+			map[string]Variant{"urgency": {Sig: "y", Value: byte(2)}},
+			int32(-1),
+		},
+	}
+
+	b, err := msg.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() error: %v", err)
+	}
+	got, err := ReadMessage(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("ReadMessage() error: %v", err)
+	}
+
+	if got.Type != msg.Type || got.Serial != msg.Serial || got.Path != msg.Path ||
+		got.Interface != msg.Interface || got.Member != msg.Member ||
+		got.Destination != msg.Destination || got.Signature != msg.Signature {
+		t.Errorf("header mismatch: got %+v", got)
+	}
+
+	want := []any{
+		"ghostio", uint32(0), "", "summary", "body",
+		[]string{"default", "Open"},
+		map[string]any{"urgency": Variant{Sig: "y", Value: byte(2)}},
+		int32(-1),
+	}
+	if !reflect.DeepEqual(got.Body, want) {
+		t.Errorf("body = %#v, want %#v", got.Body, want)
+	}
+}
+
+func TestNextType(t *testing.T) {
+	tests := []struct {
+		sig   string
+		first string
+		rest  string
+	}{
+		{"su", "s", "u"},
+		{"asi", "as", "i"},
+		{"a{sv}i", "a{sv}", "i"},
+		{"(yv)s", "(yv)", "s"},
+		{"aa(sa{sv})", "aa(sa{sv})", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sig, func(t *testing.T) {
+			first, rest, err := nextType(tt.sig)
+			if err != nil {
+				t.Fatalf("nextType(%q) error: %v", tt.sig, err)
+			}
+			if first != tt.first || rest != tt.rest {
+				t.Errorf("nextType(%q) = %q, %q, want %q, %q", tt.sig, first, rest, tt.first, tt.rest)
+			}
+		})
+	}
+
+	if _, _, err := nextType("(su"); err == nil {
+		t.Error("expected error for unterminated struct")
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		addr    string
+		path    string
+		wantErr bool
+	}{
+		{"unix:path=/run/user/1000/bus", "/run/user/1000/bus", false},
+		{"unix:abstract=/tmp/dbus-x,guid=abc", "@/tmp/dbus-x", false},
+		{"tcp:host=localhost,port=1234", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			_, path, err := parseAddress(tt.addr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAddress(%q) error = %v, wantErr %v", tt.addr, err, tt.wantErr)
+			}
+			if path != tt.path {
+				t.Errorf("parseAddress(%q) path = %q, want %q", tt.addr, path, tt.path)
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+// Package digest summarizes a period of repository activity as a report: issues opened
+// and closed, pull requests merged, releases, the most active commenters, pull requests
+// waiting for review and first-time contributors.
+package digest
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// DefaultStale is how long a pull request waits for review before the report lists it
+const DefaultStale = 3 * 24 * time.Hour
+
+// DefaultTop is how many commenters the report lists
+const DefaultTop = 5
+
+// Config selects what a report covers
+type Config struct {
+	Repo string
+	// From and To bound the period; events at From are included, events at To are not
+	From, To time.Time
+	// Stale is how long pull requests must have waited for review; zero uses DefaultStale
+	Stale time.Duration
+	// Top is how many commenters to list; zero uses DefaultTop
+	Top int
+}
+
+// Report is the activity of a repository over a period
+type Report struct {
+	Repo                  string        `json:"repo"`
+	From                  time.Time     `json:"from"`
+	To                    time.Time     `json:"to"`
+	OpenedIssues          []Item        `json:"opened_issues"`
+	ClosedIssues          []Item        `json:"closed_issues"`
+	MergedPullRequests    []Item        `json:"merged_pull_requests"`
+	Releases              []Release     `json:"releases"`
+	TopCommenters         []Commenter   `json:"top_commenters"`
+	StalePullRequests     []Pending     `json:"stale_pull_requests"`
+	FirstTimeContributors []Contributor `json:"first_time_contributors"`
+}
+
+// Item is an issue or pull request
+type Item struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	Author string `json:"author"`
+	// By is who closed or merged it, when that is not the author
+	By string    `json:"by,omitempty"`
+	At time.Time `json:"at"`
+}
+
+// Release is a published release
+type Release struct {
+	Tag    string    `json:"tag"`
+	Name   string    `json:"name,omitempty"`
+	URL    string    `json:"url"`
+	Author string    `json:"author"`
+	At     time.Time `json:"at"`
+}
+
+// Commenter is someone who commented or reviewed during the period
+type Commenter struct {
+	Login    string `json:"login"`
+	Comments int    `json:"comments"`
+}
+
+// Pending is an open pull request waiting for review
+type Pending struct {
+	Number    int      `json:"number"`
+	Title     string   `json:"title"`
+	URL       string   `json:"url"`
+	Author    string   `json:"author"`
+	Reviewers []string `json:"reviewers"`
+	// Since is when a review was last requested
+	Since time.Time `json:"since"`
+}
+
+// Contributor is someone whose first pull request to the repository was opened during the period
+type Contributor struct {
+	Login       string `json:"login"`
+	PullRequest Item   `json:"pull_request"`
+}
+
+// isBot reports whether a login belongs to a GitHub App
+func isBot(login string) bool {
+	return strings.HasSuffix(login, "[bot]")
+}
+
+// Build summarizes the events of the period. pending are the open pull requests with
+// requested reviews; those idle since before To minus Stale are reported as stale.
+func Build(config Config, events []watcher.Event, pending []Pending) Report {
+	if config.Stale == 0 {
+		config.Stale = DefaultStale
+	}
+	if config.Top == 0 {
+		config.Top = DefaultTop
+	}
+	r := Report{
+		Repo:                  config.Repo,
+		From:                  config.From,
+		To:                    config.To,
+		OpenedIssues:          []Item{},
+		ClosedIssues:          []Item{},
+		MergedPullRequests:    []Item{},
+		Releases:              []Release{},
+		TopCommenters:         []Commenter{},
+		StalePullRequests:     []Pending{},
+		FirstTimeContributors: []Contributor{},
+	}
+
+	events = slices.Clone(events)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].CreatedAt.Before(events[j].CreatedAt) })
+	closed := map[int]Item{}
+	comments := map[string]int{}
+	firstTimers := map[string]bool{}
+	for _, e := range events {
+		if e.Repo.Name != config.Repo || e.CreatedAt.Before(config.From) || !e.CreatedAt.Before(config.To) {
+			continue
+		}
+		p := e.Payload
+		switch {
+		case e.Type == "IssuesEvent" && p.Issue != nil:
+			item := Item{Number: p.Issue.Number, Title: p.Issue.Title, URL: p.Issue.HTMLURL, Author: p.Issue.User.Login, At: e.CreatedAt}
+			if item.Author == "" {
+				item.Author = e.Actor.Login
+			}
+			switch p.Action {
+			case "opened":
+				r.OpenedIssues = append(r.OpenedIssues, item)
+			case "closed":
+				if e.Actor.Login != item.Author {
+					item.By = e.Actor.Login
+				}
+				closed[item.Number] = item
+			case "reopened":
+				delete(closed, item.Number)
+			}
+		case e.Type == "PullRequestEvent" && p.PullRequest != nil:
+			pr := p.PullRequest
+			item := Item{Number: pr.Number, Title: pr.Title, URL: pr.HTMLURL, Author: pr.User.Login, At: e.CreatedAt}
+			if item.Author == "" {
+				item.Author = e.Actor.Login
+			}
+			switch {
+			case p.Action == "closed" && pr.Merged:
+				if e.Actor.Login != item.Author {
+					item.By = e.Actor.Login
+				}
+				r.MergedPullRequests = append(r.MergedPullRequests, item)
+			case p.Action == "opened" && isFirstTime(pr.AuthorAssociation) && !isBot(item.Author) && !firstTimers[item.Author]:
+				firstTimers[item.Author] = true
+				r.FirstTimeContributors = append(r.FirstTimeContributors, Contributor{Login: item.Author, PullRequest: item})
+			}
+		case e.Type == "ReleaseEvent" && p.Release != nil && p.Action == "published":
+			r.Releases = append(r.Releases, Release{
+				Tag:    p.Release.TagName,
+				Name:   p.Release.Name,
+				URL:    p.Release.HTMLURL,
+				Author: e.Actor.Login,
+				At:     e.CreatedAt,
+			})
+		case e.Type == "IssueCommentEvent" || e.Type == "PullRequestReviewCommentEvent" || e.Type == "PullRequestReviewEvent":
+			if login := e.Actor.Login; login != "" && !isBot(login) {
+				comments[login]++
+			}
+		}
+	}
+
+	for _, item := range closed {
+		r.ClosedIssues = append(r.ClosedIssues, item)
+	}
+	sort.Slice(r.ClosedIssues, func(i, j int) bool { return r.ClosedIssues[i].At.Before(r.ClosedIssues[j].At) })
+
+	for login, n := range comments {
+		r.TopCommenters = append(r.TopCommenters, Commenter{Login: login, Comments: n})
+	}
+	sort.Slice(r.TopCommenters, func(i, j int) bool {
+		a, b := r.TopCommenters[i], r.TopCommenters[j]
+		if a.Comments != b.Comments {
+			return a.Comments > b.Comments
+		}
+		return a.Login < b.Login
+	})
+	if len(r.TopCommenters) > config.Top {
+		r.TopCommenters = r.TopCommenters[:config.Top]
+	}
+
+	for _, p := range pending {
+		if len(p.Reviewers) > 0 && !p.Since.After(config.To.Add(-config.Stale)) {
+			r.StalePullRequests = append(r.StalePullRequests, p)
+		}
+	}
+	// Longest waiting first
+	sort.SliceStable(r.StalePullRequests, func(i, j int) bool {
+		return r.StalePullRequests[i].Since.Before(r.StalePullRequests[j].Since)
+	})
+	return r
+}
+
+// isFirstTime reports whether an author association marks a first contribution
+func isFirstTime(association string) bool {
+	return association == "FIRST_TIME_CONTRIBUTOR" || association == "FIRST_TIMER"
+}
+
+// PendingFromAPI returns the open pull requests that have reviews requested. requested
+// maps pull request numbers to when a review was last requested; pull requests missing
+// from it are taken to wait since they were opened.
+func PendingFromAPI(prs []watcher.OpenPullRequest, requested map[int]time.Time) []Pending {
+	var pending []Pending
+	for _, pr := range prs {
+		if pr.Draft {
+			continue
+		}
+		p := Pending{Number: pr.Number, Title: pr.Title, URL: pr.HTMLURL, Author: pr.User.Login, Since: pr.CreatedAt}
+		if at, ok := requested[pr.Number]; ok {
+			p.Since = at
+		}
+		for _, r := range pr.RequestedReviewers {
+			p.Reviewers = append(p.Reviewers, r.Login)
+		}
+		for _, t := range pr.RequestedTeams {
+			p.Reviewers = append(p.Reviewers, t.Slug)
+		}
+		if len(p.Reviewers) > 0 {
+			pending = append(pending, p)
+		}
+	}
+	return pending
+}
+
+// ReviewRequestedAt returns when a review was last requested among a pull request's
+// issue events, or the zero time when none was
+func ReviewRequestedAt(events []watcher.IssueEvent) time.Time {
+	var at time.Time
+	for _, e := range events {
+		if e.Event == "review_requested" && e.CreatedAt.After(at) {
+			at = e.CreatedAt
+		}
+	}
+	return at
+}
+
+// PendingFromEvents returns the pull requests whose latest enriched event in repo shows
+// them open, ready and with reviews requested. Pull requests without enriched events
+// are not known to wait. They wait since the last review_requested event, or else since
+// the first enriched event that showed reviewers requested.
+func PendingFromEvents(repo string, events []watcher.Event) []Pending {
+	sorted := slices.Clone(events)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].CreatedAt.Before(sorted[j].CreatedAt) })
+
+	latest := map[int]watcher.Event{}
+	requested := map[int]time.Time{}
+	for _, e := range sorted {
+		if e.Repo.Name != repo || (e.Payload.PullRequest == nil && (e.Payload.Issue == nil || !e.Payload.Issue.IsPullRequest())) {
+			continue
+		}
+		n := e.Number()
+		if e.Type == "PullRequestEvent" && e.Payload.Action == "review_requested" {
+			requested[n] = e.CreatedAt
+		}
+		if e.Details == nil {
+			continue
+		}
+		switch {
+		case len(e.Details.RequestedReviewers) == 0:
+			delete(requested, n)
+		case requested[n].IsZero():
+			requested[n] = e.CreatedAt
+		}
+		latest[n] = e
+	}
+
+	var pending []Pending
+	for n, e := range latest {
+		d := e.Details
+		if d.State != "open" || d.Draft || len(d.RequestedReviewers) == 0 {
+			continue
+		}
+		p := Pending{Number: n, Title: e.Title(), Reviewers: d.RequestedReviewers, Since: requested[n]}
+		if pr := e.Payload.PullRequest; pr != nil {
+			p.URL, p.Author = pr.HTMLURL, pr.User.Login
+		} else {
+			p.URL, p.Author = e.Payload.Issue.HTMLURL, e.Payload.Issue.User.Login
+		}
+		pending = append(pending, p)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Number < pending[j].Number })
+	return pending
+}
@@ -0,0 +1,159 @@
+package digest
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+var end = time.Date(2024, 5, 8, 0, 0, 0, 0, time.UTC)
+
+func ago(days float64) time.Time {
+	return end.Add(-time.Duration(days * float64(24*time.Hour)))
+}
+
+func issueEvent(action, actor string, number int, at time.Time) watcher.Event {
+	return watcher.Event{
+		Type: "IssuesEvent", Actor: watcher.Actor{Login: actor}, Repo: watcher.Repo{Name: "org/api"}, CreatedAt: at,
+		Payload: watcher.Payload{Action: action, Issue: &watcher.Issue{
+			Number: number, Title: "Crash", HTMLURL: "https://github.com/org/api/issues/3", User: watcher.Actor{Login: "alice"},
+		}},
+	}
+}
+
+func prEvent(action, actor string, pr watcher.PullRequest, at time.Time) watcher.Event {
+	return watcher.Event{
+		Type: "PullRequestEvent", Actor: watcher.Actor{Login: actor}, Repo: watcher.Repo{Name: "org/api"}, CreatedAt: at,
+		Payload: watcher.Payload{Action: action, PullRequest: &pr},
+	}
+}
+
+func comment(actor string, at time.Time) watcher.Event {
+	return watcher.Event{Type: "IssueCommentEvent", Actor: watcher.Actor{Login: actor}, Repo: watcher.Repo{Name: "org/api"}, CreatedAt: at}
+}
+
+func TestBuild(t *testing.T) {
+	fix := watcher.PullRequest{Number: 7, Title: "Fix crash", HTMLURL: "https://github.com/org/api/pull/7", User: watcher.Actor{Login: "erin"},
+		AuthorAssociation: "FIRST_TIME_CONTRIBUTOR", Merged: true}
+	events := []watcher.Event{
+		issueEvent("opened", "alice", 3, ago(6)),
+		issueEvent("closed", "bob", 3, ago(2)),
+		issueEvent("closed", "alice", 4, ago(3)),
+		issueEvent("reopened", "alice", 4, ago(1)),
+		issueEvent("opened", "alice", 5, ago(9)),
+		prEvent("opened", "erin", fix, ago(5)),
+		prEvent("closed", "bob", fix, ago(4)),
+		{Type: "ReleaseEvent", Actor: watcher.Actor{Login: "bob"}, Repo: watcher.Repo{Name: "org/api"}, CreatedAt: ago(0.5),
+			Payload: watcher.Payload{Action: "published", Release: &watcher.Release{TagName: "v1.2.0", Name: "v1.2.0"}}},
+		comment("bob", ago(1)), comment("bob", ago(2)), comment("carol", ago(1)),
+		comment("dependabot[bot]", ago(1)), comment("dave", ago(8)),
+		{Type: "IssueCommentEvent", Actor: watcher.Actor{Login: "zed"}, Repo: watcher.Repo{Name: "org/web"}, CreatedAt: ago(1)},
+	}
+	pending := []Pending{
+		{Number: 8, Author: "carol", Reviewers: []string{"bob"}, Since: ago(4)},
+		{Number: 9, Author: "carol", Reviewers: []string{"bob"}, Since: ago(1)},
+		{Number: 10, Title: "Cache", Author: "carol", Reviewers: []string{"core"}, Since: ago(10)},
+	}
+	r := Build(Config{Repo: "org/api", From: ago(7), To: end}, events, pending)
+
+	if len(r.OpenedIssues) != 1 || r.OpenedIssues[0].Number != 3 {
+		t.Errorf("opened issues = %+v", r.OpenedIssues)
+	}
+	// Reopened issues are no longer closed; closing someone else's issue credits the closer
+	if len(r.ClosedIssues) != 1 || r.ClosedIssues[0].Number != 3 || r.ClosedIssues[0].By != "bob" {
+		t.Errorf("closed issues = %+v", r.ClosedIssues)
+	}
+	if len(r.MergedPullRequests) != 1 || r.MergedPullRequests[0].Author != "erin" || r.MergedPullRequests[0].By != "bob" {
+		t.Errorf("merged = %+v", r.MergedPullRequests)
+	}
+	if len(r.Releases) != 1 || r.Releases[0].Tag != "v1.2.0" {
+		t.Errorf("releases = %+v", r.Releases)
+	}
+	if len(r.TopCommenters) != 2 || r.TopCommenters[0] != (Commenter{"bob", 2}) || r.TopCommenters[1] != (Commenter{"carol", 1}) {
+		t.Errorf("top commenters = %+v", r.TopCommenters)
+	}
+	if len(r.StalePullRequests) != 2 || r.StalePullRequests[0].Number != 10 || r.StalePullRequests[1].Number != 8 {
+		t.Errorf("stale = %+v", r.StalePullRequests)
+	}
+	if len(r.FirstTimeContributors) != 1 || r.FirstTimeContributors[0].Login != "erin" {
+		t.Errorf("first-time contributors = %+v", r.FirstTimeContributors)
+	}
+
+	md := r.Markdown()
+	for _, want := range []string{
+		"# org/api activity, 2024-05-01 to 2024-05-08",
+		"- [#7](https://github.com/org/api/pull/7) Fix crash by @erin, merged by @bob",
+		"| @bob | 2 |",
+		"- [#10]() Cache by @carol, waiting 10 days for @core",
+		"## Releases (1)\n\n- [v1.2.0]() by @bob",
+		"## First-time contributors (1)\n\n- @erin with [#7]",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown lacks %q:\n%s", want, md)
+		}
+	}
+
+	html, err := r.HTML()
+	if err != nil || !strings.Contains(string(html), `<a href="https://github.com/org/api/pull/7">#7</a> Fix crash by @erin, merged by @bob`) {
+		t.Errorf("HTML = %s, %v", html, err)
+	}
+	data, _ := r.JSON()
+	var decoded Report
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.MergedPullRequests[0].Number != 7 {
+		t.Errorf("JSON round trip: %v", err)
+	}
+
+	empty := Build(Config{Repo: "org/api", From: ago(7), To: end}, nil, nil)
+	if !strings.Contains(empty.Markdown(), "## New issues (0)\n\nNone.") {
+		t.Errorf("empty report:\n%s", empty.Markdown())
+	}
+	if data, _ := empty.JSON(); !strings.Contains(string(data), `"releases": []`) {
+		t.Errorf("empty lists should encode as []: %s", data)
+	}
+}
+
+func TestPending(t *testing.T) {
+	prs := []watcher.OpenPullRequest{
+		{PullRequest: watcher.PullRequest{Number: 1, CreatedAt: ago(9), UpdatedAt: ago(1)}, RequestedReviewers: []watcher.Actor{{Login: "bob"}}},
+		{PullRequest: watcher.PullRequest{Number: 2, Draft: true}, RequestedReviewers: []watcher.Actor{{Login: "bob"}}},
+		{PullRequest: watcher.PullRequest{Number: 3}},
+	}
+	prs[0].RequestedTeams = append(prs[0].RequestedTeams, struct {
+		Slug string `json:"slug"`
+	}{"core"})
+	if got := PendingFromAPI(prs, nil); len(got) != 1 || got[0].Number != 1 || strings.Join(got[0].Reviewers, ",") != "bob,core" || !got[0].Since.Equal(ago(9)) {
+		t.Errorf("PendingFromAPI = %+v", got)
+	}
+	requested := ReviewRequestedAt([]watcher.IssueEvent{
+		{Event: "review_requested", CreatedAt: ago(6)},
+		{Event: "labeled", CreatedAt: ago(2)},
+		{Event: "review_requested", CreatedAt: ago(4)},
+	})
+	if got := PendingFromAPI(prs, map[int]time.Time{1: requested}); !got[0].Since.Equal(ago(4)) {
+		t.Errorf("expected the wait to start at the last review request, got %v", got[0].Since)
+	}
+
+	open := &watcher.Details{State: "open", RequestedReviewers: []string{"bob"}}
+	pr := watcher.PullRequest{Number: 7, User: watcher.Actor{Login: "erin"}}
+	e1 := prEvent("opened", "erin", pr, ago(5))
+	e1.Details = open
+	e2 := comment("bob", ago(3))
+	e2.Payload.Issue = &watcher.Issue{Number: 7, User: watcher.Actor{Login: "erin"}, PullRequest: &watcher.PullRequestLink{}}
+	e2.Details = open
+	got := PendingFromEvents("org/api", []watcher.Event{e2, e1})
+	if len(got) != 1 || got[0].Author != "erin" || !got[0].Since.Equal(ago(5)) {
+		t.Errorf("PendingFromEvents = %+v", got)
+	}
+	e3 := prEvent("closed", "bob", pr, ago(1))
+	e3.Details = &watcher.Details{State: "closed"}
+	if got := PendingFromEvents("org/api", []watcher.Event{e1, e2, e3}); len(got) != 0 {
+		t.Errorf("closed pull request still pending: %+v", got)
+	}
+	e4 := prEvent("review_requested", "erin", pr, ago(2))
+	if got := PendingFromEvents("org/api", []watcher.Event{e1, e2, e4}); len(got) != 1 || !got[0].Since.Equal(ago(2)) {
+		t.Errorf("expected the wait to start at the review request: %+v", got)
+	}
+}
@@ -0,0 +1,155 @@
+package digest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+)
+
+// Title describes the report's repository and period
+func (r Report) Title() string {
+	return fmt.Sprintf("%s activity, %s to %s", r.Repo, r.From.Format("2006-01-02"), r.To.Format("2006-01-02"))
+}
+
+// waiting describes how long a pull request has waited by the end of the period
+func waiting(to time.Time, p Pending) string {
+	d := to.Sub(p.Since)
+	if d >= 48*time.Hour {
+		return fmt.Sprintf("%d days", int(d/(24*time.Hour)))
+	}
+	return fmt.Sprintf("%d hours", int(d/time.Hour))
+}
+
+// byline names the author, and who closed or merged the item when someone else did
+func byline(i Item, verb string) string {
+	if i.By == "" {
+		return "by @" + i.Author
+	}
+	return fmt.Sprintf("by @%s, %s by @%s", i.Author, verb, i.By)
+}
+
+// mentions formats logins as @mentions
+func mentions(logins []string) string {
+	out := make([]string, len(logins))
+	for i, l := range logins {
+		out[i] = "@" + l
+	}
+	return strings.Join(out, ", ")
+}
+
+// Markdown renders the report as Markdown
+func (r Report) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", r.Title())
+
+	section := func(title string, n int) bool {
+		fmt.Fprintf(&b, "\n## %s (%d)\n\n", title, n)
+		if n == 0 {
+			b.WriteString("None.\n")
+		}
+		return n > 0
+	}
+	items := func(items []Item, verb string) {
+		for _, i := range items {
+			fmt.Fprintf(&b, "- [#%d](%s) %s %s\n", i.Number, i.URL, i.Title, byline(i, verb))
+		}
+	}
+
+	if section("Merged pull requests", len(r.MergedPullRequests)) {
+		items(r.MergedPullRequests, "merged")
+	}
+	if section("New issues", len(r.OpenedIssues)) {
+		items(r.OpenedIssues, "")
+	}
+	if section("Closed issues", len(r.ClosedIssues)) {
+		items(r.ClosedIssues, "closed")
+	}
+	if section("Releases", len(r.Releases)) {
+		for _, rel := range r.Releases {
+			fmt.Fprintf(&b, "- [%s](%s)", rel.Tag, rel.URL)
+			if rel.Name != "" && rel.Name != rel.Tag {
+				fmt.Fprintf(&b, " %s", rel.Name)
+			}
+			fmt.Fprintf(&b, " by @%s\n", rel.Author)
+		}
+	}
+	if section("Top commenters", len(r.TopCommenters)) {
+		b.WriteString("| Commenter | Comments |\n|---|---:|\n")
+		for _, c := range r.TopCommenters {
+			fmt.Fprintf(&b, "| @%s | %d |\n", c.Login, c.Comments)
+		}
+	}
+	if section("Pull requests awaiting review", len(r.StalePullRequests)) {
+		for _, p := range r.StalePullRequests {
+			fmt.Fprintf(&b, "- [#%d](%s) %s by @%s, waiting %s for %s\n", p.Number, p.URL, p.Title, p.Author, waiting(r.To, p), mentions(p.Reviewers))
+		}
+	}
+	if section("First-time contributors", len(r.FirstTimeContributors)) {
+		for _, c := range r.FirstTimeContributors {
+			fmt.Fprintf(&b, "- @%s with [#%d](%s) %s\n", c.Login, c.PullRequest.Number, c.PullRequest.URL, c.PullRequest.Title)
+		}
+	}
+	return b.String()
+}
+
+// itemList is a list of items for the HTML template, with the verb for who closed them
+type itemList struct {
+	Items []Item
+	Verb  string
+}
+
+var reportHTML = template.Must(template.New("report").Funcs(template.FuncMap{
+	"byline":   byline,
+	"mentions": mentions,
+	"waiting":  waiting,
+	"list":     func(items []Item, verb string) itemList { return itemList{items, verb} },
+}).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body style="font-family: sans-serif">
+<h1>{{.Title}}</h1>
+{{define "items"}}{{$verb := .Verb}}{{if .Items}}<ul>
+{{range .Items}}<li><a href="{{.URL}}">#{{.Number}}</a> {{.Title}} {{byline . $verb}}</li>
+{{end}}</ul>{{else}}<p>None.</p>{{end}}
+{{end}}<h2>Merged pull requests ({{len .MergedPullRequests}})</h2>
+{{template "items" list .MergedPullRequests "merged"}}<h2>New issues ({{len .OpenedIssues}})</h2>
+{{template "items" list .OpenedIssues ""}}<h2>Closed issues ({{len .ClosedIssues}})</h2>
+{{template "items" list .ClosedIssues "closed"}}<h2>Releases ({{len .Releases}})</h2>
+{{if .Releases}}<ul>
+{{range .Releases}}<li><a href="{{.URL}}">{{.Tag}}</a>{{if and .Name (ne .Name .Tag)}} {{.Name}}{{end}} by @{{.Author}}</li>
+{{end}}</ul>{{else}}<p>None.</p>{{end}}
+<h2>Top commenters ({{len .TopCommenters}})</h2>
+{{if .TopCommenters}}<table>
+<tr><th align="left">Commenter</th><th align="right">Comments</th></tr>
+{{range .TopCommenters}}<tr><td>@{{.Login}}</td><td align="right">{{.Comments}}</td></tr>
+{{end}}</table>{{else}}<p>None.</p>{{end}}
+<h2>Pull requests awaiting review ({{len .StalePullRequests}})</h2>
+{{if .StalePullRequests}}<ul>
+{{range .StalePullRequests}}<li><a href="{{.URL}}">#{{.Number}}</a> {{.Title}} by @{{.Author}}, waiting {{waiting $.To .}} for {{mentions .Reviewers}}</li>
+{{end}}</ul>{{else}}<p>None.</p>{{end}}
+<h2>First-time contributors ({{len .FirstTimeContributors}})</h2>
+{{if .FirstTimeContributors}}<ul>
+{{range .FirstTimeContributors}}<li>@{{.Login}} with <a href="{{.PullRequest.URL}}">#{{.PullRequest.Number}}</a> {{.PullRequest.Title}}</li>
+{{end}}</ul>{{else}}<p>None.</p>{{end}}
+</body></html>
+`))
+
+// HTML renders the report as a standalone HTML page
+func (r Report) HTML() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := reportHTML.Execute(&buf, r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// JSON renders the report as indented JSON
+func (r Report) JSON() ([]byte, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
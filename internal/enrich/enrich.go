@@ -0,0 +1,268 @@
+// Package enrich attaches the full issue or pull request to events, as the events feed
+// reports only part of it: labels, assignees, milestone, draft status, requested reviewers
+// and diff stats are fetched from the API and cached.
+package enrich
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// DefaultTTL is how long fetched details are used without asking the API again
+const DefaultTTL = 5 * time.Minute
+
+// DefaultConcurrency is how many API requests run at once
+const DefaultConcurrency = 4
+
+// maxEntries bounds the cache; the least recently fetched entries are dropped first
+const maxEntries = 1000
+
+// Config configures an Enricher
+type Config struct {
+	// TTL is how long details are reused without a request; zero uses DefaultTTL.
+	// Details fetched before the event happened are always revalidated.
+	TTL time.Duration
+	// Concurrency caps simultaneous requests; zero uses DefaultConcurrency
+	Concurrency int
+	// Fetch requests an API path; nil uses watcher.FetchConditional
+	Fetch func(path, etag string) (watcher.Response, error)
+}
+
+// Enricher fetches and caches issue and pull request details by API path
+type Enricher struct {
+	config Config
+	now    func() time.Time
+	// disabled passes events through without fetching
+	disabled atomic.Bool
+
+	mu       sync.Mutex
+	cache    map[string]*entry
+	inflight map[string]chan struct{}
+}
+
+// entry is a cached API response
+type entry struct {
+	etag    string
+	details watcher.Details
+	fetched time.Time
+}
+
+// New creates an enricher
+func New(config Config) *Enricher {
+	if config.TTL == 0 {
+		config.TTL = DefaultTTL
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = DefaultConcurrency
+	}
+	if config.Fetch == nil {
+		config.Fetch = watcher.FetchConditional
+	}
+	return &Enricher{
+		config:   config,
+		now:      time.Now,
+		cache:    make(map[string]*entry),
+		inflight: make(map[string]chan struct{}),
+	}
+}
+
+// Run enriches the events from in and sends them to out in the same order, fetching up to
+// Concurrency at once. It closes out when in is closed and drained; events whose details
+// cannot be fetched are passed on without them.
+func (x *Enricher) Run(in <-chan watcher.Event, out chan<- watcher.Event) {
+	defer close(out)
+	sem := make(chan struct{}, x.config.Concurrency)
+	pending := make(chan chan watcher.Event, x.config.Concurrency)
+	go func() {
+		defer close(pending)
+		for e := range in {
+			result := make(chan watcher.Event, 1)
+			pending <- result
+			go func() {
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				enriched, err := x.Enrich(e)
+				if err != nil {
+					log.Printf("enrich error: %v", err)
+				}
+				result <- enriched
+			}()
+		}
+	}()
+	for result := range pending {
+		out <- <-result
+	}
+}
+
+// SetEnabled turns fetching on or off; a new enricher is enabled
+func (x *Enricher) SetEnabled(enabled bool) {
+	x.disabled.Store(!enabled)
+}
+
+// Enabled reports whether the enricher fetches details
+func (x *Enricher) Enabled() bool {
+	return !x.disabled.Load()
+}
+
+// Enrich returns the event with the details of its issue or pull request attached.
+// Events without one, and every event while the enricher is disabled, are returned as
+// they are. On error the event keeps any stale details.
+func (x *Enricher) Enrich(e watcher.Event) (watcher.Event, error) {
+	path := subjectPath(e)
+	if path == "" || x.disabled.Load() {
+		return e, nil
+	}
+	d, err := x.details(path, e.CreatedAt)
+	if d != nil {
+		e.Details = d
+	}
+	return e, err
+}
+
+// subjectPath returns the API path of the event's issue or pull request, or ""
+func subjectPath(e watcher.Event) string {
+	n := e.Number()
+	if n == 0 || e.Repo.Name == "" {
+		return ""
+	}
+	p := e.Payload
+	if p.PullRequest != nil || (p.Issue != nil && p.Issue.IsPullRequest()) {
+		return fmt.Sprintf("repos/%s/pulls/%d", e.Repo.Name, n)
+	}
+	return fmt.Sprintf("repos/%s/issues/%d", e.Repo.Name, n)
+}
+
+// details returns the details at path, fetching them unless the cache has a copy fetched
+// within the TTL and after since. Concurrent requests for the same path share one fetch.
+func (x *Enricher) details(path string, since time.Time) (*watcher.Details, error) {
+	x.mu.Lock()
+	for {
+		if c := x.cache[path]; c != nil && x.now().Sub(c.fetched) < x.config.TTL && !c.fetched.Before(since) {
+			x.mu.Unlock()
+			return copyDetails(c), nil
+		}
+		wait, busy := x.inflight[path]
+		if !busy {
+			break
+		}
+		x.mu.Unlock()
+		<-wait
+		x.mu.Lock()
+	}
+	done := make(chan struct{})
+	x.inflight[path] = done
+	cached := x.cache[path]
+	x.mu.Unlock()
+
+	defer func() {
+		x.mu.Lock()
+		delete(x.inflight, path)
+		x.mu.Unlock()
+		close(done)
+	}()
+
+	var etag string
+	if cached != nil {
+		etag = cached.etag
+	}
+	resp, err := x.config.Fetch(path, etag)
+	if err == nil && resp.NotModified && cached == nil {
+		err = fmt.Errorf("%s: not modified, but nothing is cached", path)
+	}
+	if err != nil {
+		if cached != nil {
+			return copyDetails(cached), err
+		}
+		return nil, err
+	}
+
+	fresh := &entry{etag: resp.ETag, fetched: x.now()}
+	if resp.NotModified {
+		fresh.details = cached.details
+	} else if fresh.details, err = parseDetails(resp.Body); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	x.mu.Lock()
+	x.cache[path] = fresh
+	x.evict()
+	x.mu.Unlock()
+	return copyDetails(fresh), nil
+}
+
+// evict drops the oldest entry when the cache is full; the caller holds the lock
+func (x *Enricher) evict() {
+	if len(x.cache) <= maxEntries {
+		return
+	}
+	var oldest string
+	for path, c := range x.cache {
+		if oldest == "" || c.fetched.Before(x.cache[oldest].fetched) {
+			oldest = path
+		}
+	}
+	delete(x.cache, oldest)
+}
+
+func copyDetails(c *entry) *watcher.Details {
+	d := c.details
+	return &d
+}
+
+// subject is the part of an issue or pull request API response that becomes Details
+type subject struct {
+	State     string          `json:"state"`
+	Labels    []watcher.Label `json:"labels"`
+	Assignees []watcher.Actor `json:"assignees"`
+	Milestone *struct {
+		Title string `json:"title"`
+	} `json:"milestone"`
+	Draft              bool            `json:"draft"`
+	Merged             bool            `json:"merged"`
+	RequestedReviewers []watcher.Actor `json:"requested_reviewers"`
+	RequestedTeams     []struct {
+		Slug string `json:"slug"`
+	} `json:"requested_teams"`
+	Additions    int `json:"additions"`
+	Deletions    int `json:"deletions"`
+	ChangedFiles int `json:"changed_files"`
+}
+
+func parseDetails(body []byte) (watcher.Details, error) {
+	var s subject
+	if err := json.Unmarshal(body, &s); err != nil {
+		return watcher.Details{}, err
+	}
+	d := watcher.Details{
+		State:        s.State,
+		Labels:       []string{},
+		Assignees:    []string{},
+		Draft:        s.Draft,
+		Merged:       s.Merged,
+		Additions:    s.Additions,
+		Deletions:    s.Deletions,
+		ChangedFiles: s.ChangedFiles,
+	}
+	for _, l := range s.Labels {
+		d.Labels = append(d.Labels, l.Name)
+	}
+	for _, a := range s.Assignees {
+		d.Assignees = append(d.Assignees, a.Login)
+	}
+	if s.Milestone != nil {
+		d.Milestone = s.Milestone.Title
+	}
+	for _, r := range s.RequestedReviewers {
+		d.RequestedReviewers = append(d.RequestedReviewers, r.Login)
+	}
+	for _, t := range s.RequestedTeams {
+		d.RequestedReviewers = append(d.RequestedReviewers, t.Slug)
+	}
+	return d, nil
+}
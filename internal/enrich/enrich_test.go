@@ -0,0 +1,161 @@
+package enrich
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+const prJSON = `{"state":"open","draft":true,"labels":[{"name":"bug"}],"assignees":[{"login":"bob"}],
+	"milestone":{"title":"v2"},"requested_reviewers":[{"login":"carol"}],"requested_teams":[{"slug":"core"}],
+	"additions":120,"deletions":30,"changed_files":4}`
+
+// fakeAPI answers requests with a fixed body and ETag, and 304 when the ETag matches
+type fakeAPI struct {
+	mu       sync.Mutex
+	requests []string
+	body     string
+	etag     string
+	err      error
+}
+
+func (f *fakeAPI) fetch(path, etag string) (watcher.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, path+" "+etag)
+	if f.err != nil {
+		return watcher.Response{}, f.err
+	}
+	if etag != "" && etag == f.etag {
+		return watcher.Response{ETag: etag, NotModified: true}, nil
+	}
+	return watcher.Response{Body: []byte(f.body), ETag: f.etag}, nil
+}
+
+func comment(number int, at time.Time) watcher.Event {
+	return watcher.Event{
+		Type:      "IssueCommentEvent",
+		Repo:      watcher.Repo{Name: "org/api"},
+		CreatedAt: at,
+		Payload: watcher.Payload{
+			Action:  "created",
+			Issue:   &watcher.Issue{Number: number, PullRequest: &watcher.PullRequestLink{}},
+			Comment: &watcher.Comment{ID: number},
+		},
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	api := &fakeAPI{body: prJSON, etag: `"v1"`}
+	x := New(Config{TTL: time.Minute, Fetch: api.fetch})
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	x.now = func() time.Time { return now }
+
+	e, err := x.Enrich(comment(12, now.Add(-time.Second)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := e.Details
+	if d == nil || !d.Draft || d.Milestone != "v2" || d.Labels[0] != "bug" || d.Assignees[0] != "bob" ||
+		d.Additions != 120 || d.ChangedFiles != 4 || len(d.RequestedReviewers) != 2 || d.RequestedReviewers[1] != "core" {
+		t.Fatalf("unexpected details: %+v", d)
+	}
+	if api.requests[0] != `repos/org/api/pulls/12 ` {
+		t.Errorf("request = %q, want the pulls endpoint", api.requests[0])
+	}
+
+	// Within the TTL, events older than the fetch are served from the cache
+	x.Enrich(comment(12, now.Add(-time.Second)))
+	if len(api.requests) != 1 {
+		t.Errorf("cached details fetched again: %v", api.requests)
+	}
+
+	// Newer events and expired entries are revalidated with the ETag
+	now = now.Add(10 * time.Second)
+	e, _ = x.Enrich(comment(12, now))
+	now = now.Add(2 * time.Minute)
+	x.Enrich(comment(12, now.Add(-time.Hour)))
+	if len(api.requests) != 3 || api.requests[1] != `repos/org/api/pulls/12 "v1"` || api.requests[2] != `repos/org/api/pulls/12 "v1"` {
+		t.Errorf("expected conditional requests, got %v", api.requests)
+	}
+	if e.Details == nil || e.Details.Milestone != "v2" {
+		t.Errorf("not modified response lost the details: %+v", e.Details)
+	}
+
+	// Failures keep the stale details and report the error
+	api.err = errors.New("rate limited")
+	now = now.Add(2 * time.Minute)
+	e, err = x.Enrich(comment(12, now))
+	if err == nil || e.Details == nil || e.Details.Milestone != "v2" {
+		t.Errorf("Enrich = %+v, %v; want stale details and an error", e.Details, err)
+	}
+
+	// Issues use the issues endpoint and events without a number are left alone
+	api.err = nil
+	issue := watcher.Event{Type: "IssuesEvent", Repo: watcher.Repo{Name: "org/api"}, Payload: watcher.Payload{Issue: &watcher.Issue{Number: 3}}}
+	x.Enrich(issue)
+	if last := api.requests[len(api.requests)-1]; last != "repos/org/api/issues/3 " {
+		t.Errorf("request = %q, want the issues endpoint", last)
+	}
+	push, _ := x.Enrich(watcher.Event{Type: "PushEvent", Repo: watcher.Repo{Name: "org/api"}})
+	if push.Details != nil {
+		t.Error("event without a number was enriched")
+	}
+
+	x.SetEnabled(false)
+	before := len(api.requests)
+	if e, _ := x.Enrich(comment(13, now)); e.Details != nil || len(api.requests) != before || x.Enabled() {
+		t.Error("disabled enricher fetched details")
+	}
+}
+
+func TestRun(t *testing.T) {
+	var running, peak, calls atomic.Int32
+	release := make(chan struct{})
+	x := New(Config{Concurrency: 2, Fetch: func(path, etag string) (watcher.Response, error) {
+		calls.Add(1)
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+		return watcher.Response{Body: []byte(fmt.Sprintf(`{"state":%q}`, path))}, nil
+	}})
+
+	in := make(chan watcher.Event)
+	out := make(chan watcher.Event)
+	go x.Run(in, out)
+	go func() {
+		// The same pull request twice shares one request
+		for _, n := range []int{1, 2, 3, 2, 4} {
+			in <- comment(n, time.Time{})
+		}
+		close(in)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	var got []string
+	for e := range out {
+		got = append(got, e.Details.State)
+	}
+	want := []string{"repos/org/api/pulls/1", "repos/org/api/pulls/2", "repos/org/api/pulls/3", "repos/org/api/pulls/2", "repos/org/api/pulls/4"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("events out of order: %v", got)
+	}
+	if peak.Load() > 2 {
+		t.Errorf("%d requests ran at once, want at most 2", peak.Load())
+	}
+	if calls.Load() != 4 {
+		t.Errorf("%d requests, want 4", calls.Load())
+	}
+}
@@ -0,0 +1,225 @@
+package feed
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/sink"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// Info describes a feed
+type Info struct {
+	// Repo is the repository the feed covers; empty means all repositories
+	Repo string
+	// Self is the URL the feed is published at, if known
+	Self string
+	Now  time.Time
+}
+
+func (i Info) title() string {
+	if i.Repo == "" {
+		return "ghostio: all repositories"
+	}
+	return "ghostio: " + i.Repo
+}
+
+func (i Info) id() string {
+	if i.Repo == "" {
+		return "urn:ghostio:feed:all"
+	}
+	return "urn:ghostio:feed:" + i.Repo
+}
+
+func (i Info) link() string {
+	if i.Repo == "" {
+		return "https://github.com"
+	}
+	return "https://github.com/" + i.Repo
+}
+
+// updated is the time of the newest entry, or now for an empty feed
+func (i Info) updated(events []watcher.Event) time.Time {
+	if len(events) > 0 {
+		return events[0].CreatedAt
+	}
+	if i.Now.IsZero() {
+		return time.Now()
+	}
+	return i.Now
+}
+
+// EntryID returns the stable entry ID derived from the GitHub event ID
+func EntryID(e watcher.Event) string {
+	return fmt.Sprintf("tag:github.com,2008:%s/%s", e.Type, e.ID)
+}
+
+// entryTitle is the formatted headline without its timestamp
+func entryTitle(e watcher.Event) string {
+	headline, _ := sink.SplitEvent(e)
+	return headline
+}
+
+// entryHTML renders the formatted event as HTML
+func entryHTML(e watcher.Event) string {
+	headline, rest := sink.SplitEvent(e)
+	var b strings.Builder
+	fmt.Fprintf(&b, "<p><strong>%s</strong></p>", html.EscapeString(headline))
+	for _, para := range strings.Split(strings.TrimSpace(rest), "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		if !strings.Contains(para, "\n") && (strings.HasPrefix(para, "https://") || strings.HasPrefix(para, "http://")) {
+			fmt.Fprintf(&b, `<p><a href="%s">%s</a></p>`, html.EscapeString(para), html.EscapeString(para))
+			continue
+		}
+		fmt.Fprintf(&b, "<p>%s</p>", strings.ReplaceAll(html.EscapeString(para), "\n", "<br>"))
+	}
+	return b.String()
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomPerson  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	Title     string      `xml:"title"`
+	ID        string      `xml:"id"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published"`
+	Links     []atomLink  `xml:"link"`
+	Author    atomPerson  `xml:"author"`
+	Category  *atomCat    `xml:"category,omitempty"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomCat struct {
+	Term string `xml:"term,attr"`
+}
+
+// Atom renders events, newest first, as an Atom 1.0 feed
+func Atom(info Info, events []watcher.Event) ([]byte, error) {
+	feed := atomFeed{
+		Title:   info.title(),
+		ID:      info.id(),
+		Updated: info.updated(events).UTC().Format(time.RFC3339),
+		Links:   []atomLink{{Href: info.link(), Rel: "alternate", Type: "text/html"}},
+		Author:  atomPerson{Name: "ghostio"},
+	}
+	if info.Self != "" {
+		feed.Links = append(feed.Links, atomLink{Href: info.Self, Rel: "self", Type: "application/atom+xml"})
+	}
+
+	for _, e := range events {
+		ts := e.CreatedAt.UTC().Format(time.RFC3339)
+		entry := atomEntry{
+			Title:     entryTitle(e),
+			ID:        EntryID(e),
+			Updated:   ts,
+			Published: ts,
+			Author:    atomPerson{Name: e.Actor.Login, URI: "https://github.com/" + e.Actor.Login},
+			Category:  &atomCat{Term: e.Type},
+			Content:   atomContent{Type: "html", Body: entryHTML(e)},
+		}
+		if url := e.HTMLURL(); url != "" {
+			entry.Links = []atomLink{{Href: url, Rel: "alternate", Type: "text/html"}}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	return marshalXML(feed)
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Self          *atomLink `xml:"atom:link,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Category    string  `xml:"category,omitempty"`
+	Description string  `xml:"description"`
+}
+
+// RSS renders events, newest first, as an RSS 2.0 feed
+func RSS(info Info, events []watcher.Event) ([]byte, error) {
+	feed := rssFeed{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:         info.title(),
+			Link:          info.link(),
+			Description:   "Recent GitHub activity collected by ghostio",
+			LastBuildDate: info.updated(events).UTC().Format(time.RFC1123Z),
+		},
+	}
+	if info.Self != "" {
+		feed.Channel.Self = &atomLink{Href: info.Self, Rel: "self", Type: "application/rss+xml"}
+	}
+
+	for _, e := range events {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       entryTitle(e),
+			Link:        e.HTMLURL(),
+			GUID:        rssGUID{IsPermaLink: "false", Value: EntryID(e)},
+			PubDate:     e.CreatedAt.UTC().Format(time.RFC1123Z),
+			Category:    e.Type,
+			Description: entryHTML(e),
+		})
+	}
+
+	return marshalXML(feed)
+}
+
+func marshalXML(v any) ([]byte, error) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to render feed: %w", err)
+	}
+	return append([]byte(xml.Header), append(body, '\n')...), nil
+}
@@ -0,0 +1,147 @@
+package feed
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+type parsedAtom struct {
+	XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Links   []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Entries []struct {
+		ID      string `xml:"id"`
+		Title   string `xml:"title"`
+		Updated string `xml:"updated"`
+		Link    struct {
+			Href string `xml:"href,attr"`
+		} `xml:"link"`
+		Content struct {
+			Type string `xml:"type,attr"`
+			Body string `xml:",chardata"`
+		} `xml:"content"`
+	} `xml:"entry"`
+}
+
+type parsedRSS struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string   `xml:"version,attr"`
+	Channel struct {
+		Title string `xml:"title"`
+		Self  struct {
+			Href string `xml:"href,attr"`
+		} `xml:"http://www.w3.org/2005/Atom link"`
+		Items []struct {
+			Title       string `xml:"title"`
+			Link        string `xml:"link"`
+			GUID        string `xml:"guid"`
+			PubDate     string `xml:"pubDate"`
+			Description string `xml:"description"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+func TestEntryID(t *testing.T) {
+	e := issueEvent("12345", "org/api", baseTime)
+	if got := EntryID(e); got != "tag:github.com,2008:IssuesEvent/12345" {
+		t.Errorf("EntryID() = %q", got)
+	}
+}
+
+func TestAtom(t *testing.T) {
+	events := []watcher.Event{
+		issueEvent("2", "org/api", baseTime),
+		issueEvent("1", "org/api", baseTime.Add(-time.Hour)),
+	}
+	body, err := Atom(Info{Repo: "org/api", Self: "http://localhost/org/api/feed.atom"}, events)
+	if err != nil {
+		t.Fatalf("Atom() failed: %v", err)
+	}
+
+	var feed parsedAtom
+	if err := xml.Unmarshal(body, &feed); err != nil {
+		t.Fatalf("invalid Atom XML: %v\n%s", err, body)
+	}
+	if feed.ID != "urn:ghostio:feed:org/api" {
+		t.Errorf("feed id = %q", feed.ID)
+	}
+	if feed.Updated != "2024-05-01T12:00:00Z" {
+		t.Errorf("feed updated = %q, want newest entry time", feed.Updated)
+	}
+	if len(feed.Links) != 2 || feed.Links[1].Rel != "self" || feed.Links[1].Href != "http://localhost/org/api/feed.atom" {
+		t.Errorf("unexpected feed links: %+v", feed.Links)
+	}
+	if len(feed.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(feed.Entries))
+	}
+
+	entry := feed.Entries[0]
+	if entry.ID != "tag:github.com,2008:IssuesEvent/2" {
+		t.Errorf("entry id = %q", entry.ID)
+	}
+	if entry.Title != `Issue opened: #7 "Crash on <start>" by @alice` {
+		t.Errorf("entry title = %q", entry.Title)
+	}
+	if entry.Link.Href != "https://github.com/org/api/issues/7" {
+		t.Errorf("entry link = %q", entry.Link.Href)
+	}
+	if entry.Content.Type != "html" {
+		t.Errorf("content type = %q", entry.Content.Type)
+	}
+	for _, want := range []string{
+		"Crash on &lt;start&gt;",
+		`<a href="https://github.com/org/api/issues/7">`,
+		"Steps:<br>1. run &amp; wait",
+	} {
+		if !strings.Contains(entry.Content.Body, want) {
+			t.Errorf("content missing %q:\n%s", want, entry.Content.Body)
+		}
+	}
+}
+
+func TestRSS(t *testing.T) {
+	events := []watcher.Event{issueEvent("2", "org/api", baseTime)}
+	body, err := RSS(Info{Self: "http://localhost/feed.rss"}, events)
+	if err != nil {
+		t.Fatalf("RSS() failed: %v", err)
+	}
+
+	var feed parsedRSS
+	if err := xml.Unmarshal(body, &feed); err != nil {
+		t.Fatalf("invalid RSS XML: %v\n%s", err, body)
+	}
+	if feed.Version != "2.0" {
+		t.Errorf("version = %q", feed.Version)
+	}
+	if feed.Channel.Title != "ghostio: all repositories" {
+		t.Errorf("channel title = %q", feed.Channel.Title)
+	}
+	if feed.Channel.Self.Href != "http://localhost/feed.rss" {
+		t.Errorf("self link = %q", feed.Channel.Self.Href)
+	}
+	if len(feed.Channel.Items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(feed.Channel.Items))
+	}
+	item := feed.Channel.Items[0]
+	if item.GUID != "tag:github.com,2008:IssuesEvent/2" {
+		t.Errorf("guid = %q", item.GUID)
+	}
+	if item.PubDate != "Wed, 01 May 2024 12:00:00 +0000" {
+		t.Errorf("pubDate = %q", item.PubDate)
+	}
+	if item.Link != "https://github.com/org/api/issues/7" {
+		t.Errorf("link = %q", item.Link)
+	}
+	if !strings.Contains(item.Description, "<p><strong>") {
+		t.Errorf("description is not HTML: %q", item.Description)
+	}
+}
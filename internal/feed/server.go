@@ -0,0 +1,110 @@
+package feed
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// Handler serves Atom and RSS feeds from a store
+type Handler struct {
+	store *Store
+}
+
+// NewHandler creates a feed handler
+func NewHandler(store *Store) *Handler {
+	return &Handler{store: store}
+}
+
+// Register adds the feed routes to mux:
+// /feed.atom and /feed.rss for all repositories, /{owner}/{repo}/feed.atom and /{owner}/{repo}/feed.rss per repository
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /feed.atom", h.serve(Atom, "application/atom+xml"))
+	mux.HandleFunc("GET /feed.rss", h.serve(RSS, "application/rss+xml"))
+	mux.HandleFunc("GET /{owner}/{repo}/feed.atom", h.serve(Atom, "application/atom+xml"))
+	mux.HandleFunc("GET /{owner}/{repo}/feed.rss", h.serve(RSS, "application/rss+xml"))
+}
+
+type renderFunc func(Info, []watcher.Event) ([]byte, error)
+
+func (h *Handler) serve(render renderFunc, contentType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		repo := ""
+		if owner := r.PathValue("owner"); owner != "" {
+			repo = owner + "/" + r.PathValue("repo")
+		}
+
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		info := Info{Repo: repo, Self: fmt.Sprintf("%s://%s%s", scheme, r.Host, r.URL.Path), Now: time.Now()}
+		body, err := render(info, h.store.Events(repo))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", contentType+"; charset=utf-8")
+		w.Write(body)
+	}
+}
+
+// WriteFiles writes all.atom and all.rss plus owner/repo.atom and owner/repo.rss for each repository to dir.
+// Passing only the repositories that changed leaves the other repositories' files as they are.
+func WriteFiles(dir string, store *Store, repos []string) error {
+	now := time.Now()
+	targets := append([]string{""}, repos...)
+	for _, repo := range targets {
+		events := store.Events(repo)
+		base := "all"
+		if repo != "" {
+			base = filepath.FromSlash(repo)
+		}
+		for _, f := range []struct {
+			ext    string
+			render renderFunc
+		}{
+			{".atom", Atom},
+			{".rss", RSS},
+		} {
+			body, err := f.render(Info{Repo: repo, Now: now}, events)
+			if err != nil {
+				return err
+			}
+			if err := writeAtomic(filepath.Join(dir, base+f.ext), body); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeAtomic replaces path with data so readers never see a partial feed
+func writeAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".feed-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
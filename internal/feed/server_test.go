@@ -0,0 +1,93 @@
+package feed
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	store := NewStore(time.Hour, 10)
+	now := time.Now()
+	store.Send(issueEvent("1", "org/api", now.Add(-time.Minute)))
+	store.Send(issueEvent("2", "org/app", now))
+
+	mux := http.NewServeMux()
+	NewHandler(store).Register(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		path        string
+		contentType string
+		contains    []string
+		excludes    []string
+	}{
+		{"/feed.atom", "application/atom+xml", []string{"IssuesEvent/1", "IssuesEvent/2"}, nil},
+		{"/feed.rss", "application/rss+xml", []string{"IssuesEvent/1", "IssuesEvent/2"}, nil},
+		{"/org/api/feed.atom", "application/atom+xml", []string{"IssuesEvent/1", server.URL + "/org/api/feed.atom"}, []string{"IssuesEvent/2"}},
+		{"/org/app/feed.rss", "application/rss+xml", []string{"IssuesEvent/2"}, []string{"IssuesEvent/1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			resp, err := http.Get(server.URL + tt.path)
+			if err != nil {
+				t.Fatalf("GET failed: %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status = %d", resp.StatusCode)
+			}
+			if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, tt.contentType) {
+				t.Errorf("Content-Type = %q, want %q", ct, tt.contentType)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(string(body), want) {
+					t.Errorf("body missing %q", want)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(string(body), unwanted) {
+					t.Errorf("body unexpectedly contains %q", unwanted)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteFiles(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(time.Hour, 10)
+	store.Send(issueEvent("1", "org/api", time.Now()))
+
+	if err := WriteFiles(dir, store, []string{"org/api", "org/idle"}); err != nil {
+		t.Fatalf("WriteFiles() failed: %v", err)
+	}
+
+	for _, name := range []string{"all.atom", "all.rss", "org/api.atom", "org/api.rss", "org/idle.atom", "org/idle.rss"} {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Errorf("missing %s: %v", name, err)
+			continue
+		}
+		hasEntry := strings.Contains(string(data), "IssuesEvent/1")
+		if hasEntry == strings.HasPrefix(name, "org/idle") {
+			t.Errorf("%s: entry present = %v", name, hasEntry)
+		}
+	}
+
+	// No temporary files are left behind
+	entries, _ := os.ReadDir(filepath.Join(dir, "org"))
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".feed-") {
+			t.Errorf("leftover temporary file %s", e.Name())
+		}
+	}
+}
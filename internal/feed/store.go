@@ -0,0 +1,130 @@
+package feed
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// Default retention limits
+const (
+	DefaultRetention  = 72 * time.Hour
+	DefaultMaxEntries = 200
+)
+
+// Store keeps recent events for feed generation
+type Store struct {
+	retention  time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu       sync.Mutex
+	events   []watcher.Event
+	seen     map[string]bool
+	onChange func(repo string)
+}
+
+// NewStore creates a store that keeps events newer than retention, up to maxEntries per feed
+func NewStore(retention time.Duration, maxEntries int) *Store {
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &Store{
+		retention:  retention,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		seen:       make(map[string]bool),
+	}
+}
+
+// OnChange registers a function called with the event's repository after each stored event
+func (s *Store) OnChange(fn func(repo string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onChange = fn
+}
+
+// Send stores the event
+func (s *Store) Send(e watcher.Event) error {
+	s.mu.Lock()
+	if e.ID != "" && s.seen[e.ID] {
+		s.mu.Unlock()
+		return nil
+	}
+	s.seen[e.ID] = true
+	s.events = append(s.events, e)
+	sort.SliceStable(s.events, func(i, j int) bool {
+		return s.events[i].CreatedAt.After(s.events[j].CreatedAt)
+	})
+	s.expire()
+	onChange := s.onChange
+	s.mu.Unlock()
+
+	if onChange != nil {
+		onChange(e.Repo.Name)
+	}
+	return nil
+}
+
+// Close does nothing; the store lives in memory
+func (s *Store) Close() error {
+	return nil
+}
+
+// Events returns retained events for a repository, or for all repositories when repo is empty, newest first
+func (s *Store) Events(repo string) []watcher.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+
+	var events []watcher.Event
+	for _, e := range s.events {
+		if repo != "" && e.Repo.Name != repo {
+			continue
+		}
+		events = append(events, e)
+		if len(events) == s.maxEntries {
+			break
+		}
+	}
+	return events
+}
+
+// Repos returns the repositories that have retained events
+func (s *Store) Repos() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := map[string]bool{}
+	var repos []string
+	for _, e := range s.events {
+		if e.Repo.Name != "" && !seen[e.Repo.Name] {
+			seen[e.Repo.Name] = true
+			repos = append(repos, e.Repo.Name)
+		}
+	}
+	sort.Strings(repos)
+	return repos
+}
+
+// expire drops events older than the retention window and beyond each repository's entry limit.
+// s.events is sorted newest first.
+func (s *Store) expire() {
+	cutoff := s.now().Add(-s.retention)
+	perRepo := map[string]int{}
+	kept := s.events[:0]
+	for _, e := range s.events {
+		if e.CreatedAt.Before(cutoff) || perRepo[e.Repo.Name] >= s.maxEntries {
+			delete(s.seen, e.ID)
+			continue
+		}
+		perRepo[e.Repo.Name]++
+		kept = append(kept, e)
+	}
+	s.events = kept
+}
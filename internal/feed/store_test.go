@@ -0,0 +1,94 @@
+package feed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+var baseTime = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func issueEvent(id, repo string, at time.Time) watcher.Event {
+	return watcher.Event{
+		ID:    id,
+		Type:  "IssuesEvent",
+		Actor: watcher.Actor{Login: "alice"},
+		Repo:  watcher.Repo{Name: repo},
+		Payload: watcher.Payload{
+			Action: "opened",
+			Issue: &watcher.Issue{
+				Number:  7,
+				Title:   "Crash on <start>",
+				Body:    "Steps:\n1. run & wait",
+				HTMLURL: "https://github.com/" + repo + "/issues/7",
+			},
+		},
+		CreatedAt: at,
+	}
+}
+
+func ids(events []watcher.Event) []string {
+	var out []string
+	for _, e := range events {
+		out = append(out, e.ID)
+	}
+	return out
+}
+
+func newTestStore(retention time.Duration, maxEntries int) *Store {
+	s := NewStore(retention, maxEntries)
+	s.now = func() time.Time { return baseTime }
+	return s
+}
+
+func TestStoreOrderAndDedup(t *testing.T) {
+	s := newTestStore(time.Hour, 10)
+	s.Send(issueEvent("2", "org/api", baseTime.Add(-2*time.Minute)))
+	s.Send(issueEvent("3", "org/app", baseTime.Add(-1*time.Minute)))
+	s.Send(issueEvent("1", "org/api", baseTime.Add(-3*time.Minute)))
+	s.Send(issueEvent("3", "org/app", baseTime.Add(-1*time.Minute)))
+
+	if got := ids(s.Events("")); len(got) != 3 || got[0] != "3" || got[1] != "2" || got[2] != "1" {
+		t.Errorf("Events(\"\") = %v, want [3 2 1]", got)
+	}
+	if got := ids(s.Events("org/api")); len(got) != 2 || got[0] != "2" || got[1] != "1" {
+		t.Errorf("Events(org/api) = %v, want [2 1]", got)
+	}
+	if got := s.Repos(); len(got) != 2 || got[0] != "org/api" || got[1] != "org/app" {
+		t.Errorf("Repos() = %v, want [org/api org/app]", got)
+	}
+}
+
+func TestStoreRetention(t *testing.T) {
+	s := newTestStore(time.Hour, 2)
+	s.Send(issueEvent("1", "org/api", baseTime.Add(-2*time.Hour)))
+	s.Send(issueEvent("2", "org/api", baseTime.Add(-30*time.Minute)))
+	s.Send(issueEvent("3", "org/api", baseTime.Add(-20*time.Minute)))
+	s.Send(issueEvent("4", "org/api", baseTime.Add(-10*time.Minute)))
+	s.Send(issueEvent("5", "org/app", baseTime.Add(-40*time.Minute)))
+
+	if got := ids(s.Events("org/api")); len(got) != 2 || got[0] != "4" || got[1] != "3" {
+		t.Errorf("Events(org/api) = %v, want [4 3]", got)
+	}
+	if got := ids(s.Events("org/app")); len(got) != 1 || got[0] != "5" {
+		t.Errorf("Events(org/app) = %v, want [5]", got)
+	}
+
+	// Events age out as the clock moves on
+	s.now = func() time.Time { return baseTime.Add(45 * time.Minute) }
+	if got := ids(s.Events("")); len(got) != 1 || got[0] != "4" {
+		t.Errorf("Events(\"\") after expiry = %v, want [4]", got)
+	}
+}
+
+func TestStoreOnChange(t *testing.T) {
+	s := newTestStore(time.Hour, 10)
+	var changed []string
+	s.OnChange(func(repo string) { changed = append(changed, repo) })
+	s.Send(issueEvent("1", "org/api", baseTime))
+	s.Send(issueEvent("1", "org/api", baseTime))
+	if len(changed) != 1 || changed[0] != "org/api" {
+		t.Errorf("expected 1 change notification for org/api, got %v", changed)
+	}
+}
@@ -0,0 +1,221 @@
+// Package filter implements a small expression language for selecting events, e.g.
+//
+//	type == "PullRequestEvent" && action in ["opened", "closed"] && !actor.endsWith("[bot]")
+//
+// Expressions are parsed and type-checked up front, so mistakes are reported
+// before any event is seen.
+package filter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// Error is a parse error pointing at a position in the expression
+type Error struct {
+	Expr string
+	// Pos is the byte offset of the problem
+	Pos int
+	Msg string
+}
+
+// Error reports the message with the expression and a caret under the offending position
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid filter at column %d: %s\n  %s\n  %s^", e.Pos+1, e.Msg, e.Expr, strings.Repeat(" ", len([]rune(e.Expr[:e.Pos]))))
+}
+
+// Filter is a compiled filter expression
+type Filter struct {
+	src     string
+	root    node
+	details bool
+	// uses holds the position of the first use of each field
+	uses map[string]int
+}
+
+// Parse compiles a filter expression
+func Parse(src string) (*Filter, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: src, tokens: tokens}
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	root = convert(root, typeBool)
+	if root.typ() != typeBool {
+		return nil, &Error{Expr: src, Pos: root.pos(), Msg: fmt.Sprintf("expression is a %s, not a condition", root.typ())}
+	}
+	return &Filter{src: src, root: root, details: p.details, uses: p.uses}, nil
+}
+
+// MustParse is like Parse but panics on error; for expressions known to be valid
+func MustParse(src string) *Filter {
+	f, err := Parse(src)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// String returns the source expression
+func (f *Filter) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.src
+}
+
+// Match reports whether the event satisfies the filter; a nil filter matches everything
+func (f *Filter) Match(e watcher.Event) bool {
+	if f == nil {
+		return true
+	}
+	return f.root.eval(e).(bool)
+}
+
+// UsesDetails reports whether the filter refers to fields that only enrichment provides
+func (f *Filter) UsesDetails() bool {
+	return f != nil && f.details
+}
+
+// Reject returns an error pointing at the first use of the field, for a filter applied
+// where the field is never set, or nil when the filter does not use it
+func (f *Filter) Reject(field, reason string) error {
+	if f == nil {
+		return nil
+	}
+	if pos, ok := f.uses[field]; ok {
+		return &Error{Expr: f.src, Pos: pos, Msg: fmt.Sprintf("%s %s", field, reason)}
+	}
+	return nil
+}
+
+// And combines filters so that an event must match all of them; nil filters are skipped
+func And(filters ...*Filter) *Filter {
+	var parts []*Filter
+	for _, f := range filters {
+		if f != nil {
+			parts = append(parts, f)
+		}
+	}
+	switch len(parts) {
+	case 0:
+		return nil
+	case 1:
+		return parts[0]
+	}
+	srcs := make([]string, len(parts))
+	root := parts[0].root
+	srcs[0] = "(" + parts[0].src + ")"
+	details := parts[0].details
+	uses := map[string]int{}
+	offset := 0
+	for i, f := range parts {
+		if i > 0 {
+			srcs[i] = "(" + f.src + ")"
+			root = &logicNode{op: "&&", left: root, right: f.root}
+			details = details || f.details
+		}
+		// Positions move past the sources before and the opening parenthesis
+		for name, pos := range f.uses {
+			if _, ok := uses[name]; !ok {
+				uses[name] = offset + 1 + pos
+			}
+		}
+		offset += len(srcs[i]) + len(" && ")
+	}
+	return &Filter{src: strings.Join(srcs, " && "), root: root, details: details, uses: uses}
+}
+
+// Fields lists the event fields available in expressions
+func Fields() []string {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, f.name)
+	}
+	return names
+}
+
+// field is an event attribute that expressions can refer to
+type field struct {
+	name string
+	typ  valueType
+	get  func(e watcher.Event) any
+}
+
+var fields = []field{
+	{"type", typeString, func(e watcher.Event) any { return e.Type }},
+	{"action", typeString, func(e watcher.Event) any { return e.Payload.Action }},
+	{"actor", typeString, func(e watcher.Event) any { return e.Actor.Login }},
+	{"repo", typeString, func(e watcher.Event) any { return e.Repo.Name }},
+	{"number", typeNumber, func(e watcher.Event) any { return e.Number() }},
+	{"title", typeString, func(e watcher.Event) any { return e.Title() }},
+	{"body", typeString, func(e watcher.Event) any { return e.Body() }},
+	{"labels", typeStringList, func(e watcher.Event) any { return e.Labels() }},
+	{"merged", typeBool, func(e watcher.Event) any {
+		return e.Payload.PullRequest != nil && e.Payload.PullRequest.Merged
+	}},
+	{"draft", typeBool, func(e watcher.Event) any {
+		if e.Details != nil {
+			return e.Details.Draft
+		}
+		return e.Payload.PullRequest != nil && e.Payload.PullRequest.Draft
+	}},
+	{"assignees", typeStringList, func(e watcher.Event) any { return e.Assignees() }},
+	{"milestone", typeString, func(e watcher.Event) any { return details(e).Milestone }},
+	{"reviewers", typeStringList, func(e watcher.Event) any {
+		if r := details(e).RequestedReviewers; r != nil {
+			return r
+		}
+		return []string{}
+	}},
+	{"additions", typeNumber, func(e watcher.Event) any { return details(e).Additions }},
+	{"deletions", typeNumber, func(e watcher.Event) any { return details(e).Deletions }},
+	{"changed_files", typeNumber, func(e watcher.Event) any { return details(e).ChangedFiles }},
+	{"alerts", typeStringList, func(e watcher.Event) any {
+		names := make([]string, len(e.Alerts))
+		for i, a := range e.Alerts {
+			names[i] = a.Rule
+		}
+		return names
+	}},
+}
+
+// enrichedFields come from enrichment; without it they are empty or fall back to the payload
+var enrichedFields = []string{"assignees", "milestone", "reviewers", "additions", "deletions", "changed_files"}
+
+// details returns the enriched details of an event, empty when it has none
+func details(e watcher.Event) watcher.Details {
+	if e.Details != nil {
+		return *e.Details
+	}
+	return watcher.Details{}
+}
+
+func lookupField(name string) (field, bool) {
+	for _, f := range fields {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// Default returns the filter used when none is configured: the event types in watcher.TargetEventTypes
+func Default() *Filter {
+	return Types(watcher.TargetEventTypes...)
+}
+
+// Types returns a filter matching events of the given types
+func Types(types ...string) *Filter {
+	quoted := make([]string, len(types))
+	for i, t := range types {
+		quoted[i] = fmt.Sprintf("%q", t)
+	}
+	return MustParse("type in [" + strings.Join(quoted, ", ") + "]")
+}
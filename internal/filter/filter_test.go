@@ -0,0 +1,257 @@
+package filter
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+func prEvent(action, actor, title string, merged bool, labels ...string) watcher.Event {
+	pr := &watcher.PullRequest{Number: 12, Title: title, Body: "Fixes the login bug", Merged: merged}
+	for _, l := range labels {
+		pr.Labels = append(pr.Labels, watcher.Label{Name: l})
+	}
+	return watcher.Event{
+		Type:    "PullRequestEvent",
+		Actor:   watcher.Actor{Login: actor},
+		Repo:    watcher.Repo{Name: "org/api"},
+		Payload: watcher.Payload{Action: action, PullRequest: pr},
+	}
+}
+
+func TestMatch(t *testing.T) {
+	merged := prEvent("closed", "alice", "Security: fix token leak", true, "security", "bug")
+	bot := prEvent("opened", "dependabot[bot]", "Bump lodash", false, "dependencies")
+	comment := watcher.Event{
+		Type:  "IssueCommentEvent",
+		Actor: watcher.Actor{Login: "bob"},
+		Repo:  watcher.Repo{Name: "org/app"},
+		Payload: watcher.Payload{
+			Action:  "created",
+			Issue:   &watcher.Issue{Number: 3, Title: "Crash", Body: "issue body"},
+			Comment: &watcher.Comment{Body: "Still seeing this REGRESSION"},
+		},
+	}
+
+	enriched := prEvent("opened", "alice", "Cache", false)
+	enriched.Details = &watcher.Details{
+		Draft: true, Labels: []string{"perf"}, Assignees: []string{"bob"}, Milestone: "v2",
+		RequestedReviewers: []string{"carol"}, Additions: 120, ChangedFiles: 4,
+	}
+
+	tests := []struct {
+		expr  string
+		event watcher.Event
+		want  bool
+	}{
+		{`type == "PullRequestEvent" && action in ["opened","closed"] && !actor.endsWith("[bot]") && title =~ "(?i)security"`, merged, true},
+		{`type == "PullRequestEvent" && action in ["opened","closed"] && !actor.endsWith("[bot]") && title =~ "(?i)security"`, bot, false},
+		{`actor.endsWith("[bot]")`, bot, true},
+		{`merged`, merged, true},
+		{`merged == false`, bot, true},
+		{`!draft`, merged, true},
+		{`"security" in labels`, merged, true},
+		{`labels.contains("dependencies") || repo == "org/app"`, comment, true},
+		{`labels.contains("dependencies") || repo == "org/app"`, merged, false},
+		{`number >= 10 && number < 20`, merged, true},
+		{`number in [1, 2, 3]`, comment, true},
+		{`body.lower().contains("regression")`, comment, true},
+		{`body =~ "login"`, merged, true},
+		{`body !~ "login"`, merged, false},
+		{`title != 'Crash'`, comment, false},
+		{`labels.len() == 2`, merged, true},
+		{`(action == "created" || action == "edited") && repo.startsWith("org/")`, comment, true},
+		{`alerts.len() == 0`, comment, true},
+		{`milestone == "v2" && "bob" in assignees && reviewers.contains("carol")`, enriched, true},
+		{`additions > 100 && changed_files == 4 && "perf" in labels`, enriched, true},
+		{`draft`, enriched, true},
+		{`milestone == "" && reviewers.len() == 0 && additions == 0`, merged, true},
+	}
+	for _, tt := range tests {
+		f, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if got := f.Match(tt.event); got != tt.want {
+			t.Errorf("%q on %s by %s = %v, want %v", tt.expr, tt.event.Type, tt.event.Actor.Login, got, tt.want)
+		}
+	}
+}
+
+func TestMatchPayload(t *testing.T) {
+	var e watcher.Event
+	raw := `{"id":"1","type":"PushEvent","actor":{"login":"alice"},"repo":{"name":"org/api"},
+		"payload":{"ref":"refs/heads/main","size":2,"forced":false,"commits":[{"sha":"abc","message":"Fix build"},{"sha":"def","message":"WIP"}],
+		"issue":{"labels":[{"name":"bug"}]}}}`
+	if err := json.Unmarshal([]byte(raw), &e); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`payload.ref == "refs/heads/main"`, true},
+		{`payload.size >= 2`, true},
+		{`payload.forced`, false},
+		{`!payload.missing`, true},
+		{`payload.commits[1].message == "WIP"`, true},
+		{`payload.commits[2].message == ""`, true},
+		{`payload.commits.len() == 2`, true},
+		{`payload.issue.labels[0].name in ["bug", "crash"]`, true},
+		{`payload.commits[0].message.lower().startsWith("fix")`, true},
+		{`payload.ref =~ "^refs/heads/"`, true},
+		{`payload.ref.contains("main") && actor == "alice"`, true},
+		{`payload.size == payload.commits.len()`, true},
+	}
+	for _, tt := range tests {
+		f, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if got := f.Match(e); got != tt.want {
+			t.Errorf("%q = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		pos  int
+		msg  string
+	}{
+		{``, 0, "empty expression"},
+		{`type = "x"`, 5, `use "==" to compare`},
+		{`titel == "x"`, 0, `unknown field "titel"`},
+		{`type == "x`, 8, "unterminated string"},
+		{`type == 3`, 5, "cannot compare string with number"},
+		{`action in "opened"`, 10, "in needs a list"},
+		{`action in ["a", 1]`, 16, "list mixes strings and numbers"},
+		{`title =~ "(unclosed"`, 9, "invalid regular expression"},
+		{`title =~ body`, 9, "string literal pattern"},
+		{`actor.endswith("[bot]")`, 6, `unknown method "endswith" for a string (have startsWith, endsWith, contains, lower, upper, len)`},
+		{`actor.endsWith(1)`, 15, "endsWith wants a string argument"},
+		{`type == "x" action == "y"`, 12, "missing && or ||?"},
+		{`title`, 0, "expression is a string, not a condition"},
+		{`type == "x" && number`, 15, "&& needs conditions on both sides"},
+		{`(type == "x"`, 12, "expected ), found end of expression"},
+		{`type == "x" # y`, 12, `unexpected character '#'`},
+		{`payload.commits[x]`, 16, "expected index after ["},
+		{`payload.size.startsWith(1)`, 24, "startsWith wants a string argument"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.expr)
+		if err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", tt.expr)
+			continue
+		}
+		perr, ok := err.(*Error)
+		if !ok {
+			t.Errorf("Parse(%q) returned %T, want *Error", tt.expr, err)
+			continue
+		}
+		if perr.Pos != tt.pos || !strings.Contains(perr.Msg, tt.msg) {
+			t.Errorf("Parse(%q) = %d: %q, want %d: %q", tt.expr, perr.Pos, perr.Msg, tt.pos, tt.msg)
+		}
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	_, err := Parse(`type == "x" && titel == "y"`)
+	want := "invalid filter at column 16: unknown field \"titel\" (have type, action, actor, repo, number, title, body, labels, merged, draft, assignees, milestone, reviewers, additions, deletions, changed_files, alerts and payload paths)\n" +
+		"  type == \"x\" && titel == \"y\"\n" +
+		"                 ^"
+	if err == nil || err.Error() != want {
+		t.Errorf("unexpected error:\n%v\nwant:\n%s", err, want)
+	}
+}
+
+func TestAnd(t *testing.T) {
+	if And(nil, nil) != nil {
+		t.Error("expected nil for no filters")
+	}
+	a := MustParse(`repo == "org/api"`)
+	if And(nil, a) != a {
+		t.Error("expected a single filter to be returned as is")
+	}
+	both := And(a, MustParse(`merged`))
+	if both.String() != `(repo == "org/api") && (merged)` {
+		t.Errorf("String() = %q", both.String())
+	}
+	if !both.Match(prEvent("closed", "alice", "x", true)) || both.Match(prEvent("closed", "alice", "x", false)) {
+		t.Error("combined filter should require both conditions")
+	}
+	var none *Filter
+	if !none.Match(watcher.Event{}) {
+		t.Error("nil filter should match everything")
+	}
+}
+
+func TestUsesDetails(t *testing.T) {
+	if MustParse(`labels.contains("bug") && draft`).UsesDetails() {
+		t.Error("payload fields do not need enrichment")
+	}
+	details := MustParse(`additions > 100`)
+	if !details.UsesDetails() || !And(MustParse(`merged`), details).UsesDetails() {
+		t.Error("expected diff stats to need enrichment")
+	}
+	var none *Filter
+	if none.UsesDetails() {
+		t.Error("nil filter uses nothing")
+	}
+}
+
+func TestReject(t *testing.T) {
+	if err := MustParse(`merged`).Reject("alerts", "is not set"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	var none *Filter
+	if none.Reject("alerts", "is not set") != nil {
+		t.Error("nil filter uses nothing")
+	}
+	for _, tt := range []struct {
+		f   *Filter
+		pos int
+	}{
+		{MustParse(`"x" in alerts || alerts.len() > 1`), 7},
+		{And(MustParse(`merged`), MustParse(`"x" in alerts`)), 20},
+	} {
+		err := tt.f.Reject("alerts", "is not set")
+		perr, ok := err.(*Error)
+		if !ok || perr.Pos != tt.pos || perr.Msg != "alerts is not set" {
+			t.Errorf("Reject on %q = %v, want position %d", tt.f, err, tt.pos)
+		}
+	}
+}
+
+func TestDefault(t *testing.T) {
+	f := Default()
+	for _, typ := range watcher.TargetEventTypes {
+		if !f.Match(watcher.Event{Type: typ}) {
+			t.Errorf("default filter rejects %s", typ)
+		}
+	}
+	if f.Match(watcher.Event{Type: "WatchEvent"}) {
+		t.Error("default filter accepts WatchEvent")
+	}
+}
+
+func TestTypes(t *testing.T) {
+	f := Types("PushEvent", "CreateEvent")
+	for _, typ := range []string{"PushEvent", "CreateEvent"} {
+		if !f.Match(watcher.Event{Type: typ}) {
+			t.Errorf("%s rejects %s", f, typ)
+		}
+	}
+	if f.Match(watcher.Event{Type: "IssuesEvent"}) {
+		t.Errorf("%s accepts IssuesEvent", f)
+	}
+	if g, err := Parse(f.String()); err != nil || !g.Match(watcher.Event{Type: "CreateEvent"}) {
+		t.Errorf("Parse(%s) = %v", f, err)
+	}
+}
@@ -0,0 +1,146 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+	tokComma
+	tokDot
+)
+
+// token is a lexical token with its byte offset in the expression
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return "string " + t.text
+	case tokNumber:
+		return "number " + t.text
+	case tokIdent:
+		return fmt.Sprintf("%q", t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// operators, longest first so that "==" wins over "="
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!"}
+
+// lex splits an expression into tokens
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		r, size := utf8.DecodeRuneInString(src[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '"' || r == '\'':
+			end, err := scanString(src, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{tokString, src[i:end], i})
+			i = end
+		case r >= '0' && r <= '9':
+			end := i
+			for end < len(src) && src[end] >= '0' && src[end] <= '9' {
+				end++
+			}
+			tokens = append(tokens, token{tokNumber, src[i:end], i})
+			i = end
+		case r == '_' || unicode.IsLetter(r):
+			end := i
+			for end < len(src) {
+				r, size := utf8.DecodeRuneInString(src[end:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				end += size
+			}
+			tokens = append(tokens, token{tokIdent, src[i:end], i})
+			i = end
+		default:
+			kind := tokenKind(-1)
+			switch r {
+			case '(':
+				kind = tokLParen
+			case ')':
+				kind = tokRParen
+			case '[':
+				kind = tokLBracket
+			case ']':
+				kind = tokRBracket
+			case ',':
+				kind = tokComma
+			case '.':
+				kind = tokDot
+			}
+			if kind >= 0 {
+				tokens = append(tokens, token{kind, string(r), i})
+				i += size
+				continue
+			}
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				if r == '=' {
+					return nil, &Error{Expr: src, Pos: i, Msg: `unexpected "="; use "==" to compare`}
+				}
+				return nil, &Error{Expr: src, Pos: i, Msg: fmt.Sprintf("unexpected character %q", r)}
+			}
+			tokens = append(tokens, token{tokOp, op, i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(src)}), nil
+}
+
+// scanString returns the end offset of the quoted string starting at start
+func scanString(src string, start int) (int, error) {
+	quote := src[start]
+	for i := start + 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1, nil
+		}
+	}
+	return 0, &Error{Expr: src, Pos: start, Msg: "unterminated string"}
+}
+
+// unquote decodes a string literal; single-quoted strings are taken literally apart from escaped quotes
+func unquote(lit string) (string, error) {
+	if lit[0] == '\'' {
+		inner := lit[1 : len(lit)-1]
+		return strings.NewReplacer(`\\`, `\`, `\'`, `'`).Replace(inner), nil
+	}
+	return strconv.Unquote(lit)
+}
@@ -0,0 +1,712 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// valueType is the static type of an expression
+type valueType int
+
+const (
+	typeString valueType = iota
+	typeNumber
+	typeBool
+	typeStringList
+	typeNumberList
+	// typeAny is a payload path, whose type is only known per event; it is converted to
+	// the type its use calls for
+	typeAny
+)
+
+func (t valueType) String() string {
+	switch t {
+	case typeString:
+		return "string"
+	case typeNumber:
+		return "number"
+	case typeBool:
+		return "boolean"
+	case typeStringList:
+		return "list of strings"
+	case typeNumberList:
+		return "list of numbers"
+	case typeAny:
+		return "payload value"
+	}
+	return "unknown"
+}
+
+// elem returns the element type of a list type
+func (t valueType) elem() (valueType, bool) {
+	switch t {
+	case typeStringList:
+		return typeString, true
+	case typeNumberList:
+		return typeNumber, true
+	}
+	return 0, false
+}
+
+// node is a type-checked expression node
+type node interface {
+	typ() valueType
+	pos() int
+	eval(e watcher.Event) any
+}
+
+type literalNode struct {
+	value any
+	t     valueType
+	p     int
+}
+
+func (n *literalNode) typ() valueType         { return n.t }
+func (n *literalNode) pos() int               { return n.p }
+func (n *literalNode) eval(watcher.Event) any { return n.value }
+
+type fieldNode struct {
+	f field
+	p int
+}
+
+func (n *fieldNode) typ() valueType           { return n.f.typ }
+func (n *fieldNode) pos() int                 { return n.p }
+func (n *fieldNode) eval(e watcher.Event) any { return n.f.get(e) }
+
+// pathNode looks up a path into the raw payload, e.g. payload.issue.labels[0].name;
+// missing values are nil
+type pathNode struct {
+	path string
+	p    int
+}
+
+func (n *pathNode) typ() valueType { return typeAny }
+func (n *pathNode) pos() int       { return n.p }
+func (n *pathNode) eval(e watcher.Event) any {
+	v, _ := e.Lookup(n.path)
+	return v
+}
+
+// convertNode converts a payload value to the type its use calls for
+type convertNode struct {
+	x node
+	t valueType
+}
+
+func (n *convertNode) typ() valueType { return n.t }
+func (n *convertNode) pos() int       { return n.x.pos() }
+func (n *convertNode) eval(e watcher.Event) any {
+	v := n.x.eval(e)
+	switch n.t {
+	case typeString:
+		return toString(v)
+	case typeNumber:
+		return toNumber(v)
+	case typeBool:
+		return toBool(v)
+	}
+	items, ok := v.([]any)
+	if !ok && v != nil {
+		items = []any{v}
+	}
+	if n.t == typeNumberList {
+		out := make([]int, len(items))
+		for i, item := range items {
+			out[i] = toNumber(item)
+		}
+		return out
+	}
+	out := make([]string, len(items))
+	for i, item := range items {
+		out[i] = toString(item)
+	}
+	return out
+}
+
+// convert wraps a payload value so it evaluates to t; other nodes are returned as they are
+func convert(n node, t valueType) node {
+	if n.typ() != typeAny {
+		return n
+	}
+	return &convertNode{x: n, t: t}
+}
+
+// toString renders a payload value as a string: missing values are empty, and objects and
+// arrays are JSON
+func toString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// toNumber converts a payload value to a number; anything that is not one is zero
+func toNumber(v any) int {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return int(n)
+		}
+		f, _ := v.Float64()
+		return int(f)
+	case string:
+		n, _ := strconv.Atoi(v)
+		return n
+	}
+	return 0
+}
+
+// toBool reports whether a payload value is set: not missing, null, false, zero or empty
+func toBool(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case json.Number:
+		f, _ := v.Float64()
+		return f != 0
+	case []any:
+		return len(v) > 0
+	case map[string]any:
+		return len(v) > 0
+	}
+	return true
+}
+
+type notNode struct {
+	x node
+	p int
+}
+
+func (n *notNode) typ() valueType           { return typeBool }
+func (n *notNode) pos() int                 { return n.p }
+func (n *notNode) eval(e watcher.Event) any { return !n.x.eval(e).(bool) }
+
+type logicNode struct {
+	op          string
+	left, right node
+}
+
+func (n *logicNode) typ() valueType { return typeBool }
+func (n *logicNode) pos() int       { return n.left.pos() }
+func (n *logicNode) eval(e watcher.Event) any {
+	if n.op == "&&" {
+		return n.left.eval(e).(bool) && n.right.eval(e).(bool)
+	}
+	return n.left.eval(e).(bool) || n.right.eval(e).(bool)
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n *compareNode) typ() valueType { return typeBool }
+func (n *compareNode) pos() int       { return n.left.pos() }
+func (n *compareNode) eval(e watcher.Event) any {
+	l, r := n.left.eval(e), n.right.eval(e)
+	var c int
+	switch l := l.(type) {
+	case string:
+		c = strings.Compare(l, r.(string))
+	case int:
+		c = l - r.(int)
+	case bool:
+		if l != r.(bool) {
+			c = 1
+		}
+	}
+	switch n.op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	}
+	return c >= 0
+}
+
+type matchNode struct {
+	negate bool
+	left   node
+	re     *regexp.Regexp
+}
+
+func (n *matchNode) typ() valueType { return typeBool }
+func (n *matchNode) pos() int       { return n.left.pos() }
+func (n *matchNode) eval(e watcher.Event) any {
+	return n.re.MatchString(n.left.eval(e).(string)) != n.negate
+}
+
+type inNode struct {
+	elem, list node
+}
+
+func (n *inNode) typ() valueType { return typeBool }
+func (n *inNode) pos() int       { return n.elem.pos() }
+func (n *inNode) eval(e watcher.Event) any {
+	return contains(n.list.eval(e), n.elem.eval(e))
+}
+
+func contains(list, v any) bool {
+	switch list := list.(type) {
+	case []string:
+		return slices.Contains(list, v.(string))
+	case []int:
+		return slices.Contains(list, v.(int))
+	}
+	return false
+}
+
+type listNode struct {
+	items []node
+	t     valueType
+	p     int
+}
+
+func (n *listNode) typ() valueType { return n.t }
+func (n *listNode) pos() int       { return n.p }
+func (n *listNode) eval(e watcher.Event) any {
+	if n.t == typeNumberList {
+		out := make([]int, len(n.items))
+		for i, item := range n.items {
+			out[i] = item.eval(e).(int)
+		}
+		return out
+	}
+	out := make([]string, len(n.items))
+	for i, item := range n.items {
+		out[i] = item.eval(e).(string)
+	}
+	return out
+}
+
+type methodNode struct {
+	recv node
+	args []node
+	t    valueType
+	p    int
+	call func(recv any, args []any) any
+}
+
+func (n *methodNode) typ() valueType { return n.t }
+func (n *methodNode) pos() int       { return n.p }
+func (n *methodNode) eval(e watcher.Event) any {
+	args := make([]any, len(n.args))
+	for i, a := range n.args {
+		args[i] = a.eval(e)
+	}
+	return n.call(n.recv.eval(e), args)
+}
+
+// method is a function callable on a value with dot syntax, e.g. actor.endsWith("[bot]")
+type method struct {
+	recv   valueType
+	name   string
+	args   []valueType
+	result valueType
+	call   func(recv any, args []any) any
+}
+
+var methods = []method{
+	{typeString, "startsWith", []valueType{typeString}, typeBool, func(r any, a []any) any { return strings.HasPrefix(r.(string), a[0].(string)) }},
+	{typeString, "endsWith", []valueType{typeString}, typeBool, func(r any, a []any) any { return strings.HasSuffix(r.(string), a[0].(string)) }},
+	{typeString, "contains", []valueType{typeString}, typeBool, func(r any, a []any) any { return strings.Contains(r.(string), a[0].(string)) }},
+	{typeString, "lower", nil, typeString, func(r any, a []any) any { return strings.ToLower(r.(string)) }},
+	{typeString, "upper", nil, typeString, func(r any, a []any) any { return strings.ToUpper(r.(string)) }},
+	{typeStringList, "contains", []valueType{typeString}, typeBool, func(r any, a []any) any { return contains(r, a[0]) }},
+	{typeNumberList, "contains", []valueType{typeNumber}, typeBool, func(r any, a []any) any { return contains(r, a[0]) }},
+	{typeStringList, "len", nil, typeNumber, func(r any, a []any) any { return len(r.([]string)) }},
+	{typeString, "len", nil, typeNumber, func(r any, a []any) any { return len([]rune(r.(string))) }},
+	{typeAny, "contains", []valueType{typeString}, typeBool, func(r any, a []any) any {
+		if items, ok := r.([]any); ok {
+			return slices.ContainsFunc(items, func(item any) bool { return toString(item) == a[0] })
+		}
+		return strings.Contains(toString(r), a[0].(string))
+	}},
+	{typeAny, "len", nil, typeNumber, func(r any, a []any) any {
+		switch r := r.(type) {
+		case []any:
+			return len(r)
+		case map[string]any:
+			return len(r)
+		}
+		return len([]rune(toString(r)))
+	}},
+}
+
+// parser is a recursive descent parser over the token stream:
+//
+//	or      = and { "||" and }
+//	and     = unary { "&&" unary }
+//	unary   = "!" unary | compare
+//	compare = postfix [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" | "=~" | "!~" | "in" ) postfix ]
+//	postfix = primary { "." ident "(" [ or { "," or } ] ")" }
+//	primary = string | number | "true" | "false" | field | path | "[" [ or { "," or } ] "]" | "(" or ")"
+//	path    = "payload" { "." ident | "[" number "]" }
+type parser struct {
+	src    string
+	tokens []token
+	i      int
+	// details records whether a field from enrichment was used
+	details bool
+	// uses records the position of the first use of each field
+	uses map[string]int
+}
+
+func (p *parser) peek() token { return p.tokens[p.i] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) errorf(pos int, format string, args ...any) error {
+	return &Error{Expr: p.src, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, p.errorf(t.pos, "expected %s, found %s", what, t)
+	}
+	return t, nil
+}
+
+func (p *parser) parse() (node, error) {
+	if p.peek().kind == tokEOF {
+		return nil, p.errorf(0, "empty expression")
+	}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t.pos, "unexpected %s; missing && or ||?", t)
+	}
+	return n, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	return p.parseLogic("||", p.parseAnd)
+}
+
+func (p *parser) parseAnd() (node, error) {
+	return p.parseLogic("&&", p.parseUnary)
+}
+
+func (p *parser) parseLogic(op string, operand func() (node, error)) (node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOp && p.peek().text == op {
+		p.next()
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left, right = convert(left, typeBool), convert(right, typeBool)
+		for _, n := range []node{left, right} {
+			if n.typ() != typeBool {
+				return nil, p.errorf(n.pos(), "%s needs conditions on both sides, found a %s", op, n.typ())
+			}
+		}
+		left = &logicNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if t := p.peek(); t.kind == tokOp && t.text == "!" {
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		x = convert(x, typeBool)
+		if x.typ() != typeBool {
+			return nil, p.errorf(x.pos(), "! needs a condition, found a %s", x.typ())
+		}
+		return &notNode{x: x, p: t.pos}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (node, error) {
+	left, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	isIn := t.kind == tokIdent && t.text == "in"
+	if !isIn && (t.kind != tokOp || t.text == "&&" || t.text == "||" || t.text == "!") {
+		return left, nil
+	}
+	p.next()
+	right, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case isIn:
+		if right.typ() == typeAny {
+			list := typeStringList
+			if left.typ() == typeNumber {
+				list = typeNumberList
+			}
+			right = convert(right, list)
+		}
+		elem, ok := right.typ().elem()
+		if !ok {
+			return nil, p.errorf(right.pos(), "in needs a list on the right, found a %s", right.typ())
+		}
+		left = convert(left, elem)
+		if left.typ() != elem {
+			return nil, p.errorf(left.pos(), "cannot look for a %s in a %s", left.typ(), right.typ())
+		}
+		return &inNode{elem: left, list: right}, nil
+
+	case t.text == "=~" || t.text == "!~":
+		left = convert(left, typeString)
+		if left.typ() != typeString {
+			return nil, p.errorf(left.pos(), "%s needs a string on the left, found a %s", t.text, left.typ())
+		}
+		lit, ok := right.(*literalNode)
+		if !ok || lit.t != typeString {
+			return nil, p.errorf(right.pos(), "%s needs a string literal pattern on the right", t.text)
+		}
+		re, err := regexp.Compile(lit.value.(string))
+		if err != nil {
+			return nil, p.errorf(right.pos(), "invalid regular expression: %v", err)
+		}
+		return &matchNode{negate: t.text == "!~", left: left, re: re}, nil
+	}
+
+	switch {
+	case left.typ() == typeAny && right.typ() == typeAny:
+		left, right = convert(left, typeString), convert(right, typeString)
+	case left.typ() == typeAny:
+		left = convert(left, right.typ())
+	case right.typ() == typeAny:
+		right = convert(right, left.typ())
+	}
+	if left.typ() != right.typ() {
+		return nil, p.errorf(t.pos, "cannot compare %s with %s", left.typ(), right.typ())
+	}
+	if _, isList := left.typ().elem(); isList {
+		return nil, p.errorf(t.pos, "cannot compare lists; use in or .contains()")
+	}
+	if left.typ() == typeBool && t.text != "==" && t.text != "!=" {
+		return nil, p.errorf(t.pos, "%s is not defined for booleans", t.text)
+	}
+	return &compareNode{op: t.text, left: left, right: right}, nil
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	n, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokDot {
+		p.next()
+		name, err := p.expect(tokIdent, "method name after .")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokLParen, fmt.Sprintf("( after %s", name.text)); err != nil {
+			return nil, err
+		}
+		args, err := p.parseList(tokRParen, ")")
+		if err != nil {
+			return nil, err
+		}
+		n, err = p.bindMethod(n, name, args)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// bindMethod resolves a method call against the receiver's type. Payload values have
+// contains and len of their own and take the string methods otherwise.
+func (p *parser) bindMethod(recv node, name token, args []node) (node, error) {
+	if recv.typ() == typeAny && !slices.ContainsFunc(methods, func(m method) bool {
+		return m.recv == typeAny && m.name == name.text
+	}) {
+		recv = convert(recv, typeString)
+	}
+	var known []string
+	for _, m := range methods {
+		if m.recv != recv.typ() {
+			continue
+		}
+		known = append(known, m.name)
+		if m.name != name.text {
+			continue
+		}
+		if len(args) != len(m.args) {
+			return nil, p.errorf(name.pos, "%s takes %d argument(s), found %d", m.name, len(m.args), len(args))
+		}
+		for i, a := range args {
+			if a.typ() != m.args[i] {
+				return nil, p.errorf(a.pos(), "%s wants a %s argument, found a %s", m.name, m.args[i], a.typ())
+			}
+		}
+		return &methodNode{recv: recv, args: args, t: m.result, p: name.pos, call: m.call}, nil
+	}
+	if len(known) == 0 {
+		return nil, p.errorf(name.pos, "a %s has no methods", recv.typ())
+	}
+	return nil, p.errorf(name.pos, "unknown method %q for a %s (have %s)", name.text, recv.typ(), strings.Join(known, ", "))
+}
+
+// parsePath parses the keys and indexes after "payload". A dot followed by a name and "("
+// is a method call and ends the path.
+func (p *parser) parsePath(start token) (node, error) {
+	path := start.text
+	for {
+		switch p.peek().kind {
+		case tokDot:
+			name := p.tokens[p.i+1]
+			if name.kind != tokIdent || p.tokens[p.i+2].kind == tokLParen {
+				return &pathNode{path: path, p: start.pos}, nil
+			}
+			p.i += 2
+			path += "." + name.text
+		case tokLBracket:
+			p.next()
+			index, err := p.expect(tokNumber, "index after [")
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(tokRBracket, "]"); err != nil {
+				return nil, err
+			}
+			path += "[" + index.text + "]"
+		default:
+			return &pathNode{path: path, p: start.pos}, nil
+		}
+	}
+}
+
+// parseList parses comma-separated expressions up to the closing token
+func (p *parser) parseList(closing tokenKind, closingText string) ([]node, error) {
+	var items []node
+	if p.peek().kind == closing {
+		p.next()
+		return items, nil
+	}
+	for {
+		item, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		t := p.next()
+		switch t.kind {
+		case closing:
+			return items, nil
+		case tokComma:
+		default:
+			return nil, p.errorf(t.pos, "expected , or %s, found %s", closingText, t)
+		}
+	}
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString:
+		s, err := unquote(t.text)
+		if err != nil {
+			return nil, p.errorf(t.pos, "invalid string %s", t.text)
+		}
+		return &literalNode{value: s, t: typeString, p: t.pos}, nil
+
+	case tokNumber:
+		n, err := strconv.Atoi(t.text)
+		if err != nil {
+			return nil, p.errorf(t.pos, "invalid number %s", t.text)
+		}
+		return &literalNode{value: n, t: typeNumber, p: t.pos}, nil
+
+	case tokIdent:
+		switch t.text {
+		case "true", "false":
+			return &literalNode{value: t.text == "true", t: typeBool, p: t.pos}, nil
+		case "payload":
+			return p.parsePath(t)
+		}
+		f, ok := lookupField(t.text)
+		if !ok {
+			return nil, p.errorf(t.pos, "unknown field %q (have %s and payload paths)", t.text, strings.Join(Fields(), ", "))
+		}
+		p.details = p.details || slices.Contains(enrichedFields, f.name)
+		if _, ok := p.uses[f.name]; !ok {
+			if p.uses == nil {
+				p.uses = map[string]int{}
+			}
+			p.uses[f.name] = t.pos
+		}
+		return &fieldNode{f: f, p: t.pos}, nil
+
+	case tokLBracket:
+		items, err := p.parseList(tokRBracket, "]")
+		if err != nil {
+			return nil, err
+		}
+		list := &listNode{items: items, t: typeStringList, p: t.pos}
+		for i, item := range items {
+			if item.typ() != typeString && item.typ() != typeNumber {
+				return nil, p.errorf(item.pos(), "lists can hold strings or numbers, found a %s", item.typ())
+			}
+			if i == 0 && item.typ() == typeNumber {
+				list.t = typeNumberList
+			}
+			if elem, _ := list.t.elem(); item.typ() != elem {
+				return nil, p.errorf(item.pos(), "list mixes %ss and %ss", elem, item.typ())
+			}
+		}
+		return list, nil
+
+	case tokLParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokRParen, ")"); err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+	return nil, p.errorf(t.pos, "expected a value, found %s", t)
+}
@@ -1,13 +1,16 @@
+// Package formatter renders events in a compact layout that names the raw event type and action.
 package formatter
 
 import (
 	"fmt"
 	"strings"
 
-	"github.com/ytnobody/ghostio/internal/github"
+	"github.com/ytnobody/ghostio/internal/markdown"
+	"github.com/ytnobody/ghostio/internal/watcher"
 )
 
-func Format(event *github.Event) string {
+// Format renders the event header, then the issue or pull request it belongs to, its URL and body
+func Format(event *watcher.Event) string {
 	var b strings.Builder
 
 	timestamp := event.CreatedAt.Format("2006-01-02 15:04:05")
@@ -19,38 +22,18 @@ func Format(event *github.Event) string {
 	b.WriteString(fmt.Sprintf("[%s] %s (%s) by @%s\n",
 		timestamp, event.Type, action, event.Actor.Login))
 
-	switch event.Type {
-	case "IssuesEvent":
-		if issue := event.Payload.Issue; issue != nil {
-			b.WriteString(fmt.Sprintf("#%d: %s\n", issue.Number, issue.Title))
-			b.WriteString(fmt.Sprintf("%s\n", issue.HTMLURL))
-			if issue.Body != "" {
-				b.WriteString(fmt.Sprintf("\n%s\n", issue.Body))
-			}
-		}
-
-	case "PullRequestEvent":
-		if pr := event.Payload.PullRequest; pr != nil {
-			b.WriteString(fmt.Sprintf("#%d: %s\n", pr.Number, pr.Title))
-			b.WriteString(fmt.Sprintf("%s\n", pr.HTMLURL))
-			if pr.Body != "" {
-				b.WriteString(fmt.Sprintf("\n%s\n", pr.Body))
-			}
-		}
-
-	case "IssueCommentEvent", "PullRequestReviewCommentEvent":
-		if issue := event.Payload.Issue; issue != nil {
-			b.WriteString(fmt.Sprintf("on #%d: %s\n", issue.Number, issue.Title))
-		}
-		if pr := event.Payload.PullRequest; pr != nil {
-			b.WriteString(fmt.Sprintf("on #%d: %s\n", pr.Number, pr.Title))
-		}
-		if comment := event.Payload.Comment; comment != nil {
-			b.WriteString(fmt.Sprintf("%s\n", comment.HTMLURL))
-			if comment.Body != "" {
-				b.WriteString(fmt.Sprintf("\n%s\n", comment.Body))
-			}
+	if number := event.Number(); number != 0 {
+		prefix := ""
+		if event.Payload.Comment != nil {
+			prefix = "on "
 		}
+		b.WriteString(fmt.Sprintf("%s#%d: %s\n", prefix, number, event.Title()))
+	}
+	if url := event.HTMLURL(); url != "" {
+		b.WriteString(fmt.Sprintf("%s\n", url))
+	}
+	if body := markdown.Plain(event.Body()); body != "" {
+		b.WriteString(fmt.Sprintf("\n%s\n", body))
 	}
 
 	return b.String()
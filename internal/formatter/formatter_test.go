@@ -5,21 +5,21 @@ import (
 	"testing"
 	"time"
 
-	"github.com/ytnobody/ghostio/internal/github"
+	"github.com/ytnobody/ghostio/internal/watcher"
 )
 
 func TestFormat_IssuesEvent(t *testing.T) {
-	event := &github.Event{
+	event := &watcher.Event{
 		Type: "IssuesEvent",
-		Actor: github.Actor{
+		Actor: watcher.Actor{
 			Login: "testuser",
 		},
-		Repo: github.Repo{
-			FullName: "owner/repo",
+		Repo: watcher.Repo{
+			Name: "owner/repo",
 		},
-		Payload: github.Payload{
+		Payload: watcher.Payload{
 			Action: "opened",
-			Issue: &github.Issue{
+			Issue: &watcher.Issue{
 				Number:  42,
 				Title:   "Bug in login",
 				Body:    "Login fails with error 500",
@@ -48,14 +48,14 @@ func TestFormat_IssuesEvent(t *testing.T) {
 }
 
 func TestFormat_PullRequestEvent(t *testing.T) {
-	event := &github.Event{
+	event := &watcher.Event{
 		Type: "PullRequestEvent",
-		Actor: github.Actor{
+		Actor: watcher.Actor{
 			Login: "pruser",
 		},
-		Payload: github.Payload{
+		Payload: watcher.Payload{
 			Action: "opened",
-			PullRequest: &github.PullRequest{
+			PullRequest: &watcher.PullRequest{
 				Number:  123,
 				Title:   "Add new feature",
 				Body:    "This PR adds a cool feature",
@@ -82,18 +82,18 @@ func TestFormat_PullRequestEvent(t *testing.T) {
 }
 
 func TestFormat_IssueCommentEvent(t *testing.T) {
-	event := &github.Event{
+	event := &watcher.Event{
 		Type: "IssueCommentEvent",
-		Actor: github.Actor{
+		Actor: watcher.Actor{
 			Login: "commenter",
 		},
-		Payload: github.Payload{
+		Payload: watcher.Payload{
 			Action: "created",
-			Issue: &github.Issue{
+			Issue: &watcher.Issue{
 				Number: 42,
 				Title:  "Bug in login",
 			},
-			Comment: &github.Comment{
+			Comment: &watcher.Comment{
 				Body:    "I can reproduce this issue",
 				HTMLURL: "https://github.com/owner/repo/issues/42#issuecomment-1",
 			},
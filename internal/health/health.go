@@ -0,0 +1,84 @@
+// Package health serves liveness and readiness checks for the pollers.
+package health
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// Checker answers health checks from the current poller status
+type Checker struct {
+	status func() []watcher.PollerStatus
+	now    func() time.Time
+}
+
+// NewChecker creates a checker reading poller state from status
+func NewChecker(status func() []watcher.PollerStatus) *Checker {
+	return &Checker{status: status, now: time.Now}
+}
+
+// Register adds /healthz and /readyz to mux
+func (c *Checker) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", c.serveHealth)
+	mux.HandleFunc("/readyz", c.serveReady)
+}
+
+// Healthy returns the problems that make the process unhealthy: pollers that are stuck
+func (c *Checker) Healthy() []string {
+	now := c.now()
+	var problems []string
+	for _, s := range c.status() {
+		if s.Stuck(now) {
+			problems = append(problems, fmt.Sprintf("%s: no poll since %s", s.Repo, lastActivity(s).Format(time.RFC3339)))
+		}
+	}
+	return problems
+}
+
+// Ready returns the reasons the process is not ready to serve
+func (c *Checker) Ready() []string {
+	statuses := c.status()
+	if len(statuses) == 0 {
+		return []string{"no pollers running"}
+	}
+	var problems []string
+	for _, s := range statuses {
+		switch {
+		case s.LastSuccess.IsZero():
+			problems = append(problems, s.Repo+": no successful poll yet")
+		case s.RateLimitRemaining == 0:
+			problems = append(problems, s.Repo+": rate limit exhausted")
+		}
+	}
+	return problems
+}
+
+func lastActivity(s watcher.PollerStatus) time.Time {
+	if s.LastPoll.IsZero() {
+		return s.Started
+	}
+	return s.LastPoll
+}
+
+func (c *Checker) serveHealth(w http.ResponseWriter, r *http.Request) {
+	writeResult(w, c.Healthy())
+}
+
+func (c *Checker) serveReady(w http.ResponseWriter, r *http.Request) {
+	writeResult(w, c.Ready())
+}
+
+func writeResult(w http.ResponseWriter, problems []string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	if len(problems) > 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintln(w, strings.Join(problems, "\n"))
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
@@ -0,0 +1,67 @@
+package health
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+func TestChecker(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	healthy := watcher.PollerStatus{
+		Repo:               "org/api",
+		Interval:           time.Minute,
+		Started:            now.Add(-time.Hour),
+		LastPoll:           now.Add(-30 * time.Second),
+		LastSuccess:        now.Add(-30 * time.Second),
+		RateLimitRemaining: 4000,
+	}
+	starting := watcher.PollerStatus{Repo: "org/new", Interval: time.Minute, Started: now.Add(-10 * time.Second), RateLimitRemaining: -1}
+	limited := healthy
+	limited.Repo = "org/limited"
+	limited.RateLimitRemaining = 0
+	stuck := healthy
+	stuck.Repo = "org/stuck"
+	stuck.LastPoll = now.Add(-10 * time.Minute)
+
+	tests := []struct {
+		name        string
+		statuses    []watcher.PollerStatus
+		healthCode  int
+		readyCode   int
+		readyReason string
+	}{
+		{"all good", []watcher.PollerStatus{healthy}, http.StatusOK, http.StatusOK, ""},
+		{"no successful poll", []watcher.PollerStatus{healthy, starting}, http.StatusOK, http.StatusServiceUnavailable, "org/new: no successful poll yet"},
+		{"rate limited", []watcher.PollerStatus{limited}, http.StatusOK, http.StatusServiceUnavailable, "org/limited: rate limit exhausted"},
+		{"stuck poller", []watcher.PollerStatus{healthy, stuck}, http.StatusServiceUnavailable, http.StatusOK, ""},
+		{"no pollers", nil, http.StatusOK, http.StatusServiceUnavailable, "no pollers running"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker(func() []watcher.PollerStatus { return tt.statuses })
+			c.now = func() time.Time { return now }
+			mux := http.NewServeMux()
+			c.Register(mux)
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+			if rec.Code != tt.healthCode {
+				t.Errorf("/healthz = %d, want %d (%s)", rec.Code, tt.healthCode, rec.Body.String())
+			}
+
+			rec = httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+			if rec.Code != tt.readyCode {
+				t.Errorf("/readyz = %d, want %d (%s)", rec.Code, tt.readyCode, rec.Body.String())
+			}
+			if tt.readyReason != "" && !strings.Contains(rec.Body.String(), tt.readyReason) {
+				t.Errorf("/readyz body %q does not mention %q", rec.Body.String(), tt.readyReason)
+			}
+		})
+	}
+}
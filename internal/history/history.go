@@ -0,0 +1,267 @@
+// Package history keeps every delivered event on disk and searches them.
+//
+// Events are appended to a log, one JSON object per line, by any number of processes.
+// An inverted index over titles and bodies is kept beside the log and brought up to date
+// with it whenever the store is opened for searching.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// File names inside the store directory
+const (
+	logName   = "events.log"
+	indexName = "index"
+)
+
+// indexVersion changes whenever the index layout or tokenization does, forcing a rebuild
+const indexVersion = 1
+
+// DefaultDir returns $XDG_DATA_HOME/ghostio/history, falling back to ~/.local/share
+func DefaultDir() string {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "ghostio", "history")
+}
+
+// Recorder is a sink appending events to the log in dir. Merged events are recorded as
+// their parts so that each can be found on its own.
+type Recorder struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewRecorder opens the log in dir for appending, creating it if needed
+func NewRecorder(dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, logName), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	return &Recorder{f: f}, nil
+}
+
+// Send appends the event, and the events merged into it, to the log
+func (r *Recorder) Send(e watcher.Event) error {
+	parts := append([]watcher.Event{e}, e.Coalesced...)
+	parts[0].Coalesced = nil
+	var buf bytes.Buffer
+	for _, p := range parts {
+		line, err := json.Marshal(p)
+		if err != nil {
+			return fmt.Errorf("failed to encode event: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	// One write per event keeps lines whole when several processes append
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.f.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to record event: %w", err)
+	}
+	return nil
+}
+
+// Close closes the log
+func (r *Recorder) Close() error {
+	return r.f.Close()
+}
+
+// Store is the log and its index opened for searching
+type Store struct {
+	dir string
+	log *os.File
+	idx index
+	ids map[string]bool
+}
+
+// index is the inverted index persisted beside the log
+type index struct {
+	Version int
+	// Size is how many bytes of the log are indexed
+	Size int64
+	Docs []doc
+	// Terms maps each term to the documents containing it
+	Terms map[string][]posting
+}
+
+// doc is an indexed event
+type doc struct {
+	Offset  int64
+	ID      string
+	Repo    string
+	Type    string
+	Created int64
+	// Length is the weighted number of terms
+	Length int
+}
+
+// posting records how often a term occurs in a document, weighted by field
+type posting struct {
+	Doc  int32
+	Freq uint16
+}
+
+// Open opens the store in dir and indexes events logged since it was last opened
+func Open(dir string) (*Store, error) {
+	log, err := os.Open(filepath.Join(dir, logName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no history in %s yet; events are recorded while watching", dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	s := &Store{dir: dir, log: log, ids: make(map[string]bool)}
+	if err := s.load(); err != nil {
+		log.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close closes the log
+func (s *Store) Close() error {
+	return s.log.Close()
+}
+
+// Len returns the number of indexed events
+func (s *Store) Len() int {
+	return len(s.idx.Docs)
+}
+
+// load reads the index, starting over when it is missing, outdated or ahead of the log,
+// and indexes the rest of the log
+func (s *Store) load() error {
+	info, err := s.log.Stat()
+	if err != nil {
+		return err
+	}
+	if f, err := os.Open(filepath.Join(s.dir, indexName)); err == nil {
+		err = gob.NewDecoder(bufio.NewReader(f)).Decode(&s.idx)
+		f.Close()
+		if err != nil || s.idx.Version != indexVersion || s.idx.Size > info.Size() {
+			s.idx = index{}
+		}
+	}
+	if s.idx.Terms == nil {
+		s.idx = index{Version: indexVersion, Terms: make(map[string][]posting)}
+	}
+	for _, d := range s.idx.Docs {
+		if d.ID != "" {
+			s.ids[d.ID] = true
+		}
+	}
+	if s.idx.Size == info.Size() {
+		return nil
+	}
+	if err := s.catchUp(); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+// catchUp indexes the complete lines of the log after the indexed size. Events already
+// indexed under the same ID are skipped.
+func (s *Store) catchUp() error {
+	if _, err := s.log.Seek(s.idx.Size, io.SeekStart); err != nil {
+		return err
+	}
+	r := bufio.NewReader(s.log)
+	offset := s.idx.Size
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// A partial line is still being written
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read history: %w", err)
+		}
+		var e watcher.Event
+		if json.Unmarshal(line, &e) == nil && (e.ID == "" || !s.ids[e.ID]) {
+			s.add(offset, e)
+		}
+		offset += int64(len(line))
+		s.idx.Size = offset
+	}
+}
+
+// add indexes an event logged at offset
+func (s *Store) add(offset int64, e watcher.Event) {
+	id := int32(len(s.idx.Docs))
+	freqs := termFreqs(e)
+	length := 0
+	for term, n := range freqs {
+		length += n
+		s.idx.Terms[term] = append(s.idx.Terms[term], posting{Doc: id, Freq: uint16(min(n, 1<<16-1))})
+	}
+	s.idx.Docs = append(s.idx.Docs, doc{
+		Offset:  offset,
+		ID:      e.ID,
+		Repo:    e.Repo.Name,
+		Type:    e.Type,
+		Created: e.CreatedAt.Unix(),
+		Length:  length,
+	})
+	if e.ID != "" {
+		s.ids[e.ID] = true
+	}
+}
+
+// save writes the index atomically, so concurrent searches see either version
+func (s *Store) save() error {
+	tmp, err := os.CreateTemp(s.dir, indexName+".*")
+	if err != nil {
+		return fmt.Errorf("failed to save history index: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	if err := gob.NewEncoder(w).Encode(&s.idx); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save history index: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save history index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save history index: %w", err)
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.dir, indexName))
+}
+
+// event reads the event logged at offset
+func (s *Store) event(offset int64) (watcher.Event, error) {
+	r := bufio.NewReader(io.NewSectionReader(s.log, offset, 1<<62))
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return watcher.Event{}, fmt.Errorf("failed to read history: %w", err)
+	}
+	var e watcher.Event
+	if err := json.Unmarshal(line, &e); err != nil {
+		return watcher.Event{}, fmt.Errorf("failed to parse history: %w", err)
+	}
+	return e, nil
+}
@@ -0,0 +1,145 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+var base = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+func commentEvent(id, repo, title, body string, age time.Duration) watcher.Event {
+	return watcher.Event{
+		ID:        id,
+		Type:      "IssueCommentEvent",
+		Actor:     watcher.Actor{Login: "bob"},
+		Repo:      watcher.Repo{Name: repo},
+		CreatedAt: base.Add(-age),
+		Payload: watcher.Payload{
+			Action:  "created",
+			Issue:   &watcher.Issue{Number: 12, Title: title},
+			Comment: &watcher.Comment{Body: body},
+		},
+	}
+}
+
+func record(t *testing.T, dir string, events ...watcher.Event) {
+	t.Helper()
+	r, err := NewRecorder(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range events {
+		if err := r.Send(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func search(t *testing.T, dir string, q Query) []string {
+	t.Helper()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	results, err := s.Search(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]string, len(results))
+	for i, r := range results {
+		ids[i] = r.Event.ID
+	}
+	return ids
+}
+
+func TestSearch(t *testing.T) {
+	dir := t.TempDir()
+	merge := watcher.Event{
+		ID: "5", Type: "PullRequestEvent", Repo: watcher.Repo{Name: "org/api"}, CreatedAt: base,
+		Payload:   watcher.Payload{Action: "closed", PullRequest: &watcher.PullRequest{Number: 12, Title: "Fix session handling"}},
+		Coalesced: []watcher.Event{commentEvent("6", "org/api", "Fix session handling", "The **login** bug is gone", 0)},
+	}
+	record(t, dir,
+		commentEvent("1", "org/api", "Login fails", "Still seeing the bug on login", time.Hour),
+		commentEvent("2", "org/api", "Crash", "A bug in the parser, unrelated to login pages", 2*time.Hour),
+		commentEvent("3", "org/web", "Login fails", "Same login bug here", 3*time.Hour),
+		commentEvent("4", "org/api", "Docs", "Typo", 10*24*time.Hour),
+		merge,
+	)
+
+	tests := []struct {
+		q    Query
+		want string
+	}{
+		// Title matches rank first, then shorter texts; every term must occur
+		{Query{Text: "login bug"}, "3 1 2 6"},
+		{Query{Text: "Login BUG", Repo: "org/api"}, "1 2 6"},
+		{Query{Text: "login bug", Types: []string{"PullRequestEvent"}}, ""},
+		{Query{Text: "login", Since: base.Add(-90 * time.Minute)}, "1 6"},
+		{Query{Text: "login bug", Limit: 1}, "3"},
+		{Query{Text: "nothing"}, ""},
+		// Without terms the newest come first
+		{Query{Repo: "org/api", Since: base.Add(-5 * time.Hour)}, "6 5 1 2"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(search(t, dir, tt.q), " "); got != tt.want {
+			t.Errorf("Search(%+v) = %q, want %q", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestIndexCatchUp(t *testing.T) {
+	dir := t.TempDir()
+	record(t, dir, commentEvent("1", "org/api", "Login fails", "bug", 0))
+	if got := search(t, dir, Query{Text: "bug"}); len(got) != 1 {
+		t.Fatalf("got %v", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, indexName)); err != nil {
+		t.Fatalf("index not saved: %v", err)
+	}
+
+	// New events, a repeated event and a line still being written
+	record(t, dir, commentEvent("2", "org/api", "Crash", "another bug", 0), commentEvent("1", "org/api", "Login fails", "bug", 0))
+	f, _ := os.OpenFile(filepath.Join(dir, logName), os.O_WRONLY|os.O_APPEND, 0)
+	f.WriteString(`{"id":"3","type":"IssuesEv`)
+	f.Close()
+	if got := strings.Join(search(t, dir, Query{Text: "bug"}), " "); got != "2 1" {
+		t.Errorf("after catching up got %q, want %q", got, "2 1")
+	}
+
+	// A corrupt index is rebuilt from the log
+	os.WriteFile(filepath.Join(dir, indexName), []byte("garbage"), 0o600)
+	if got := strings.Join(search(t, dir, Query{Text: "bug"}), " "); got != "2 1" {
+		t.Errorf("after rebuilding got %q, want %q", got, "2 1")
+	}
+
+	if _, err := Open(t.TempDir()); err == nil || !strings.Contains(err.Error(), "no history") {
+		t.Errorf("Open of an empty directory: %v", err)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	body := strings.Repeat("filler ", 40) + "the login page shows a bug when the session expires " + strings.Repeat("tail ", 40)
+	e := commentEvent("1", "org/api", "Login", body, 0)
+	mark := func(s string) string { return "[" + s + "]" }
+
+	got := Snippet(e, "login bug", mark)
+	if !strings.HasPrefix(got, "…filler filler filler the [login] page shows a [bug]") || !strings.HasSuffix(got, "…") {
+		t.Errorf("Snippet = %q", got)
+	}
+	if got := Snippet(commentEvent("1", "r", "t", "Short **Bug** report", 0), "bug", mark); got != "Short [Bug] report" {
+		t.Errorf("Snippet = %q", got)
+	}
+	if got := Highlight(`Login fails on "über" logins`, "login über", mark); got != `[Login] fails on "[über]" logins` {
+		t.Errorf("Highlight = %q", got)
+	}
+}
@@ -0,0 +1,245 @@
+package history
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ytnobody/ghostio/internal/markdown"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// titleWeight counts a term in a title as this many occurrences in a body
+const titleWeight = 3
+
+// BM25 parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Query selects events from the store
+type Query struct {
+	// Text holds the words that must all occur in the title or body; empty matches every event
+	Text string
+	// Repo keeps events of this repository only
+	Repo string
+	// Types keeps events of these types only
+	Types []string
+	// Since keeps events created at or after this time
+	Since time.Time
+	// Limit caps the number of results; zero returns all
+	Limit int
+}
+
+// Result is a matching event
+type Result struct {
+	Event watcher.Event
+	// Score ranks the match; higher is better
+	Score float64
+}
+
+// Terms splits text into lowercase search terms: runs of letters and digits
+func Terms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// termFreqs counts the weighted terms of an event's title and body
+func termFreqs(e watcher.Event) map[string]int {
+	freqs := map[string]int{}
+	for _, t := range Terms(e.Title()) {
+		freqs[t] += titleWeight
+	}
+	for _, t := range Terms(markdown.Plain(e.Body())) {
+		freqs[t]++
+	}
+	return freqs
+}
+
+// Search returns the events matching q, best first. Events matching every term are ranked
+// by BM25, with ties going to newer events; without terms the newest come first.
+func (s *Store) Search(q Query) ([]Result, error) {
+	keep := func(d doc) bool {
+		return (q.Repo == "" || d.Repo == q.Repo) &&
+			(len(q.Types) == 0 || slices.Contains(q.Types, d.Type)) &&
+			(q.Since.IsZero() || d.Created >= q.Since.Unix())
+	}
+
+	scores := map[int32]float64{}
+	terms := uniq(Terms(q.Text))
+	if len(terms) == 0 {
+		for i, d := range s.idx.Docs {
+			if keep(d) {
+				scores[int32(i)] = 0
+			}
+		}
+	} else {
+		scores = s.score(terms, keep)
+	}
+
+	ids := make([]int32, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := ids[i], ids[j]
+		if scores[a] != scores[b] {
+			return scores[a] > scores[b]
+		}
+		if s.idx.Docs[a].Created != s.idx.Docs[b].Created {
+			return s.idx.Docs[a].Created > s.idx.Docs[b].Created
+		}
+		return a > b
+	})
+	if q.Limit > 0 && len(ids) > q.Limit {
+		ids = ids[:q.Limit]
+	}
+
+	results := make([]Result, 0, len(ids))
+	for _, id := range ids {
+		e, err := s.event(s.idx.Docs[id].Offset)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, Result{Event: e, Score: scores[id]})
+	}
+	return results, nil
+}
+
+// score ranks the kept documents containing every term
+func (s *Store) score(terms []string, keep func(doc) bool) map[int32]float64 {
+	n := float64(len(s.idx.Docs))
+	total := 0
+	for _, d := range s.idx.Docs {
+		total += d.Length
+	}
+	avg := float64(total) / max(n, 1)
+
+	scores := map[int32]float64{}
+	matched := map[int32]int{}
+	for _, term := range terms {
+		postings := s.idx.Terms[term]
+		if len(postings) == 0 {
+			return nil
+		}
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for _, p := range postings {
+			d := s.idx.Docs[p.Doc]
+			if !keep(d) {
+				continue
+			}
+			tf := float64(p.Freq)
+			scores[p.Doc] += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*float64(d.Length)/avg))
+			matched[p.Doc]++
+		}
+	}
+	for id, count := range matched {
+		if count < len(terms) {
+			delete(scores, id)
+		}
+	}
+	return scores
+}
+
+func uniq(terms []string) []string {
+	var out []string
+	for _, t := range terms {
+		if !slices.Contains(out, t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// snippetWords is the length of a snippet, and snippetLead how many words come before the
+// first match when there is room
+const (
+	snippetWords = 24
+	snippetLead  = 4
+)
+
+// Snippet returns the part of the event's body with the most query terms, about
+// snippetWords long, with each occurrence of a term wrapped by mark. Text left out at
+// either end is shown as "…".
+func Snippet(e watcher.Event, query string, mark func(string) string) string {
+	words := strings.Fields(markdown.Plain(e.Body()))
+	if len(words) == 0 {
+		return ""
+	}
+	terms := uniq(Terms(query))
+	hits := make([]int, len(words)+1)
+	for i, w := range words {
+		hits[i+1] = hits[i]
+		for _, t := range Terms(w) {
+			if slices.Contains(terms, t) {
+				hits[i+1]++
+				break
+			}
+		}
+	}
+
+	// The window with the most hits, moved on so that a few words lead up to the first
+	size := min(snippetWords, len(words))
+	count := func(start int) int { return hits[start+size] - hits[start] }
+	start := 0
+	for i := 0; i+size <= len(words); i++ {
+		if count(i) > count(start) {
+			start = i
+		}
+	}
+	for start+size < len(words) && hits[start+snippetLead+1] == hits[start] && count(start+1) >= count(start) {
+		start++
+	}
+	text := markTerms(strings.Join(words[start:start+size], " "), terms, mark)
+	if start > 0 {
+		text = "…" + text
+	}
+	if start+size < len(words) {
+		text += "…"
+	}
+	return text
+}
+
+// Highlight wraps each occurrence of the query terms in text by mark
+func Highlight(text, query string, mark func(string) string) string {
+	return markTerms(text, uniq(Terms(query)), mark)
+}
+
+// markTerms wraps the words of text that are one of the terms by mark, splitting words
+// the way Terms does
+func markTerms(text string, terms []string, mark func(string) string) string {
+	if len(terms) == 0 || mark == nil {
+		return text
+	}
+	var b strings.Builder
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		word := text[start:end]
+		if slices.Contains(terms, strings.ToLower(word)) {
+			word = mark(word)
+		}
+		b.WriteString(word)
+		start = -1
+	}
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+		b.WriteRune(r)
+	}
+	flush(len(text))
+	return b.String()
+}
@@ -0,0 +1,366 @@
+// Package markdown renders GitHub-Flavored Markdown bodies of issues, pull requests and
+// comments for the terminal, with ANSI styling or as plain text.
+package markdown
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ANSI escapes used in color mode
+const (
+	ansiReset     = "\x1b[0m"
+	ansiBold      = "\x1b[1m"
+	ansiDim       = "\x1b[2m"
+	ansiItalic    = "\x1b[3m"
+	ansiUnderline = "\x1b[4m"
+	ansiStrike    = "\x1b[9m"
+	ansiCode      = "\x1b[36m"
+)
+
+// Options configures rendering
+type Options struct {
+	// Width wraps text to this many columns; zero does not wrap
+	Width int
+	// Color styles headings, emphasis and code with ANSI escapes; without it their markers
+	// are removed, for sinks that cannot take escapes
+	Color bool
+}
+
+var (
+	headingRe = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	listRe    = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+	taskRe    = regexp.MustCompile(`^\[([ xX])\]\s+(.*)$`)
+	ruleRe    = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+	fenceRe   = regexp.MustCompile("^\\s*(```|~~~)")
+	tableSep  = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+
+	commentRe = regexp.MustCompile(`(?s)<!--.*?-->`)
+	imgTagRe  = regexp.MustCompile(`(?i)<img\b[^>]*>`)
+	altRe     = regexp.MustCompile(`(?i)\balt\s*=\s*["']([^"']*)["']`)
+	brRe      = regexp.MustCompile(`(?i)<br\s*/?>`)
+	// tagRe matches the HTML tags GitHub bodies use; other <Word> text, such as Vec<String>, is kept
+	tagRe = regexp.MustCompile(`(?i)</?(?:a|abbr|b|blockquote|center|code|dd|del|details|div|dl|dt|em|h[1-6]|hr|i|ins|kbd|li|mark|ol|p|picture|pre|s|samp|small|source|span|strike|strong|sub|summary|sup|table|tbody|td|tfoot|th|thead|tr|tt|u|ul|var|video)(?:\s[^<>]*)?/?>`)
+
+	imageRe = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	linkRe  = regexp.MustCompile(`\[([^\]]*)\]\(([^)\s]+)[^)]*\)`)
+	codeRe  = regexp.MustCompile("`([^`]+)`")
+	boldRe  = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	// underRe is __bold__ between word boundaries; identifiers such as __init__ are kept as they are
+	underRe  = regexp.MustCompile(`(^|[^\w])__([^_\s](?:[^_]*[^_\s])?)__($|[^\w])`)
+	identRe  = regexp.MustCompile(`^\w+$`)
+	italicRe = regexp.MustCompile(`\*([^*\s](?:[^*]*[^*\s])?)\*`)
+	strikeRe = regexp.MustCompile(`~~([^~]+)~~`)
+	ansiRe   = regexp.MustCompile("\x1b\\[[0-9;]*m")
+)
+
+// Render renders markdown as terminal text. Line breaks are kept, as GitHub does for issue
+// and comment bodies, and long lines are wrapped to the width.
+func Render(src string, o Options) string {
+	r := renderer{width: o.Width, color: o.Color}
+	lines := clean(src)
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			r.blank()
+		case fenceRe.MatchString(line):
+			fence := fenceRe.FindStringSubmatch(line)[1]
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				r.code(lines[i])
+			}
+		case headingRe.MatchString(line):
+			text := r.inline(headingRe.FindStringSubmatch(line)[2])
+			r.blank()
+			if r.color {
+				text = ansiBold + ansiUnderline + text + ansiReset
+			}
+			r.wrap(text, "", "")
+			r.blank()
+		case strings.Contains(line, "|") && i+1 < len(lines) && tableSep.MatchString(lines[i+1]):
+			rows := [][]string{cells(line)}
+			for i += 2; i < len(lines) && strings.Contains(lines[i], "|"); i++ {
+				rows = append(rows, cells(lines[i]))
+			}
+			i--
+			r.table(rows)
+		case ruleRe.MatchString(line):
+			r.out = append(r.out, strings.Repeat("─", r.ruleWidth()))
+		case listRe.MatchString(line):
+			m := listRe.FindStringSubmatch(line)
+			pad := strings.Repeat(" ", len(strings.ReplaceAll(m[1], "\t", "    ")))
+			marker, text := m[2], m[3]
+			if marker == "-" || marker == "*" || marker == "+" {
+				marker = "•"
+				if task := taskRe.FindStringSubmatch(text); task != nil {
+					marker, text = "☐", task[2]
+					if task[1] != " " {
+						marker = "☑"
+					}
+				}
+			}
+			r.wrap(r.inline(text), pad+marker+" ", pad+strings.Repeat(" ", utf8.RuneCountInString(marker)+1))
+		case strings.HasPrefix(trimmed, ">"):
+			text := strings.TrimSpace(strings.TrimLeft(trimmed, "> "))
+			r.wrap(r.inline(text), "│ ", "│ ")
+		default:
+			r.wrap(r.inline(trimmed), "", "")
+		}
+	}
+	return strings.Join(r.trim(), "\n")
+}
+
+// Plain renders markdown without escapes or wrapping
+func Plain(src string) string {
+	return Render(src, Options{})
+}
+
+// clean splits the source into lines, dropping HTML comments and tags outside code blocks.
+// Images become "[image: alt]" and <br> becomes a line break.
+func clean(src string) []string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	var out []string
+	var fence string
+	var block []string
+	flush := func() {
+		if len(block) == 0 {
+			return
+		}
+		text := outsideCode(strings.Join(block, "\n"), func(text string) string {
+			text = commentRe.ReplaceAllString(text, "")
+			text = imgTagRe.ReplaceAllStringFunc(text, func(tag string) string {
+				if alt := altRe.FindStringSubmatch(tag); alt != nil && alt[1] != "" {
+					return "[image: " + alt[1] + "]"
+				}
+				return "[image]"
+			})
+			text = brRe.ReplaceAllString(text, "\n")
+			return tagRe.ReplaceAllString(text, "")
+		})
+		out = append(out, strings.Split(text, "\n")...)
+		block = nil
+	}
+
+	for _, line := range strings.Split(src, "\n") {
+		if m := fenceRe.FindStringSubmatch(line); m != nil {
+			switch {
+			case fence == "":
+				flush()
+				fence = m[1]
+			case m[1] == fence:
+				fence = ""
+				out = append(out, line)
+				continue
+			}
+		}
+		if fence != "" {
+			out = append(out, line)
+			continue
+		}
+		block = append(block, line)
+	}
+	flush()
+	return out
+}
+
+// cells splits a table row into trimmed cells
+func cells(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	line = strings.TrimSuffix(line, "|")
+	parts := strings.Split(line, "|")
+	for i, p := range parts {
+		parts[i] = strings.TrimSpace(p)
+	}
+	return parts
+}
+
+type renderer struct {
+	width int
+	color bool
+	out   []string
+}
+
+// blank adds an empty line unless the output is empty or already ends with one
+func (r *renderer) blank() {
+	if len(r.out) > 0 && r.out[len(r.out)-1] != "" {
+		r.out = append(r.out, "")
+	}
+}
+
+func (r *renderer) code(line string) {
+	line = "    " + strings.ReplaceAll(line, "\t", "    ")
+	if r.color {
+		line = ansiDim + line + ansiReset
+	}
+	r.out = append(r.out, line)
+}
+
+func (r *renderer) ruleWidth() int {
+	if r.width <= 0 || r.width > 40 {
+		return 40
+	}
+	return r.width
+}
+
+// table renders rows with aligned columns; the first row is the header
+func (r *renderer) table(rows [][]string) {
+	var widths []int
+	for i, row := range rows {
+		for j, cell := range row {
+			cell = r.inline(cell)
+			rows[i][j] = cell
+			if j >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[j] = max(widths[j], Width(cell))
+		}
+	}
+	line := func(row []string) string {
+		var b strings.Builder
+		for j, cell := range row {
+			if j > 0 {
+				b.WriteString("  ")
+			}
+			b.WriteString(cell)
+			if j < len(row)-1 {
+				b.WriteString(strings.Repeat(" ", widths[j]-Width(cell)))
+			}
+		}
+		return b.String()
+	}
+
+	header := line(rows[0])
+	if r.color {
+		header = ansiBold + header + ansiReset
+	}
+	r.out = append(r.out, header)
+	var sep []string
+	for _, w := range widths {
+		sep = append(sep, strings.Repeat("─", w))
+	}
+	r.out = append(r.out, strings.Join(sep, "  "))
+	for _, row := range rows[1:] {
+		r.out = append(r.out, line(row))
+	}
+}
+
+// trim drops trailing blank lines
+func (r *renderer) trim() []string {
+	out := r.out
+	for len(out) > 0 && out[len(out)-1] == "" {
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+// style wraps text in an escape in color mode
+func (r *renderer) style(escape, text string) string {
+	if r.color {
+		return escape + text + ansiReset
+	}
+	return text
+}
+
+// outsideCode applies fn to the text between inline code spans, leaving the spans as they are
+func outsideCode(text string, fn func(string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, span := range codeRe.FindAllStringIndex(text, -1) {
+		b.WriteString(fn(text[last:span[0]]))
+		b.WriteString(text[span[0]:span[1]])
+		last = span[1]
+	}
+	b.WriteString(fn(text[last:]))
+	return b.String()
+}
+
+// inline renders images, links, code spans and emphasis; code spans are shown as written
+func (r *renderer) inline(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, span := range codeRe.FindAllStringSubmatchIndex(text, -1) {
+		b.WriteString(r.emphasis(text[last:span[0]]))
+		b.WriteString(r.style(ansiCode, text[span[2]:span[3]]))
+		last = span[1]
+	}
+	b.WriteString(r.emphasis(text[last:]))
+	return b.String()
+}
+
+// emphasis renders images, links and emphasis in text without code spans
+func (r *renderer) emphasis(text string) string {
+	text = imageRe.ReplaceAllStringFunc(text, func(m string) string {
+		if alt := imageRe.FindStringSubmatch(m)[1]; alt != "" {
+			return "[image: " + alt + "]"
+		}
+		return "[image]"
+	})
+	text = linkRe.ReplaceAllStringFunc(text, func(m string) string {
+		sub := linkRe.FindStringSubmatch(m)
+		if sub[1] == "" || sub[1] == sub[2] {
+			return sub[2]
+		}
+		return sub[1] + " (" + sub[2] + ")"
+	})
+	text = boldRe.ReplaceAllStringFunc(text, func(m string) string {
+		return r.style(ansiBold, boldRe.FindStringSubmatch(m)[1])
+	})
+	text = underRe.ReplaceAllStringFunc(text, func(m string) string {
+		sub := underRe.FindStringSubmatch(m)
+		if identRe.MatchString(sub[2]) {
+			return m
+		}
+		return sub[1] + r.style(ansiBold, sub[2]) + sub[3]
+	})
+	text = italicRe.ReplaceAllStringFunc(text, func(m string) string {
+		return r.style(ansiItalic, italicRe.FindStringSubmatch(m)[1])
+	})
+	return strikeRe.ReplaceAllStringFunc(text, func(m string) string {
+		return r.style(ansiStrike, strikeRe.FindStringSubmatch(m)[1])
+	})
+}
+
+// wrap word-wraps text, starting the first line with first and the others with rest
+func (r *renderer) wrap(text, first, rest string) {
+	words := strings.Fields(text)
+	line := first
+	empty := true
+	for _, w := range words {
+		if !empty && r.width > 0 && Width(line)+1+Width(w) > r.width {
+			r.out = append(r.out, line)
+			line = rest
+			empty = true
+		}
+		if !empty {
+			line += " "
+		}
+		line += w
+		empty = false
+	}
+	if !empty {
+		r.out = append(r.out, line)
+	}
+}
+
+// Width returns the number of terminal columns text occupies, ignoring ANSI escapes
+func Width(text string) int {
+	return utf8.RuneCountInString(ansiRe.ReplaceAllString(text, ""))
+}
+
+// Wrap word-wraps plain text to width columns, keeping its line breaks; zero width does not wrap
+func Wrap(text string, width int) []string {
+	r := renderer{width: width}
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			r.out = append(r.out, "")
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		r.wrap(line, indent, indent)
+	}
+	return r.trim()
+}
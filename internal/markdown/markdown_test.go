@@ -0,0 +1,128 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderPlain(t *testing.T) {
+	src := "## Summary\r\n\r\nThis fixes the **parser** when `input` is\nempty. See [the docs](https://example.com/docs).\n\n" +
+		"- first item that is long enough to wrap\n  - nested\n1. numbered\n\n> quoted text\n\n```go\nfunc main() {}\n```\n\n---\n"
+	got := Render(src, Options{Width: 30})
+	want := strings.Join([]string{
+		"Summary",
+		"",
+		"This fixes the parser when",
+		"input is",
+		"empty. See the docs",
+		"(https://example.com/docs).",
+		"",
+		"• first item that is long",
+		"  enough to wrap",
+		"  • nested",
+		"1. numbered",
+		"",
+		"│ quoted text",
+		"",
+		"    func main() {}",
+		"",
+		strings.Repeat("─", 30),
+	}, "\n")
+	if got != want {
+		t.Errorf("unexpected rendering:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderKeepsCode(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{"Use `Vec<String>` here", "Use Vec<String> here"},
+		{"returns Option<T> now", "returns Option<T> now"},
+		{"call <b>this</b> <details><summary>More</summary>text</details>", "call this Moretext"},
+		{"the __init__ method and `__str__`", "the __init__ method and __str__"},
+		{"this is __very important__ now", "this is very important now"},
+		{"`**not bold**` but **bold**", "**not bold** but bold"},
+	}
+	for _, tt := range tests {
+		if got := Plain(tt.src); got != tt.want {
+			t.Errorf("Plain(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestRenderPullRequestTemplate(t *testing.T) {
+	src := `<!-- Thanks for contributing!
+Please fill in the sections below. -->
+## Changes
+Adds caching.<br>Faster now.
+
+## Checklist
+- [x] Tests
+- [ ] Docs
+
+![screenshot](https://example.com/a.png) <img src="b.png" alt="diagram" width="200">
+<details><summary>Logs</summary>
+
+~~~
+<!-- kept inside code -->
+~~~
+</details>
+
+| Case | Before | After |
+|------|-------:|-------|
+| cold | 120ms | 80ms |
+| warm | 9ms | *2ms* |`
+
+	want := strings.Join([]string{
+		"Changes",
+		"",
+		"Adds caching.",
+		"Faster now.",
+		"",
+		"Checklist",
+		"",
+		"☑ Tests",
+		"☐ Docs",
+		"",
+		"[image: screenshot] [image: diagram]",
+		"Logs",
+		"",
+		"    <!-- kept inside code -->",
+		"",
+		"Case  Before  After",
+		"────  ──────  ─────",
+		"cold  120ms   80ms",
+		"warm  9ms     2ms",
+	}, "\n")
+	if got := Plain(src); got != want {
+		t.Errorf("unexpected rendering:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderColor(t *testing.T) {
+	got := Render("# Title\nSome **bold**, *italic*, ~~gone~~ and `code`", Options{Color: true})
+	if !strings.Contains(got, ansiBold+ansiUnderline+"Title"+ansiReset) {
+		t.Errorf("heading not styled: %q", got)
+	}
+	for _, want := range []string{ansiBold + "bold", ansiItalic + "italic", ansiStrike + "gone", ansiCode + "code"} {
+		if !strings.Contains(got, want+ansiReset) {
+			t.Errorf("%q not styled in %q", want, got)
+		}
+	}
+	if strings.Contains(Plain("Some **bold**"), "\x1b") {
+		t.Error("plain mode must not contain escapes")
+	}
+}
+
+func TestWidth(t *testing.T) {
+	if w := Width(ansiBold + "héllo" + ansiReset); w != 5 {
+		t.Errorf("Width = %d, want 5", w)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	got := Wrap("one two three four\r\n\n  indented words here", 10)
+	want := []string{"one two", "three four", "", "  indented", "  words", "  here"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Wrap = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func render(t *testing.T, r *Registry) string {
+	t.Helper()
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	return b.String()
+}
+
+func TestCounterAndGauge(t *testing.T) {
+	r := NewRegistry()
+	polls := r.Counter("polls_total", "Polls by result.", "repo", "result")
+	polls.With("org/api", "200").Inc()
+	polls.With("org/api", "200").Add(2)
+	polls.With("org/api", "error").Inc()
+	polls.With("org/api", "error").Add(-5)
+	depth := r.Gauge("queue_depth", "Queued events.").With()
+	depth.Set(7)
+	depth.Add(-2)
+
+	want := `# HELP polls_total Polls by result.
+# TYPE polls_total counter
+polls_total{repo="org/api",result="200"} 3
+polls_total{repo="org/api",result="error"} 1
+# HELP queue_depth Queued events.
+# TYPE queue_depth gauge
+queue_depth 5
+`
+	if got := render(t, r); got != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry()
+	h := r.Histogram("latency_seconds", "Latency.", []float64{1, 0.1}, "sink").With("file")
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(3)
+
+	want := `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{sink="file",le="0.1"} 1
+latency_seconds_bucket{sink="file",le="1"} 2
+latency_seconds_bucket{sink="file",le="+Inf"} 3
+latency_seconds_sum{sink="file"} 3.55
+latency_seconds_count{sink="file"} 3
+`
+	if got := render(t, r); got != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestLabelEscaping(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("events_total", "Events.", "action").With("a\"b\\c\nd").Inc()
+	if got := render(t, r); !strings.Contains(got, `events_total{action="a\"b\\c\nd"} 1`) {
+		t.Errorf("label not escaped:\n%s", got)
+	}
+}
+
+func TestOnCollect(t *testing.T) {
+	r := NewRegistry()
+	g := r.Gauge("age_seconds", "Age.").With()
+	n := 0
+	r.OnCollect(func() {
+		n++
+		g.Set(float64(n))
+	})
+	render(t, r)
+	if got := render(t, r); !strings.Contains(got, "age_seconds 2\n") {
+		t.Errorf("hook not run before each scrape:\n%s", got)
+	}
+}
+
+func TestDuplicateRegistration(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("x_total", "X.")
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic on duplicate registration")
+		}
+	}()
+	r.Gauge("x_total", "X.")
+}
+
+func TestServeHTTP(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("up_total", "Up.").With().Inc()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "up_total 1\n") {
+		t.Errorf("unexpected body:\n%s", rec.Body.String())
+	}
+}
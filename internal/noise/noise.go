@@ -0,0 +1,218 @@
+// Package noise suppresses low-value events: bot activity, label churn,
+// CI bot comments edited in place and authors commenting on their own new pull requests.
+package noise
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/metrics"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// Suppression reasons
+const (
+	ReasonBot         = "bot"
+	ReasonLabelChurn  = "label_churn"
+	ReasonCommentEdit = "comment_edit"
+	ReasonSelfComment = "self_comment"
+)
+
+// DefaultSelfCommentWindow is how long after opening a pull request its author's comments are dropped
+const DefaultSelfCommentWindow = 60 * time.Second
+
+// DefaultCIActors are the CI bots whose comments are delivered once and whose edits are collapsed
+var DefaultCIActors = []string{"github-actions[bot]"}
+
+// maxTrackedComments bounds the comment IDs remembered for collapsing edits
+const maxTrackedComments = 10000
+
+// Config configures a Suppressor
+type Config struct {
+	// Actors are extra login patterns to drop, in path.Match syntax (e.g. "renovate*");
+	// logins ending in "[bot]" are always dropped
+	Actors []string
+	// CIActors are login patterns of CI bots that update a status comment in place: their new
+	// comments are delivered and later edits of them dropped. Nil uses DefaultCIActors.
+	CIActors []string
+	// SelfCommentWindow is how long after opening a pull request its author's comments are dropped;
+	// zero uses DefaultSelfCommentWindow and a negative value disables the check
+	SelfCommentWindow time.Duration
+	// Metrics records suppressed events when set
+	Metrics *Metrics
+}
+
+// Metrics records suppressed events in a metrics registry
+type Metrics struct {
+	suppressed *metrics.CounterVec
+}
+
+// NewMetrics registers the suppression metrics in reg
+func NewMetrics(reg *metrics.Registry) *Metrics {
+	return &Metrics{suppressed: reg.Counter("ghostio_suppressed_events_total", "Events dropped as noise by reason.", "reason")}
+}
+
+// Suppressor decides which events are noise and counts what it drops
+type Suppressor struct {
+	config Config
+
+	mu       sync.Mutex
+	counts   map[string]int
+	comments map[int]bool
+	order    []int
+	opened   map[string]openedPR
+}
+
+// openedPR remembers who opened a pull request and when
+type openedPR struct {
+	author string
+	at     time.Time
+}
+
+// New creates a suppressor
+func New(config Config) *Suppressor {
+	if config.SelfCommentWindow == 0 {
+		config.SelfCommentWindow = DefaultSelfCommentWindow
+	}
+	if config.CIActors == nil {
+		config.CIActors = DefaultCIActors
+	}
+	return &Suppressor{
+		config:   config,
+		counts:   make(map[string]int),
+		comments: make(map[int]bool),
+		opened:   make(map[string]openedPR),
+	}
+}
+
+// Allow reports whether the event should be delivered, counting it when it is suppressed
+func (s *Suppressor) Allow(e watcher.Event) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reason := s.classify(e)
+	if reason == "" {
+		return true
+	}
+	s.counts[reason]++
+	if s.config.Metrics != nil {
+		s.config.Metrics.suppressed.With(reason).Inc()
+	}
+	return false
+}
+
+// classify returns the reason the event is noise, or "" to keep it
+func (s *Suppressor) classify(e watcher.Event) string {
+	if c := e.Payload.Comment; c != nil && c.ID != 0 && matches(s.config.CIActors, e.Actor.Login) {
+		if e.Payload.Action == "edited" && s.comments[c.ID] {
+			return ReasonCommentEdit
+		}
+		s.trackComment(c.ID)
+		return ""
+	}
+	if s.isBot(e.Actor.Login) {
+		return ReasonBot
+	}
+	if isLabelChurn(e) {
+		return ReasonLabelChurn
+	}
+
+	key := fmt.Sprintf("%s#%d", e.Repo.Name, e.Number())
+	if e.Type == "PullRequestEvent" && e.Payload.Action == "opened" {
+		s.opened[key] = openedPR{author: e.Actor.Login, at: e.CreatedAt}
+	}
+
+	if e.Payload.Comment != nil && s.config.SelfCommentWindow > 0 {
+		s.expireOpened(e.CreatedAt)
+		if pr, ok := s.opened[key]; ok && pr.author == e.Actor.Login && e.CreatedAt.Sub(pr.at) <= s.config.SelfCommentWindow {
+			return ReasonSelfComment
+		}
+	}
+	return ""
+}
+
+func (s *Suppressor) isBot(login string) bool {
+	return strings.HasSuffix(login, "[bot]") || matches(s.config.Actors, login)
+}
+
+// matches reports whether login is or matches one of the patterns; comparing first keeps
+// logins such as "github-actions[bot]" from being read as a character class
+func matches(patterns []string, login string) bool {
+	for _, pattern := range patterns {
+		if pattern == login {
+			return true
+		}
+		if ok, _ := path.Match(pattern, login); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isLabelChurn reports issue and pull request edits that changed nothing, or only labels,
+// such as the edits GitHub records for label changes. Other changes, such as a pull
+// request's base branch, are real edits.
+func isLabelChurn(e watcher.Event) bool {
+	if e.Payload.Action != "edited" || e.Payload.Comment != nil {
+		return false
+	}
+	for field := range e.Payload.Changes {
+		if field != "labels" && field != "label" {
+			return false
+		}
+	}
+	return true
+}
+
+// trackComment remembers a comment ID, forgetting the oldest beyond maxTrackedComments
+func (s *Suppressor) trackComment(id int) {
+	if s.comments[id] {
+		return
+	}
+	s.comments[id] = true
+	s.order = append(s.order, id)
+	if len(s.order) > maxTrackedComments {
+		delete(s.comments, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+// expireOpened forgets pull requests opened longer ago than the self-comment window
+func (s *Suppressor) expireOpened(now time.Time) {
+	for key, pr := range s.opened {
+		if now.Sub(pr.at) > s.config.SelfCommentWindow {
+			delete(s.opened, key)
+		}
+	}
+}
+
+// Counts returns the number of suppressed events by reason
+func (s *Suppressor) Counts() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := make(map[string]int, len(s.counts))
+	for reason, n := range s.counts {
+		counts[reason] = n
+	}
+	return counts
+}
+
+// Summary describes the suppressed counts, e.g. "12 bot, 3 label_churn", or "" when nothing was suppressed
+func Summary(counts map[string]int) string {
+	reasons := make([]string, 0, len(counts))
+	for reason := range counts {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	parts := make([]string, 0, len(reasons))
+	for _, reason := range reasons {
+		if counts[reason] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[reason], reason))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,130 @@
+package noise
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/metrics"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+var baseTime = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func prOpened(number int, author string, at time.Time) watcher.Event {
+	return watcher.Event{
+		Type:      "PullRequestEvent",
+		Actor:     watcher.Actor{Login: author},
+		Repo:      watcher.Repo{Name: "org/api"},
+		Payload:   watcher.Payload{Action: "opened", PullRequest: &watcher.PullRequest{Number: number}},
+		CreatedAt: at,
+	}
+}
+
+func comment(number, id int, actor, action string, at time.Time) watcher.Event {
+	return watcher.Event{
+		Type:  "IssueCommentEvent",
+		Actor: watcher.Actor{Login: actor},
+		Repo:  watcher.Repo{Name: "org/api"},
+		Payload: watcher.Payload{
+			Action:  action,
+			Issue:   &watcher.Issue{Number: number},
+			Comment: &watcher.Comment{ID: id},
+		},
+		CreatedAt: at,
+	}
+}
+
+func issueEdited(changes string) watcher.Event {
+	e := watcher.Event{
+		Type:    "IssuesEvent",
+		Actor:   watcher.Actor{Login: "alice"},
+		Repo:    watcher.Repo{Name: "org/api"},
+		Payload: watcher.Payload{Action: "edited", Issue: &watcher.Issue{Number: 1}},
+	}
+	if changes != "" {
+		json.Unmarshal([]byte(changes), &e.Payload.Changes)
+	}
+	return e
+}
+
+func TestSuppressor(t *testing.T) {
+	s := New(Config{Actors: []string{"renovate*", "ci-runner"}})
+
+	tests := []struct {
+		name  string
+		event watcher.Event
+		want  bool
+	}{
+		{"bot suffix", comment(1, 1, "dependabot[bot]", "created", baseTime), false},
+		{"configured pattern", comment(1, 2, "renovate-bot", "created", baseTime), false},
+		{"configured login", comment(1, 3, "ci-runner", "created", baseTime), false},
+		{"human comment", comment(1, 4, "alice", "created", baseTime), true},
+		{"labeled", watcher.Event{Type: "IssuesEvent", Payload: watcher.Payload{Action: "labeled"}}, true},
+		{"edit without changes", issueEdited(""), false},
+		{"edit of labels only", issueEdited(`{"labels": {"from": []}}`), false},
+		{"edit of title", issueEdited(`{"title": {"from": "old"}}`), true},
+		{"base branch retarget", issueEdited(`{"base": {"ref": {"from": "main"}}}`), true},
+		{"human comment edited", comment(1, 4, "alice", "edited", baseTime.Add(time.Minute)), true},
+		{"CI comment", comment(1, 20, "github-actions[bot]", "created", baseTime), true},
+		{"CI comment edited in place", comment(1, 20, "github-actions[bot]", "edited", baseTime.Add(time.Minute)), false},
+		{"CI edit of unseen comment", comment(1, 21, "github-actions[bot]", "edited", baseTime), true},
+		{"pr opened", prOpened(7, "bob", baseTime), true},
+		{"author comment right after opening", comment(7, 10, "bob", "created", baseTime.Add(20*time.Second)), false},
+		{"reviewer comment right after opening", comment(7, 11, "carol", "created", baseTime.Add(30*time.Second)), true},
+		{"author comment later", comment(7, 12, "bob", "created", baseTime.Add(5*time.Minute)), true},
+	}
+	for _, tt := range tests {
+		if got := s.Allow(tt.event); got != tt.want {
+			t.Errorf("%s: Allow() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	counts := s.Counts()
+	want := map[string]int{ReasonBot: 3, ReasonLabelChurn: 2, ReasonCommentEdit: 1, ReasonSelfComment: 1}
+	for reason, n := range want {
+		if counts[reason] != n {
+			t.Errorf("Counts()[%s] = %d, want %d", reason, counts[reason], n)
+		}
+	}
+	if got := Summary(counts); got != "3 bot, 1 comment_edit, 2 label_churn, 1 self_comment" {
+		t.Errorf("Summary() = %q", got)
+	}
+}
+
+func TestCIActors(t *testing.T) {
+	s := New(Config{CIActors: []string{"buildkite*"}})
+	if !s.Allow(comment(1, 1, "buildkite-bot", "created", baseTime)) || s.Allow(comment(1, 1, "buildkite-bot", "edited", baseTime)) {
+		t.Error("expected the configured CI bot's comment to pass and its edit to be dropped")
+	}
+	if s.Allow(comment(1, 2, "github-actions[bot]", "created", baseTime)) {
+		t.Error("expected bots outside the configured CI actors to be dropped")
+	}
+}
+
+func TestSelfCommentWindowDisabled(t *testing.T) {
+	s := New(Config{SelfCommentWindow: -1})
+	s.Allow(prOpened(7, "bob", baseTime))
+	if !s.Allow(comment(7, 10, "bob", "created", baseTime.Add(time.Second))) {
+		t.Error("expected the author comment to pass with the window disabled")
+	}
+}
+
+func TestSuppressorMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	s := New(Config{Metrics: NewMetrics(reg)})
+	s.Allow(comment(1, 1, "dependabot[bot]", "created", baseTime))
+
+	var b strings.Builder
+	reg.WriteText(&b)
+	if !strings.Contains(b.String(), `ghostio_suppressed_events_total{reason="bot"} 1`) {
+		t.Errorf("missing suppression metric:\n%s", b.String())
+	}
+}
+
+func TestSummaryEmpty(t *testing.T) {
+	if got := Summary(map[string]int{}); got != "" {
+		t.Errorf("Summary() = %q, want empty", got)
+	}
+}
@@ -0,0 +1,295 @@
+// Package replay records the raw API responses seen while watching and plays them back.
+//
+// A recording is a file of watcher.Exchange values, one JSON object per line. Playing it
+// back serves each repository's event pages in the order and, scaled by a speed, at the
+// pace they were recorded, so pollers and everything after them behave as they did live.
+// Issue and pull request requests get the version of the resource recorded by then.
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// notModified is the response served once a recording has nothing newer
+const notModified = "HTTP/2.0 304 Not Modified\r\n\r\n"
+
+// Recorder writes the exchanges of a transport to a recording
+type Recorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	n   int
+	err error
+}
+
+// NewRecorder creates a recorder writing to w
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w)}
+}
+
+// Transport returns t with every exchange recorded
+func (r *Recorder) Transport(t watcher.Transport) watcher.Transport {
+	return func(path, etag string) watcher.Exchange {
+		x := t(path, etag)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if err := r.enc.Encode(x); err != nil {
+			if r.err == nil {
+				r.err = fmt.Errorf("failed to record %s: %w", path, err)
+			}
+		} else {
+			r.n++
+		}
+		return x
+	}
+}
+
+// Count returns the number of exchanges recorded
+func (r *Recorder) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.n
+}
+
+// Err returns the first write error
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Load reads a recording
+func Load(r io.Reader) ([]watcher.Exchange, error) {
+	var exchanges []watcher.Exchange
+	dec := json.NewDecoder(r)
+	for {
+		var x watcher.Exchange
+		err := dec.Decode(&x)
+		if errors.Is(err, io.EOF) {
+			return exchanges, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid recording after %d responses: %w", len(exchanges), err)
+		}
+		exchanges = append(exchanges, x)
+	}
+}
+
+// ParseSpeed parses a playback speed such as "10x", "0.5x" or "instant", which is 0
+func ParseSpeed(s string) (float64, error) {
+	if s == "instant" {
+		return 0, nil
+	}
+	v, err := strconv.ParseFloat(strings.TrimSuffix(s, "x"), 64)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid speed %q: want e.g. 1x, 10x or instant", s)
+	}
+	return v, nil
+}
+
+// isEvents reports whether path requests a repository's events
+func isEvents(path string) bool {
+	return strings.HasPrefix(path, "repos/") && strings.HasSuffix(path, "/events")
+}
+
+// queue holds the recorded event pages of one path
+type queue struct {
+	exchanges []watcher.Exchange
+	// seqs are the positions of the exchanges among all event pages
+	seqs []int
+	next int
+}
+
+// Player serves a recording as a watcher.Transport
+type Player struct {
+	ctx   context.Context
+	speed float64
+	start time.Time
+	// origin is when the recording starts
+	origin time.Time
+
+	queues map[string]*queue
+	// resources holds the full responses of other paths, oldest first
+	resources map[string][]watcher.Exchange
+	// times are the recorded times of the event pages by position
+	times []time.Time
+
+	mu sync.Mutex
+	// completed marks the event pages whose poller has asked for the next one, meaning
+	// their events have been handed on
+	completed []bool
+	// low is the first event page not completed; served pages precede it or are in progress
+	low int
+	// served counts the event pages served
+	served  int
+	changed chan struct{}
+	done    chan struct{}
+}
+
+// New creates a player for the exchanges at speed times the recorded pace, or as fast as
+// the pollers ask with speed 0. Requests blocked on the recording return when ctx is done.
+func New(ctx context.Context, exchanges []watcher.Exchange, speed float64) (*Player, error) {
+	exchanges = slices.Clone(exchanges)
+	slices.SortStableFunc(exchanges, func(a, b watcher.Exchange) int { return a.Time.Compare(b.Time) })
+
+	p := &Player{
+		ctx:       ctx,
+		speed:     speed,
+		queues:    make(map[string]*queue),
+		resources: make(map[string][]watcher.Exchange),
+		changed:   make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	for _, x := range exchanges {
+		if !isEvents(x.Path) {
+			if x.Error == "" && !x.NotModified() {
+				p.resources[x.Path] = append(p.resources[x.Path], x)
+			}
+			continue
+		}
+		q := p.queues[x.Path]
+		if q == nil {
+			q = &queue{}
+			p.queues[x.Path] = q
+		}
+		q.exchanges = append(q.exchanges, x)
+		q.seqs = append(q.seqs, len(p.times))
+		p.times = append(p.times, x.Time)
+	}
+	if len(p.times) == 0 {
+		return nil, errors.New("the recording has no event pages")
+	}
+	p.origin = p.times[0]
+	p.completed = make([]bool, len(p.times))
+	p.start = time.Now()
+	return p, nil
+}
+
+// Repos returns the recorded repositories
+func (p *Player) Repos() []string {
+	var repos []string
+	for path := range p.queues {
+		repos = append(repos, strings.TrimSuffix(strings.TrimPrefix(path, "repos/"), "/events"))
+	}
+	slices.Sort(repos)
+	return repos
+}
+
+// HasResources reports whether the recording holds issue or pull request responses
+func (p *Player) HasResources() bool {
+	return len(p.resources) > 0
+}
+
+// Done is closed once every event page has been served and handed on
+func (p *Player) Done() <-chan struct{} {
+	return p.done
+}
+
+// Transport serves a request from the recording. Each path's event pages are served in
+// turn, and a page only after every earlier page of any path has been handed on, so the
+// events come out in recorded order. Once a path has no more pages the request waits for
+// the player's context and answers 304 Not Modified.
+func (p *Player) Transport(path, etag string) watcher.Exchange {
+	p.mu.Lock()
+	q := p.queues[path]
+	if q == nil {
+		p.mu.Unlock()
+		return p.resource(path, etag)
+	}
+
+	// Asking again means the previous page has been handed on
+	if q.next > 0 {
+		p.complete(q.seqs[q.next-1])
+	}
+	if q.next == len(q.exchanges) {
+		p.mu.Unlock()
+		<-p.ctx.Done()
+		return watcher.Exchange{Time: time.Now().UTC(), Path: path, ETag: etag, Output: notModified}
+	}
+	seq := q.seqs[q.next]
+	for p.low < seq {
+		changed := p.changed
+		p.mu.Unlock()
+		select {
+		case <-changed:
+		case <-p.ctx.Done():
+			return watcher.Exchange{Time: time.Now().UTC(), Path: path, ETag: etag, Output: notModified}
+		}
+		p.mu.Lock()
+	}
+	x := q.exchanges[q.next]
+	p.mu.Unlock()
+
+	p.wait(x.Time)
+
+	p.mu.Lock()
+	q.next++
+	p.served = seq + 1
+	p.mu.Unlock()
+	return x
+}
+
+// complete marks an event page as handed on; the caller holds the lock
+func (p *Player) complete(seq int) {
+	if p.completed[seq] {
+		return
+	}
+	p.completed[seq] = true
+	for p.low < len(p.completed) && p.completed[p.low] {
+		p.low++
+	}
+	close(p.changed)
+	p.changed = make(chan struct{})
+	if p.low == len(p.completed) {
+		close(p.done)
+	}
+}
+
+// wait sleeps until the recorded time t is due at the player's speed
+func (p *Player) wait(t time.Time) {
+	if p.speed == 0 {
+		return
+	}
+	due := p.start.Add(time.Duration(float64(t.Sub(p.origin)) / p.speed))
+	timer := time.NewTimer(time.Until(due))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-p.ctx.Done():
+	}
+}
+
+// resource serves the latest response for path recorded before the next event page, or
+// the earliest one when all came later. A matching ETag is answered with 304.
+func (p *Player) resource(path, etag string) watcher.Exchange {
+	versions := p.resources[path]
+	if len(versions) == 0 {
+		return watcher.Exchange{Time: time.Now().UTC(), Path: path, ETag: etag, Error: "no recorded response for " + path}
+	}
+	p.mu.Lock()
+	served := p.served
+	p.mu.Unlock()
+
+	x := versions[0]
+	for _, v := range versions[1:] {
+		if served < len(p.times) && !v.Time.Before(p.times[served]) {
+			break
+		}
+		x = v
+	}
+	if etag != "" && x.Header("etag") == etag {
+		return watcher.Exchange{Time: x.Time, Path: path, ETag: etag, Output: notModified}
+	}
+	x.ETag = etag
+	return x
+}
@@ -0,0 +1,169 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+var origin = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+// page returns a recorded event page of repo holding ids, newest first
+func page(repo string, at time.Duration, etag string, ids ...string) watcher.Exchange {
+	var events []string
+	for _, id := range ids {
+		events = append(events, fmt.Sprintf(`{"id":%q,"type":"WatchEvent","repo":{"name":%q}}`, id, repo))
+	}
+	return watcher.Exchange{
+		Time:   origin.Add(at),
+		Path:   "repos/" + repo + "/events",
+		Output: fmt.Sprintf("HTTP/2.0 200 OK\r\nEtag: %s\r\n\r\n[%s]", etag, strings.Join(events, ",")),
+	}
+}
+
+// play runs a poller per recorded repository against the player and returns the
+// delivered event IDs once the recording is over
+func play(t *testing.T, exchanges []watcher.Exchange, speed float64) []string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	p, err := New(ctx, exchanges, speed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eventCh := make(chan watcher.Event, 100)
+	var wg sync.WaitGroup
+	for _, repo := range p.Repos() {
+		poller := watcher.NewPoller(watcher.PollerConfig{Repo: repo, Interval: time.Millisecond, EmitInitial: true, Transport: p.Transport})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			poller.Start(ctx, eventCh)
+		}()
+	}
+	select {
+	case <-p.Done():
+	case <-ctx.Done():
+		t.Fatal("the recording did not finish")
+	}
+	cancel()
+	wg.Wait()
+	close(eventCh)
+
+	var ids []string
+	for e := range eventCh {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func TestReplayOrder(t *testing.T) {
+	not := watcher.Exchange{Time: origin.Add(3 * time.Second), Path: "repos/org/web/events", ETag: `"b1"`, Output: notModified}
+	exchanges := []watcher.Exchange{
+		page("org/api", 2*time.Second, `"a2"`, "a3", "a2"),
+		not,
+		page("org/api", 0, `"a1"`, "a2", "a1"),
+		page("org/web", time.Second, `"b1"`, "b1"),
+	}
+	// Replays are the same every time
+	for range 20 {
+		if got := strings.Join(play(t, exchanges, 0), " "); got != "a1 a2 b1 a3" {
+			t.Fatalf("events = %q, want %q", got, "a1 a2 b1 a3")
+		}
+	}
+}
+
+func TestReplaySpeed(t *testing.T) {
+	exchanges := []watcher.Exchange{page("org/api", 0, `"1"`, "1"), page("org/api", 2*time.Second, `"2"`, "2", "1")}
+	start := time.Now()
+	if got := strings.Join(play(t, exchanges, 50), " "); got != "1 2" {
+		t.Errorf("events = %q", got)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("replay at 50x took %v, want at least 40ms", elapsed)
+	}
+}
+
+func TestResource(t *testing.T) {
+	pr := func(at time.Duration, etag, state string) watcher.Exchange {
+		return watcher.Exchange{
+			Time:   origin.Add(at),
+			Path:   "repos/org/api/pulls/1",
+			Output: fmt.Sprintf("HTTP/2.0 200 OK\r\nEtag: %s\r\n\r\n{\"state\":%q}", etag, state),
+		}
+	}
+	p, err := New(context.Background(), []watcher.Exchange{
+		page("org/api", 0, `"p1"`, "1"),
+		pr(time.Second, `"v1"`, "open"),
+		{Time: origin.Add(5 * time.Second), Path: "repos/org/api/pulls/1", ETag: `"v1"`, Output: notModified},
+		page("org/api", 10*time.Second, `"p2"`, "2", "1"),
+		pr(11*time.Second, `"v2"`, "closed"),
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.HasResources() {
+		t.Error("HasResources = false")
+	}
+
+	fetch := watcher.Transport(p.Transport).Fetch
+	p.Transport("repos/org/api/events", "")
+	resp, err := fetch("repos/org/api/pulls/1", "")
+	if err != nil || string(resp.Body) != `{"state":"open"}` || resp.ETag != `"v1"` {
+		t.Errorf("before the second page: %q %q %v", resp.Body, resp.ETag, err)
+	}
+	if resp, _ := fetch("repos/org/api/pulls/1", `"v1"`); !resp.NotModified {
+		t.Error("matching ETag was not answered with 304")
+	}
+	p.Transport("repos/org/api/events", `"p1"`)
+	if resp, _ := fetch("repos/org/api/pulls/1", `"v1"`); string(resp.Body) != `{"state":"closed"}` {
+		t.Errorf("after the second page: %q", resp.Body)
+	}
+	if _, err := fetch("repos/org/api/issues/9", ""); err == nil {
+		t.Error("unrecorded path did not fail")
+	}
+}
+
+func TestRecordAndLoad(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRecorder(&buf)
+	transport := r.Transport(func(path, etag string) watcher.Exchange {
+		return page("org/api", 0, `"1"`, "1")
+	})
+	transport("repos/org/api/events", "")
+	transport("repos/org/api/events", `"1"`)
+	if r.Count() != 2 || r.Err() != nil {
+		t.Fatalf("Count = %d, Err = %v", r.Count(), r.Err())
+	}
+
+	exchanges, err := Load(&buf)
+	if err != nil || len(exchanges) != 2 || exchanges[0].Header("etag") != `"1"` {
+		t.Fatalf("Load = %+v, %v", exchanges, err)
+	}
+	if _, err := Load(strings.NewReader(`{"path":`)); err == nil {
+		t.Error("truncated recording loaded")
+	}
+	if _, err := New(context.Background(), nil, 0); err == nil {
+		t.Error("empty recording accepted")
+	}
+}
+
+func TestParseSpeed(t *testing.T) {
+	for s, want := range map[string]float64{"10x": 10, "0.5x": 0.5, "2": 2, "instant": 0} {
+		if got, err := ParseSpeed(s); err != nil || got != want {
+			t.Errorf("ParseSpeed(%q) = %v, %v", s, got, err)
+		}
+	}
+	for _, s := range []string{"", "fast", "0x", "-1x"} {
+		if _, err := ParseSpeed(s); err == nil {
+			t.Errorf("ParseSpeed(%q) succeeded", s)
+		}
+	}
+}
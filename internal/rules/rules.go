@@ -0,0 +1,187 @@
+// Package rules evaluates alerting rules against events.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/ytnobody/ghostio/internal/config"
+	"github.com/ytnobody/ghostio/internal/filter"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// Severities in increasing order
+var Severities = []string{"info", "warning", "critical"}
+
+// Rule is a compiled alerting rule
+type Rule struct {
+	Name     string
+	Severity string
+	Actions  config.RuleActions
+
+	when *filter.Filter
+	text *regexp.Regexp
+}
+
+// Match is a rule matched by an event
+type Match struct {
+	watcher.Alert
+	Bell bool
+	Exit *int
+}
+
+// Engine evaluates a set of rules
+type Engine struct {
+	rules []*Rule
+}
+
+// Compile validates and compiles rule configurations
+func Compile(configs []config.RuleConfig) (*Engine, error) {
+	engine := &Engine{}
+	seen := map[string]bool{}
+	for i, c := range configs {
+		r, err := compile(c)
+		if err != nil {
+			name := c.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i+1)
+			}
+			return nil, fmt.Errorf("rule %s: %w", name, err)
+		}
+		if seen[r.Name] {
+			return nil, fmt.Errorf("rule %s: defined twice", r.Name)
+		}
+		seen[r.Name] = true
+		engine.rules = append(engine.rules, r)
+	}
+	return engine, nil
+}
+
+func compile(c config.RuleConfig) (*Rule, error) {
+	if c.Name == "" {
+		return nil, errors.New("missing name")
+	}
+	r := &Rule{Name: c.Name, Severity: c.Severity, Actions: c.Actions}
+	if r.Severity == "" {
+		r.Severity = "warning"
+	}
+	if !slices.Contains(Severities, r.Severity) {
+		return nil, fmt.Errorf("invalid severity %q (want %s)", r.Severity, strings.Join(Severities, ", "))
+	}
+
+	if c.When != "" {
+		when, err := filter.Parse(c.When)
+		if err != nil {
+			return nil, fmt.Errorf("when: %w", err)
+		}
+		r.when = when
+	}
+
+	var alternatives []string
+	for _, k := range c.Keywords {
+		if k = strings.TrimSpace(k); k != "" {
+			alternatives = append(alternatives, wordPattern(k))
+		}
+	}
+	for _, m := range c.Mentions {
+		m = strings.TrimPrefix(strings.TrimSpace(m), "@")
+		if m != "" {
+			alternatives = append(alternatives, `@`+regexp.QuoteMeta(m)+`\b`)
+		}
+	}
+	for _, p := range c.Patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+		alternatives = append(alternatives, "(?:"+p+")")
+	}
+	if len(alternatives) > 0 {
+		r.text = regexp.MustCompile("(?i)" + strings.Join(alternatives, "|"))
+	}
+
+	if r.when == nil && r.text == nil {
+		return nil, errors.New("needs a when condition, keywords, mentions or patterns")
+	}
+	return r, nil
+}
+
+// wordPattern matches a keyword on word boundaries where its ends are word characters
+func wordPattern(k string) string {
+	isWord := func(b byte) bool {
+		return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+	}
+	p := regexp.QuoteMeta(k)
+	if isWord(k[0]) {
+		p = `\b` + p
+	}
+	if isWord(k[len(k)-1]) {
+		p += `\b`
+	}
+	return p
+}
+
+// Rules returns the compiled rules in order
+func (e *Engine) Rules() []*Rule {
+	if e == nil {
+		return nil
+	}
+	return e.rules
+}
+
+// UsesDetails reports whether a rule's condition refers to fields that only enrichment provides
+func (e *Engine) UsesDetails() bool {
+	for _, r := range e.Rules() {
+		if r.when.UsesDetails() {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate returns the rules the event matches, in rule order
+func (e *Engine) Evaluate(ev watcher.Event) []Match {
+	if e == nil {
+		return nil
+	}
+	var matches []Match
+	for _, r := range e.rules {
+		fragments, ok := r.match(ev)
+		if !ok {
+			continue
+		}
+		matches = append(matches, Match{
+			Alert: watcher.Alert{
+				Rule:      r.Name,
+				Severity:  r.Severity,
+				Fragments: fragments,
+				Highlight: r.Actions.Highlight,
+				Sinks:     r.Actions.Sinks,
+			},
+			Bell: r.Actions.Bell,
+			Exit: r.Actions.Exit,
+		})
+	}
+	return matches
+}
+
+// match reports whether the rule matches and the distinct text fragments it found
+func (r *Rule) match(ev watcher.Event) ([]string, bool) {
+	if !r.when.Match(ev) {
+		return nil, false
+	}
+	if r.text == nil {
+		return nil, true
+	}
+	var fragments []string
+	for _, text := range []string{ev.Title(), ev.Body()} {
+		for _, m := range r.text.FindAllString(text, -1) {
+			if !slices.ContainsFunc(fragments, func(f string) bool { return strings.EqualFold(f, m) }) {
+				fragments = append(fragments, m)
+			}
+		}
+	}
+	return fragments, len(fragments) > 0
+}
@@ -0,0 +1,108 @@
+package rules
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ytnobody/ghostio/internal/config"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+func issue(title, body string) watcher.Event {
+	return watcher.Event{
+		Type:    "IssuesEvent",
+		Actor:   watcher.Actor{Login: "alice"},
+		Repo:    watcher.Repo{Name: "org/api"},
+		Payload: watcher.Payload{Action: "opened", Issue: &watcher.Issue{Number: 1, Title: title, Body: body}},
+	}
+}
+
+func comment(body string) watcher.Event {
+	e := issue("Login fails", "")
+	e.Type = "IssueCommentEvent"
+	e.Payload.Action = "created"
+	e.Payload.Comment = &watcher.Comment{ID: 1, Body: body}
+	return e
+}
+
+func ruleNames(matches []Match) []string {
+	var names []string
+	for _, m := range matches {
+		names = append(names, m.Rule)
+	}
+	return names
+}
+
+func TestEvaluate(t *testing.T) {
+	code := 3
+	engine, err := Compile([]config.RuleConfig{
+		{Name: "team", Mentions: []string{"@org/core"}, Actions: config.RuleActions{Highlight: true, Bell: true}},
+		{Name: "incident", Keywords: []string{"regression", "outage"}, Severity: "critical", Actions: config.RuleActions{Sinks: []string{"notify"}, Exit: &code}},
+		{Name: "security", Patterns: []string{`CVE-\d{4}-\d+`}, When: `type == "IssuesEvent"`},
+		{Name: "prs", When: `type == "PullRequestEvent"`},
+	})
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		event watcher.Event
+		rules []string
+	}{
+		{"mention in comment", comment("cc @org/core please look"), []string{"team"}},
+		{"keyword in title", issue("Outage in EU", ""), []string{"incident"}},
+		{"keyword needs a whole word", issue("Regressions dashboard", ""), nil},
+		{"pattern with condition", issue("Fix", "Tracks CVE-2024-1234"), []string{"security"}},
+		{"pattern condition fails", comment("Tracks CVE-2024-1234"), nil},
+		{"several rules", issue("Regression after fix", "@org/core CVE-2024-99"), []string{"team", "incident", "security"}},
+		{"condition only", watcher.Event{Type: "PullRequestEvent", Payload: watcher.Payload{PullRequest: &watcher.PullRequest{}}}, []string{"prs"}},
+	}
+	for _, tt := range tests {
+		if got := ruleNames(engine.Evaluate(tt.event)); !slices.Equal(got, tt.rules) {
+			t.Errorf("%s: matched %v, want %v", tt.name, got, tt.rules)
+		}
+	}
+
+	m := engine.Evaluate(issue("OUTAGE and outage again", "another Outage"))[0]
+	if m.Severity != "critical" || !slices.Equal(m.Fragments, []string{"OUTAGE"}) {
+		t.Errorf("unexpected match: %+v", m)
+	}
+	if m.Exit == nil || *m.Exit != 3 || !slices.Equal(m.Sinks, []string{"notify"}) {
+		t.Errorf("actions not carried over: %+v", m)
+	}
+	if m := engine.Evaluate(comment("@org/core"))[0]; m.Severity != "warning" || !m.Highlight || !m.Bell {
+		t.Errorf("unexpected defaults: %+v", m)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		rules []config.RuleConfig
+		want  string
+	}{
+		{[]config.RuleConfig{{Keywords: []string{"x"}}}, "rule #1: missing name"},
+		{[]config.RuleConfig{{Name: "a"}}, "rule a: needs a when condition"},
+		{[]config.RuleConfig{{Name: "a", Keywords: []string{"x"}, Severity: "high"}}, `invalid severity "high"`},
+		{[]config.RuleConfig{{Name: "a", Patterns: []string{"("}}}, "invalid pattern"},
+		{[]config.RuleConfig{{Name: "a", When: "titel == 'x'"}}, `rule a: when: invalid filter`},
+		{[]config.RuleConfig{{Name: "a", Keywords: []string{"x"}}, {Name: "a", Keywords: []string{"y"}}}, "rule a: defined twice"},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.rules)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Compile error = %v, want %q", err, tt.want)
+		}
+	}
+}
+
+func TestWordPattern(t *testing.T) {
+	engine, err := Compile([]config.RuleConfig{{Name: "lang", Keywords: []string{"C++", ".NET"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(engine.Evaluate(issue("Port to C++ and .NET", ""))) != 1 {
+		t.Error("expected keywords with punctuation at the edges to match")
+	}
+}
@@ -0,0 +1,74 @@
+package rules
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ytnobody/ghostio/internal/sink"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// Sink attaches rule matches to events before delivering them and runs the bell and exit actions
+type Sink struct {
+	next   sink.Sink
+	engine atomic.Pointer[Engine]
+	bell   io.Writer
+	onExit func(code int)
+
+	exitOnce sync.Once
+}
+
+// NewSink wraps next with the rules in engine. The bell is written to bell, and onExit
+// is called once with the exit code of the first matched rule that has one.
+func NewSink(next sink.Sink, engine *Engine, bell io.Writer, onExit func(code int)) *Sink {
+	s := &Sink{next: next, bell: bell, onExit: onExit}
+	s.engine.Store(engine)
+	return s
+}
+
+// SetEngine replaces the rules, e.g. after the configuration is reloaded
+func (s *Sink) SetEngine(engine *Engine) {
+	s.engine.Store(engine)
+}
+
+// Send evaluates the rules, delivers the event with its alerts and then runs the actions
+func (s *Sink) Send(e watcher.Event) error {
+	matches := s.engine.Load().Evaluate(e)
+	for _, m := range matches {
+		e.Alerts = append(e.Alerts, m.Alert)
+	}
+	err := s.next.Send(e)
+
+	for _, m := range matches {
+		if m.Bell && s.bell != nil {
+			s.bell.Write([]byte("\a"))
+			break
+		}
+	}
+	for _, m := range matches {
+		if m.Exit != nil && s.onExit != nil {
+			code := *m.Exit
+			s.exitOnce.Do(func() { s.onExit(code) })
+			break
+		}
+	}
+	return err
+}
+
+// Close closes the wrapped sink
+func (s *Sink) Close() error {
+	return s.next.Close()
+}
+
+// Routed reports whether an alert on the event routes it to the named sink
+func Routed(e watcher.Event, name string) bool {
+	for _, a := range e.Alerts {
+		for _, s := range a.Sinks {
+			if s == name {
+				return true
+			}
+		}
+	}
+	return false
+}
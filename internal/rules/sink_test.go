@@ -0,0 +1,52 @@
+package rules
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ytnobody/ghostio/internal/config"
+	"github.com/ytnobody/ghostio/internal/sink/sinktest"
+)
+
+func TestSink(t *testing.T) {
+	code := 2
+	engine, _ := Compile([]config.RuleConfig{
+		{Name: "incident", Keywords: []string{"outage"}, Actions: config.RuleActions{Bell: true, Exit: &code, Sinks: []string{"notify"}}},
+	})
+	next := &sinktest.Recorder{}
+	var bell bytes.Buffer
+	var exits []int
+	s := NewSink(next, engine, &bell, func(code int) { exits = append(exits, code) })
+
+	s.Send(issue("All good", ""))
+	s.Send(issue("Outage", ""))
+	s.Send(issue("Another outage", ""))
+
+	if len(next.Events()) != 3 {
+		t.Fatalf("expected every event to be delivered, got %d", len(next.Events()))
+	}
+	if len(next.Events()[0].Alerts) != 0 || len(next.Events()[1].Alerts) != 1 {
+		t.Errorf("unexpected alerts: %+v / %+v", next.Events()[0].Alerts, next.Events()[1].Alerts)
+	}
+	if !Routed(next.Events()[1], "notify") || Routed(next.Events()[1], "email") || Routed(next.Events()[0], "notify") {
+		t.Error("unexpected routing")
+	}
+	if bell.String() != "\a\a" {
+		t.Errorf("bell = %q, want two rings", bell.String())
+	}
+	if len(exits) != 1 || exits[0] != 2 {
+		t.Errorf("exits = %v, want a single exit with code 2", exits)
+	}
+
+	// Reloading replaces the rules
+	s.SetEngine(nil)
+	s.Send(issue("Outage", ""))
+	if len(next.Events()[3].Alerts) != 0 {
+		t.Error("expected no alerts after the rules were cleared")
+	}
+
+	s.Close()
+	if !next.Closed() {
+		t.Error("expected Close to reach the wrapped sink")
+	}
+}
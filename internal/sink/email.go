@@ -0,0 +1,354 @@
+package sink
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/cron"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// EmailConfig holds configuration for the SMTP digest sink
+type EmailConfig struct {
+	// Addr is the SMTP server as host:port
+	Addr     string
+	From     string
+	To       []string
+	Username string
+	Password string
+	// StartTLS fails delivery when the server does not offer STARTTLS
+	StartTLS  bool
+	TLSConfig *tls.Config
+	// Schedule triggers digests; nil only sends on SendNow
+	Schedule *cron.Schedule
+	Now      func() time.Time
+}
+
+// Email buffers events and sends them as a digest email on a schedule
+type Email struct {
+	config EmailConfig
+
+	mu     sync.Mutex
+	events []watcher.Event
+
+	sendMu  sync.Mutex
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// NewEmail creates an SMTP digest sink and starts its schedule
+func NewEmail(config EmailConfig) (*Email, error) {
+	if config.Addr == "" || config.From == "" || len(config.To) == 0 {
+		return nil, errors.New("email sink requires a server, a sender and at least one recipient")
+	}
+	if _, _, err := net.SplitHostPort(config.Addr); err != nil {
+		return nil, fmt.Errorf("invalid SMTP server %q: %w", config.Addr, err)
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	e := &Email{
+		config:  config,
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go e.run()
+	return e, nil
+}
+
+// Send adds the event to the pending digest
+func (s *Email) Send(e watcher.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, e)
+	return nil
+}
+
+// Pending returns the number of buffered events
+func (s *Email) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.events)
+}
+
+// SendNow sends the digest immediately and returns the number of events it contained
+func (s *Email) SendNow() (int, error) {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	s.mu.Lock()
+	events := s.events
+	s.events = nil
+	s.mu.Unlock()
+
+	if len(events) == 0 {
+		return 0, nil
+	}
+	if err := s.deliver(events); err != nil {
+		// Keep the events for the next attempt
+		s.mu.Lock()
+		s.events = append(events, s.events...)
+		s.mu.Unlock()
+		return 0, err
+	}
+	return len(events), nil
+}
+
+// Close stops the schedule and sends the events not yet sent, so none are lost on exit
+func (s *Email) Close() error {
+	close(s.stop)
+	<-s.stopped
+	n, err := s.SendNow()
+	if err != nil {
+		return fmt.Errorf("email digest failed, %d events not sent: %w", s.Pending(), err)
+	}
+	if n > 0 {
+		log.Printf("email digest sent with %d events", n)
+	}
+	return nil
+}
+
+func (s *Email) run() {
+	defer close(s.stopped)
+	if s.config.Schedule == nil {
+		<-s.stop
+		return
+	}
+	for {
+		now := s.config.Now()
+		next := s.config.Schedule.Next(now)
+		if next.IsZero() {
+			<-s.stop
+			return
+		}
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-s.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+			if n, err := s.SendNow(); err != nil {
+				log.Printf("email digest failed: %v", err)
+			} else if n > 0 {
+				log.Printf("email digest sent with %d events", n)
+			}
+		}
+	}
+}
+
+// deliver sends a digest of events over SMTP
+func (s *Email) deliver(events []watcher.Event) error {
+	msg, err := s.message(events)
+	if err != nil {
+		return err
+	}
+
+	host, _, _ := net.SplitHostPort(s.config.Addr)
+	conn, err := net.DialTimeout("tcp", s.config.Addr, 30*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", s.config.Addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(2 * time.Minute))
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp handshake failed: %w", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		tlsConfig := s.config.TLSConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{ServerName: host}
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("starttls failed: %w", err)
+		}
+	} else if s.config.StartTLS {
+		return fmt.Errorf("%s does not support STARTTLS", s.config.Addr)
+	}
+
+	if s.config.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.config.Username, s.config.Password, host)); err != nil {
+			return fmt.Errorf("smtp auth failed: %w", err)
+		}
+	}
+	if err := c.Mail(s.config.From); err != nil {
+		return fmt.Errorf("smtp MAIL failed: %w", err)
+	}
+	for _, to := range s.config.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("smtp RCPT %s failed: %w", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("smtp DATA failed: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	return c.Quit()
+}
+
+// digestThread is one issue or pull request conversation in a digest
+type digestThread struct {
+	Number int
+	Title  string
+	URL    string
+	Lines  []string
+}
+
+// digestRepo is one repository's section of a digest
+type digestRepo struct {
+	Name    string
+	Threads []*digestThread
+}
+
+// groupDigest groups events by repository, then by issue or pull request
+func groupDigest(events []watcher.Event) []*digestRepo {
+	sorted := make([]watcher.Event, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+
+	var repos []*digestRepo
+	repoIndex := map[string]*digestRepo{}
+	threadIndex := map[string]*digestThread{}
+	for _, e := range sorted {
+		repo, ok := repoIndex[e.Repo.Name]
+		if !ok {
+			repo = &digestRepo{Name: e.Repo.Name}
+			repoIndex[e.Repo.Name] = repo
+			repos = append(repos, repo)
+		}
+
+		key := fmt.Sprintf("%s#%d", e.Repo.Name, e.Number())
+		thread, ok := threadIndex[key]
+		if !ok {
+			thread = &digestThread{Number: e.Number(), Title: e.Title()}
+			if e.Number() != 0 {
+				if e.Payload.Issue != nil {
+					thread.URL = e.Payload.Issue.HTMLURL
+				} else {
+					thread.URL = e.Payload.PullRequest.HTMLURL
+				}
+			}
+			threadIndex[key] = thread
+			repo.Threads = append(repo.Threads, thread)
+		}
+		headline, _ := SplitEvent(e)
+		thread.Lines = append(thread.Lines, fmt.Sprintf("%s %s", e.CreatedAt.Format("01-02 15:04"), headline))
+	}
+
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Name < repos[j].Name })
+	for _, repo := range repos {
+		// Events without an issue or pull request go last
+		sort.SliceStable(repo.Threads, func(i, j int) bool {
+			return repo.Threads[i].Number != 0 && repo.Threads[j].Number == 0
+		})
+	}
+	return repos
+}
+
+var digestHTML = template.Must(template.New("digest").Parse(`<!DOCTYPE html>
+<html><body style="font-family: sans-serif">
+<h1>{{.Subject}}</h1>
+{{range .Repos}}<h2>{{if .Name}}{{.Name}}{{else}}(unknown repository){{end}}</h2>
+{{range .Threads}}<h3>{{if .Number}}<a href="{{.URL}}">#{{.Number}}</a> {{.Title}}{{else}}Other activity{{end}}</h3>
+<ul>
+{{range .Lines}}<li>{{.}}</li>
+{{end}}</ul>
+{{end}}{{end}}</body></html>
+`))
+
+// message builds a multipart/alternative digest email
+func (s *Email) message(events []watcher.Event) ([]byte, error) {
+	repos := groupDigest(events)
+	subject := fmt.Sprintf("ghostio digest: %d events (%s)", len(events), s.config.Now().Format("2006-01-02"))
+
+	var text strings.Builder
+	for _, repo := range repos {
+		name := repo.Name
+		if name == "" {
+			name = "(unknown repository)"
+		}
+		fmt.Fprintf(&text, "== %s ==\n\n", name)
+		for _, thread := range repo.Threads {
+			if thread.Number != 0 {
+				fmt.Fprintf(&text, "#%d %s\n%s\n", thread.Number, thread.Title, thread.URL)
+			} else {
+				text.WriteString("Other activity\n")
+			}
+			for _, line := range thread.Lines {
+				fmt.Fprintf(&text, "  - %s\n", line)
+			}
+			text.WriteString("\n")
+		}
+	}
+
+	var html bytes.Buffer
+	if err := digestHTML.Execute(&html, map[string]any{"Subject": subject, "Repos": repos}); err != nil {
+		return nil, fmt.Errorf("failed to render digest: %w", err)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", text.String()},
+		{"text/html; charset=utf-8", html.String()},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		qp.Write([]byte(part.content))
+		qp.Close()
+	}
+	mw.Close()
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(s.config.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", s.config.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <%s@ghostio>\r\n", randomID())
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+func randomID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,355 @@
+package sink
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// fakeSMTP is a minimal SMTP stand-in that records one delivered message
+type fakeSMTP struct {
+	ln        net.Listener
+	tlsConfig *tls.Config
+	auth      chan string
+	messages  chan string
+}
+
+func newFakeSMTP(t *testing.T, tlsConfig *tls.Config) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	s := &fakeSMTP{
+		ln:        ln,
+		tlsConfig: tlsConfig,
+		auth:      make(chan string, 1),
+		messages:  make(chan string, 1),
+	}
+	t.Cleanup(func() { ln.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeSMTP) serve() {
+	conn, err := s.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer func() { conn.Close() }()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+	reply("220 localhost ESMTP")
+
+	secure := false
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimSpace(line)
+		switch verb := strings.ToUpper(strings.SplitN(cmd, " ", 2)[0]); verb {
+		case "EHLO":
+			reply("250-localhost")
+			if s.tlsConfig != nil && !secure {
+				reply("250-STARTTLS")
+			}
+			reply("250 AUTH PLAIN")
+		case "STARTTLS":
+			reply("220 ready")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			r = bufio.NewReader(conn)
+			secure = true
+		case "AUTH":
+			s.auth <- cmd
+			reply("235 ok")
+		case "MAIL", "RCPT":
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			s.messages <- data.String()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 unknown")
+		}
+	}
+}
+
+func selfSignedTLS(t *testing.T) (*tls.Config, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}, pool
+}
+
+var digestEvents = []watcher.Event{
+	{
+		ID: "3", Type: "IssueCommentEvent", Actor: watcher.Actor{Login: "bob"}, Repo: watcher.Repo{Name: "org/api"},
+		Payload: watcher.Payload{
+			Action:  "created",
+			Issue:   &watcher.Issue{Number: 42, Title: "Bug in login", HTMLURL: "https://github.com/org/api/issues/42"},
+			Comment: &watcher.Comment{Body: "I can reproduce this"},
+		},
+		CreatedAt: time.Date(2026, 10, 19, 11, 0, 0, 0, time.UTC),
+	},
+	{
+		ID: "1", Type: "IssuesEvent", Actor: watcher.Actor{Login: "alice"}, Repo: watcher.Repo{Name: "org/api"},
+		Payload: watcher.Payload{
+			Action: "opened",
+			Issue:  &watcher.Issue{Number: 42, Title: "Bug in login", HTMLURL: "https://github.com/org/api/issues/42"},
+		},
+		CreatedAt: time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC),
+	},
+	{
+		ID: "2", Type: "ReleaseEvent", Actor: watcher.Actor{Login: "carol"}, Repo: watcher.Repo{Name: "org/api"},
+		Payload: watcher.Payload{
+			Action:  "published",
+			Release: &watcher.Release{TagName: "v1.0.0", HTMLURL: "https://github.com/org/api/releases/tag/v1.0.0"},
+		},
+		CreatedAt: time.Date(2026, 10, 19, 10, 30, 0, 0, time.UTC),
+	},
+	{
+		ID: "4", Type: "PullRequestEvent", Actor: watcher.Actor{Login: "dave"}, Repo: watcher.Repo{Name: "org/app"},
+		Payload: watcher.Payload{
+			Action:      "opened",
+			PullRequest: &watcher.PullRequest{Number: 7, Title: "Add <feature>", HTMLURL: "https://github.com/org/app/pull/7"},
+		},
+		CreatedAt: time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC),
+	},
+}
+
+func TestGroupDigest(t *testing.T) {
+	repos := groupDigest(digestEvents)
+	if len(repos) != 2 || repos[0].Name != "org/api" || repos[1].Name != "org/app" {
+		t.Fatalf("unexpected repos: %+v", repos)
+	}
+	api := repos[0]
+	if len(api.Threads) != 2 || api.Threads[0].Number != 42 || api.Threads[1].Number != 0 {
+		t.Fatalf("unexpected threads: %+v", api.Threads)
+	}
+	lines := api.Threads[0].Lines
+	if len(lines) != 2 || !strings.Contains(lines[0], "Issue opened") || !strings.Contains(lines[1], "Comment on #42") {
+		t.Errorf("expected chronological thread lines, got %v", lines)
+	}
+}
+
+func parseDigest(t *testing.T, raw string) (*mail.Message, map[string]string) {
+	t.Helper()
+	msg, err := mail.ReadMessage(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("invalid message: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("unexpected content type %q", msg.Header.Get("Content-Type"))
+	}
+	parts := map[string]string{}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid part: %v", err)
+		}
+		body, _ := io.ReadAll(p)
+		ct, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		parts[ct] = string(body)
+	}
+	return msg, parts
+}
+
+func TestEmailSendNow(t *testing.T) {
+	server := newFakeSMTP(t, nil)
+	clock := &fakeClock{now: time.Date(2026, 10, 19, 18, 0, 0, 0, time.UTC)}
+
+	s, err := NewEmail(EmailConfig{
+		Addr:     server.ln.Addr().String(),
+		From:     "ghostio@example.com",
+		To:       []string{"manager@example.com"},
+		Username: "user",
+		Password: "secret",
+		Now:      clock.Now,
+	})
+	if err != nil {
+		t.Fatalf("NewEmail() error: %v", err)
+	}
+	defer s.Close()
+
+	if n, err := s.SendNow(); n != 0 || err != nil {
+		t.Fatalf("SendNow() with empty buffer = %d, %v", n, err)
+	}
+
+	for _, e := range digestEvents {
+		s.Send(e)
+	}
+	n, err := s.SendNow()
+	if err != nil {
+		t.Fatalf("SendNow() error: %v", err)
+	}
+	if n != len(digestEvents) || s.Pending() != 0 {
+		t.Errorf("sent %d events, %d pending", n, s.Pending())
+	}
+
+	auth := <-server.auth
+	creds, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(auth, "AUTH PLAIN "))
+	if string(creds) != "\x00user\x00secret" {
+		t.Errorf("unexpected credentials %q", creds)
+	}
+
+	msg, parts := parseDigest(t, <-server.messages)
+	if subject := msg.Header.Get("Subject"); subject != "ghostio digest: 4 events (2026-10-19)" {
+		t.Errorf("Subject = %q", subject)
+	}
+	text := parts["text/plain"]
+	for _, want := range []string{"== org/api ==", "#42 Bug in login", "Other activity", "Release v1.0.0 published", "== org/app =="} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text part missing %q:\n%s", want, text)
+		}
+	}
+	if html := parts["text/html"]; !strings.Contains(html, `<a href="https://github.com/org/app/pull/7">#7</a> Add &lt;feature&gt;`) {
+		t.Errorf("html part missing escaped PR link:\n%s", html)
+	}
+}
+
+func TestEmailClose(t *testing.T) {
+	server := newFakeSMTP(t, nil)
+	s, err := NewEmail(EmailConfig{
+		Addr:     server.ln.Addr().String(),
+		From:     "ghostio@example.com",
+		To:       []string{"manager@example.com"},
+		Username: "user",
+		Password: "secret",
+		Now:      (&fakeClock{now: time.Date(2026, 10, 19, 18, 0, 0, 0, time.UTC)}).Now,
+	})
+	if err != nil {
+		t.Fatalf("NewEmail() error: %v", err)
+	}
+	for _, e := range digestEvents {
+		s.Send(e)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	<-server.auth
+	if msg, _ := parseDigest(t, <-server.messages); msg.Header.Get("Subject") != "ghostio digest: 4 events (2026-10-19)" {
+		t.Errorf("Subject = %q", msg.Header.Get("Subject"))
+	}
+}
+
+func TestEmailStartTLS(t *testing.T) {
+	serverTLS, pool := selfSignedTLS(t)
+	server := newFakeSMTP(t, serverTLS)
+
+	s, err := NewEmail(EmailConfig{
+		Addr:      server.ln.Addr().String(),
+		From:      "ghostio@example.com",
+		To:        []string{"manager@example.com"},
+		StartTLS:  true,
+		TLSConfig: &tls.Config{RootCAs: pool, ServerName: "127.0.0.1"},
+	})
+	if err != nil {
+		t.Fatalf("NewEmail() error: %v", err)
+	}
+	defer s.Close()
+
+	s.Send(digestEvents[0])
+	if _, err := s.SendNow(); err != nil {
+		t.Fatalf("SendNow() error: %v", err)
+	}
+	select {
+	case <-server.messages:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+}
+
+func TestEmailRequiresStartTLS(t *testing.T) {
+	server := newFakeSMTP(t, nil)
+	s, err := NewEmail(EmailConfig{
+		Addr:     server.ln.Addr().String(),
+		From:     "ghostio@example.com",
+		To:       []string{"manager@example.com"},
+		StartTLS: true,
+	})
+	if err != nil {
+		t.Fatalf("NewEmail() error: %v", err)
+	}
+
+	s.Send(digestEvents[0])
+	if _, err := s.SendNow(); err == nil {
+		t.Fatal("expected error when server lacks STARTTLS")
+	}
+	if s.Pending() != 1 {
+		t.Errorf("expected failed digest to stay buffered, %d pending", s.Pending())
+	}
+
+	// Close tries once more and reports what it could not send
+	server.ln.Close()
+	if err := s.Close(); err == nil || !strings.Contains(err.Error(), "1 events not sent") {
+		t.Errorf("Close() error = %v", err)
+	}
+}
+
+func TestNewEmailValidation(t *testing.T) {
+	if _, err := NewEmail(EmailConfig{Addr: "localhost:25", From: "a@example.com"}); err == nil {
+		t.Error("expected error without recipients")
+	}
+	if _, err := NewEmail(EmailConfig{Addr: "localhost", From: "a@example.com", To: []string{"b@example.com"}}); err == nil {
+		t.Error("expected error for server without port")
+	}
+}
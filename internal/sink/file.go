@@ -0,0 +1,439 @@
+package sink
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// dedupWindow is how many recent event IDs are remembered for deduplication
+const dedupWindow = 10000
+
+// FileConfig holds configuration for the file sink
+type FileConfig struct {
+	Dir    string
+	Prefix string
+	// Daily starts a new segment when the date changes
+	Daily bool
+	// MaxSize starts a new segment once the active one reaches this many bytes; zero disables it
+	MaxSize int64
+	// Keep is the number of rotated segments to retain; zero keeps all of them
+	Keep int
+	// Compress gzips rotated segments
+	Compress bool
+	// SyncInterval is how often written data is fsynced; zero syncs after every event
+	SyncInterval time.Duration
+	// Now returns the current time; used by tests
+	Now func() time.Time
+}
+
+// File appends events as NDJSON to rotated segment files
+type File struct {
+	config FileConfig
+
+	mu      sync.Mutex
+	file    *os.File
+	name    string
+	day     string
+	size    int64
+	dirty   bool
+	seen    map[string]bool
+	recent  []string
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// segment is a segment file on disk
+type segment struct {
+	name string
+	day  string
+	seq  int
+}
+
+// NewFile creates a file sink, repairing and resuming the newest segment
+func NewFile(config FileConfig) (*File, error) {
+	if config.Prefix == "" {
+		config.Prefix = "events"
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", config.Dir, err)
+	}
+
+	f := &File{
+		config: config,
+		seen:   make(map[string]bool),
+	}
+
+	segments, err := f.segments()
+	if err != nil {
+		return nil, err
+	}
+	for _, seg := range segments {
+		if err := f.loadIDs(seg.name); err != nil {
+			return nil, err
+		}
+	}
+	if err := f.resume(segments); err != nil {
+		return nil, err
+	}
+
+	if config.SyncInterval > 0 {
+		f.stop = make(chan struct{})
+		f.stopped = make(chan struct{})
+		go f.syncLoop()
+	}
+	return f, nil
+}
+
+// Send appends the event unless an event with the same ID was already written
+func (f *File) Send(e watcher.Event) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	line = append(line, '\n')
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if e.ID != "" && f.seen[e.ID] {
+		return nil
+	}
+	if err := f.rotateIfNeeded(int64(len(line))); err != nil {
+		return err
+	}
+
+	n, err := f.file.Write(line)
+	f.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", f.name, err)
+	}
+	f.remember(e.ID)
+
+	f.dirty = true
+	if f.config.SyncInterval == 0 {
+		return f.sync()
+	}
+	return nil
+}
+
+// Close syncs and closes the active segment
+func (f *File) Close() error {
+	if f.stop != nil {
+		close(f.stop)
+		<-f.stopped
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.sync()
+	if cerr := f.file.Close(); err == nil {
+		err = cerr
+	}
+	f.file = nil
+	return err
+}
+
+func (f *File) syncLoop() {
+	defer close(f.stopped)
+	ticker := time.NewTicker(f.config.SyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+			f.mu.Lock()
+			if err := f.sync(); err != nil {
+				log.Printf("file sink: %v", err)
+			}
+			f.mu.Unlock()
+		}
+	}
+}
+
+func (f *File) sync() error {
+	if !f.dirty || f.file == nil {
+		return nil
+	}
+	if err := f.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", f.name, err)
+	}
+	f.dirty = false
+	return nil
+}
+
+func (f *File) remember(id string) {
+	if id == "" || f.seen[id] {
+		return
+	}
+	f.seen[id] = true
+	f.recent = append(f.recent, id)
+	if len(f.recent) > dedupWindow {
+		delete(f.seen, f.recent[0])
+		f.recent = f.recent[1:]
+	}
+}
+
+// resume reopens the newest uncompressed segment, or starts a new one
+func (f *File) resume(segments []segment) error {
+	if len(segments) > 0 {
+		last := segments[len(segments)-1]
+		if strings.HasSuffix(last.name, ".ndjson") {
+			if err := repairTail(filepath.Join(f.config.Dir, last.name)); err != nil {
+				return err
+			}
+			return f.open(last)
+		}
+	}
+	return f.open(f.nextSegment(segments))
+}
+
+func (f *File) open(seg segment) error {
+	path := filepath.Join(f.config.Dir, seg.name)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	f.file = file
+	f.name = seg.name
+	f.day = seg.day
+	f.size = info.Size()
+	return nil
+}
+
+// rotateIfNeeded starts a new segment when the day changed or the size limit would be exceeded
+func (f *File) rotateIfNeeded(incoming int64) error {
+	if f.file == nil {
+		// A failed rotation left no segment open
+		segments, err := f.segments()
+		if err != nil {
+			return err
+		}
+		return f.open(f.nextSegment(segments))
+	}
+
+	today := f.config.Now().Format("2006-01-02")
+	dayChanged := f.config.Daily && today != f.day
+	full := f.config.MaxSize > 0 && f.size > 0 && f.size+incoming > f.config.MaxSize
+	if !dayChanged && !full {
+		return nil
+	}
+
+	if err := f.sync(); err != nil {
+		return err
+	}
+	err := f.file.Close()
+	f.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to close %s: %w", f.name, err)
+	}
+
+	// The next segment is opened first, so events are still written when compressing
+	// fails; the rotated segment is then kept uncompressed
+	rotated := filepath.Join(f.config.Dir, f.name)
+	segments, err := f.segments()
+	if err != nil {
+		return err
+	}
+	if err := f.open(f.nextSegment(segments)); err != nil {
+		return err
+	}
+	if f.config.Compress {
+		if err := compressFile(rotated); err != nil {
+			log.Printf("file sink: %v", err)
+		}
+	}
+	return f.prune()
+}
+
+// nextSegment names the segment that follows the existing ones
+func (f *File) nextSegment(segments []segment) segment {
+	today := f.config.Now().Format("2006-01-02")
+	seq := 0
+	for _, seg := range segments {
+		if seg.day == today && seg.seq >= seq {
+			seq = seg.seq + 1
+		}
+	}
+	name := fmt.Sprintf("%s-%s.ndjson", f.config.Prefix, today)
+	if seq > 0 {
+		name = fmt.Sprintf("%s-%s.%d.ndjson", f.config.Prefix, today, seq)
+	}
+	return segment{name: name, day: today, seq: seq}
+}
+
+// prune removes the oldest rotated segments beyond the Keep limit
+func (f *File) prune() error {
+	if f.config.Keep <= 0 {
+		return nil
+	}
+	segments, err := f.segments()
+	if err != nil {
+		return err
+	}
+	var rotated []segment
+	for _, seg := range segments {
+		if seg.name != f.name {
+			rotated = append(rotated, seg)
+		}
+	}
+	for len(rotated) > f.config.Keep {
+		if err := os.Remove(filepath.Join(f.config.Dir, rotated[0].name)); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", rotated[0].name, err)
+		}
+		rotated = rotated[1:]
+	}
+	return nil
+}
+
+// segments lists segment files, oldest first
+func (f *File) segments() ([]segment, error) {
+	entries, err := os.ReadDir(f.config.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", f.config.Dir, err)
+	}
+
+	var segments []segment
+	prefix := f.config.Prefix + "-"
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		base := strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ".ndjson")
+		if base == strings.TrimSuffix(name, ".gz") {
+			continue
+		}
+		rest := strings.TrimPrefix(base, prefix)
+		day, seqStr, _ := strings.Cut(rest, ".")
+		if _, err := time.Parse("2006-01-02", day); err != nil {
+			continue
+		}
+		seq := 0
+		if seqStr != "" {
+			if seq, err = strconv.Atoi(seqStr); err != nil {
+				continue
+			}
+		}
+		segments = append(segments, segment{name: name, day: day, seq: seq})
+	}
+
+	sort.Slice(segments, func(i, j int) bool {
+		if segments[i].day != segments[j].day {
+			return segments[i].day < segments[j].day
+		}
+		return segments[i].seq < segments[j].seq
+	})
+	return segments, nil
+}
+
+// loadIDs records the IDs of events already written to a segment
+func (f *File) loadIDs(name string) error {
+	path := filepath.Join(f.config.Dir, name)
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(name, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e struct {
+			ID string `json:"id"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &e) == nil {
+			f.remember(e.ID)
+		}
+	}
+	// A torn record at the end of a segment is expected after a crash
+	if err := scanner.Err(); err != nil && err != io.ErrUnexpectedEOF {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return nil
+}
+
+// repairTail truncates a partially written last line left behind by a crash
+func repairTail(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if len(data) == 0 || data[len(data)-1] == '\n' {
+		return nil
+	}
+	end := strings.LastIndexByte(string(data), '\n') + 1
+	if err := os.Truncate(path, int64(end)); err != nil {
+		return fmt.Errorf("failed to repair %s: %w", path, err)
+	}
+	return nil
+}
+
+// compressFile gzips path to path.gz and removes the original
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer src.Close()
+
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", tmp, err)
+	}
+	gz := gzip.NewWriter(dst)
+	gz.Name = filepath.Base(path)
+	_, err = io.Copy(gz, src)
+	if cerr := gz.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = dst.Sync()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to compress %s: %w", path, err)
+	}
+
+	if err := os.Rename(tmp, path+".gz"); err != nil {
+		return fmt.Errorf("failed to compress %s: %w", path, err)
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,220 @@
+package sink
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func readIDs(t *testing.T, path string) []string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	var scanner *bufio.Scanner
+	if filepath.Ext(path) == ".gz" {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatalf("failed to read gzip: %v", err)
+		}
+		scanner = bufio.NewScanner(gz)
+	} else {
+		scanner = bufio.NewScanner(file)
+	}
+
+	var ids []string
+	for scanner.Scan() {
+		var e watcher.Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("invalid NDJSON line %q: %v", scanner.Text(), err)
+		}
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func TestFileDailyRotation(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{now: time.Date(2026, 10, 19, 23, 0, 0, 0, time.UTC)}
+
+	f, err := NewFile(FileConfig{Dir: dir, Daily: true, Compress: true, Now: clock.Now})
+	if err != nil {
+		t.Fatalf("NewFile() error: %v", err)
+	}
+	f.Send(watcher.Event{ID: "1", Type: "IssuesEvent"})
+	f.Send(watcher.Event{ID: "2", Type: "IssuesEvent"})
+
+	clock.now = clock.now.Add(2 * time.Hour)
+	f.Send(watcher.Event{ID: "3", Type: "IssuesEvent"})
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	expected := []string{"events-2026-10-19.ndjson.gz", "events-2026-10-20.ndjson"}
+	names := listDir(t, dir)
+	if len(names) != 2 || names[0] != expected[0] || names[1] != expected[1] {
+		t.Fatalf("files = %v, want %v", names, expected)
+	}
+	if ids := readIDs(t, filepath.Join(dir, expected[0])); len(ids) != 2 || ids[0] != "1" || ids[1] != "2" {
+		t.Errorf("rotated segment ids = %v", ids)
+	}
+	if ids := readIDs(t, filepath.Join(dir, expected[1])); len(ids) != 1 || ids[0] != "3" {
+		t.Errorf("active segment ids = %v", ids)
+	}
+}
+
+func TestFileCompressFailure(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{now: time.Date(2026, 10, 19, 23, 0, 0, 0, time.UTC)}
+	f, err := NewFile(FileConfig{Dir: dir, Daily: true, Compress: true, Now: clock.Now})
+	if err != nil {
+		t.Fatalf("NewFile() error: %v", err)
+	}
+	defer f.Close()
+	f.Send(watcher.Event{ID: "1"})
+
+	// A directory in the way of the compressed file makes compressing fail
+	os.Mkdir(filepath.Join(dir, "events-2026-10-19.ndjson.gz.tmp"), 0o755)
+	clock.now = clock.now.Add(2 * time.Hour)
+	for _, id := range []string{"2", "3"} {
+		if err := f.Send(watcher.Event{ID: id}); err != nil {
+			t.Fatalf("Send(%s) error: %v", id, err)
+		}
+	}
+	if ids := readIDs(t, filepath.Join(dir, "events-2026-10-19.ndjson")); len(ids) != 1 || ids[0] != "1" {
+		t.Errorf("uncompressed segment ids = %v", ids)
+	}
+	if ids := readIDs(t, filepath.Join(dir, "events-2026-10-20.ndjson")); len(ids) != 2 {
+		t.Errorf("active segment ids = %v", ids)
+	}
+}
+
+func TestFileSizeRotationAndRetention(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{now: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)}
+
+	f, err := NewFile(FileConfig{Dir: dir, MaxSize: 1, Keep: 2, Compress: true, Now: clock.Now})
+	if err != nil {
+		t.Fatalf("NewFile() error: %v", err)
+	}
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		if err := f.Send(watcher.Event{ID: id}); err != nil {
+			t.Fatalf("Send() error: %v", err)
+		}
+	}
+	f.Close()
+
+	expected := []string{
+		"events-2026-10-19.2.ndjson.gz",
+		"events-2026-10-19.3.ndjson.gz",
+		"events-2026-10-19.4.ndjson",
+	}
+	names := listDir(t, dir)
+	if len(names) != len(expected) {
+		t.Fatalf("files = %v, want %v", names, expected)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("files = %v, want %v", names, expected)
+			break
+		}
+	}
+}
+
+func TestFileDedupOnRestart(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{now: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)}
+	config := FileConfig{Dir: dir, Now: clock.Now}
+
+	f, err := NewFile(config)
+	if err != nil {
+		t.Fatalf("NewFile() error: %v", err)
+	}
+	f.Send(watcher.Event{ID: "1"})
+	f.Send(watcher.Event{ID: "2"})
+	f.Close()
+
+	// Simulate a crash in the middle of writing a record
+	path := filepath.Join(dir, "events-2026-10-19.ndjson")
+	file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	file.WriteString(`{"id":"3","ty`)
+	file.Close()
+
+	f, err = NewFile(config)
+	if err != nil {
+		t.Fatalf("NewFile() after restart error: %v", err)
+	}
+	f.Send(watcher.Event{ID: "2"})
+	f.Send(watcher.Event{ID: "3"})
+	f.Send(watcher.Event{ID: "3"})
+	f.Close()
+
+	ids := readIDs(t, path)
+	expected := []string{"1", "2", "3"}
+	if len(ids) != len(expected) {
+		t.Fatalf("ids = %v, want %v", ids, expected)
+	}
+	for i := range expected {
+		if ids[i] != expected[i] {
+			t.Fatalf("ids = %v, want %v", ids, expected)
+		}
+	}
+}
+
+func TestFileSyncInterval(t *testing.T) {
+	dir := t.TempDir()
+	f, err := NewFile(FileConfig{Dir: dir, SyncInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewFile() error: %v", err)
+	}
+	f.Send(watcher.Event{ID: "1"})
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		f.mu.Lock()
+		dirty := f.dirty
+		f.mu.Unlock()
+		if !dirty {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected background sync to flush the segment")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("Close() error: %v", err)
+	}
+}
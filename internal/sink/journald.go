@@ -0,0 +1,71 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// DefaultJournalSocket is the native journald protocol socket
+const DefaultJournalSocket = "/run/systemd/journal/socket"
+
+// Journald writes events to the systemd journal using the native protocol
+type Journald struct {
+	conn       *net.UnixConn
+	identifier string
+}
+
+// NewJournald creates a journald sink; an empty socket uses DefaultJournalSocket
+func NewJournald(socket string) (*Journald, error) {
+	if socket == "" {
+		socket = DefaultJournalSocket
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to journald: %w", err)
+	}
+	return &Journald{conn: conn, identifier: "ghostio"}, nil
+}
+
+// Send writes the event as a journal entry with GHOSTIO_* fields
+func (j *Journald) Send(e watcher.Event) error {
+	if _, err := j.conn.Write(j.entry(e)); err != nil {
+		return fmt.Errorf("failed to write to journald: %w", err)
+	}
+	return nil
+}
+
+// Close closes the journal socket
+func (j *Journald) Close() error {
+	return j.conn.Close()
+}
+
+// entry serializes the event in the journal export format
+func (j *Journald) entry(e watcher.Event) []byte {
+	var b bytes.Buffer
+	writeJournalField(&b, "MESSAGE", watcher.FormatEvent(e))
+	writeJournalField(&b, "PRIORITY", strconv.Itoa(severity(e)))
+	writeJournalField(&b, "SYSLOG_IDENTIFIER", j.identifier)
+	for _, f := range eventFields(e) {
+		writeJournalField(&b, f.key, f.value)
+	}
+	return b.Bytes()
+}
+
+// writeJournalField writes KEY=value, or the length-prefixed form for multi-line values
+func writeJournalField(b *bytes.Buffer, key, value string) {
+	if !strings.Contains(value, "\n") {
+		fmt.Fprintf(b, "%s=%s\n", key, value)
+		return
+	}
+	b.WriteString(key)
+	b.WriteByte('\n')
+	binary.Write(b, binary.LittleEndian, uint64(len(value)))
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
@@ -0,0 +1,76 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJournald(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "journal.sock")
+	server, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	j, err := NewJournald(socket)
+	if err != nil {
+		t.Fatalf("NewJournald() error: %v", err)
+	}
+	defer j.Close()
+
+	if err := j.Send(syslogEvent); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+
+	buf := make([]byte, 8192)
+	server.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := server.Read(buf)
+	if err != nil {
+		t.Fatalf("failed to read datagram: %v", err)
+	}
+	entry := buf[:n]
+
+	for _, line := range []string{
+		"PRIORITY=5\n",
+		"SYSLOG_IDENTIFIER=ghostio\n",
+		"GHOSTIO_REPO=org/api\n",
+		"GHOSTIO_EVENT_TYPE=IssuesEvent\n",
+		"GHOSTIO_ACTOR=username\n",
+		"GHOSTIO_URL=https://github.com/org/api/issues/42\n",
+	} {
+		if !bytes.Contains(entry, []byte(line)) {
+			t.Errorf("expected entry to contain %q", line)
+		}
+	}
+
+	// MESSAGE is multi-line, so it uses the length-prefixed encoding
+	if !bytes.HasPrefix(entry, []byte("MESSAGE\n")) {
+		t.Fatalf("expected binary MESSAGE field, got %q", entry)
+	}
+	size := binary.LittleEndian.Uint64(entry[8:16])
+	message := string(entry[16 : 16+size])
+	if !strings.HasPrefix(message, "[2024-01-15 10:30:45] Issue opened: #42") || !strings.Contains(message, "with error 500") {
+		t.Errorf("unexpected MESSAGE %q", message)
+	}
+}
+
+func TestWriteJournalField(t *testing.T) {
+	var b bytes.Buffer
+	writeJournalField(&b, "KEY", "value")
+	if b.String() != "KEY=value\n" {
+		t.Errorf("got %q", b.String())
+	}
+
+	b.Reset()
+	writeJournalField(&b, "KEY", "a\nb")
+	want := "KEY\n\x03\x00\x00\x00\x00\x00\x00\x00a\nb\n"
+	if b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+}
@@ -0,0 +1,50 @@
+package sink
+
+import (
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/metrics"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// Metrics records sink deliveries in a metrics registry
+type Metrics struct {
+	deliveries *metrics.CounterVec
+	latency    *metrics.HistogramVec
+}
+
+// NewMetrics registers the sink metrics in reg
+func NewMetrics(reg *metrics.Registry) *Metrics {
+	return &Metrics{
+		deliveries: reg.Counter("ghostio_sink_deliveries_total", "Event deliveries to sinks by result.", "sink", "result"),
+		latency:    reg.Histogram("ghostio_sink_delivery_duration_seconds", "Latency of event deliveries to sinks.", nil, "sink"),
+	}
+}
+
+// instrumented wraps a sink to record its deliveries
+type instrumented struct {
+	Sink
+	name    string
+	metrics *Metrics
+}
+
+// Instrument returns s recording its deliveries under name; a nil m returns s unchanged
+func Instrument(name string, s Sink, m *Metrics) Sink {
+	if m == nil {
+		return s
+	}
+	return &instrumented{Sink: s, name: name, metrics: m}
+}
+
+// Send delivers the event and records the result and latency
+func (s *instrumented) Send(e watcher.Event) error {
+	start := time.Now()
+	err := s.Sink.Send(e)
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	s.metrics.deliveries.With(s.name, result).Inc()
+	s.metrics.latency.With(s.name).Observe(time.Since(start).Seconds())
+	return err
+}
@@ -0,0 +1,44 @@
+package sink
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ytnobody/ghostio/internal/metrics"
+	"github.com/ytnobody/ghostio/internal/sink/sinktest"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+func TestInstrument(t *testing.T) {
+	reg := metrics.NewRegistry()
+	m := NewMetrics(reg)
+
+	rec := &sinktest.Recorder{}
+	ok := Instrument("file", rec, m)
+	bad := Instrument("syslog", &sinktest.Recorder{Err: errors.New("unreachable")}, m)
+	ok.Send(watcher.Event{ID: "1"})
+	ok.Send(watcher.Event{ID: "2"})
+	if err := bad.Send(watcher.Event{ID: "3"}); err == nil {
+		t.Error("expected the sink error to be returned")
+	}
+	if len(rec.Events()) != 2 {
+		t.Errorf("expected 2 delivered events, got %d", len(rec.Events()))
+	}
+
+	var b strings.Builder
+	reg.WriteText(&b)
+	for _, want := range []string{
+		`ghostio_sink_deliveries_total{sink="file",result="success"} 2`,
+		`ghostio_sink_deliveries_total{sink="syslog",result="failure"} 1`,
+		`ghostio_sink_delivery_duration_seconds_count{sink="file"} 2`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, b.String())
+		}
+	}
+
+	if Instrument("file", rec, nil) != Sink(rec) {
+		t.Error("expected nil metrics to return the sink unchanged")
+	}
+}
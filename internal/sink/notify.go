@@ -0,0 +1,212 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ytnobody/ghostio/internal/dbus"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+const (
+	notifyDest  = "org.freedesktop.Notifications"
+	notifyPath  = dbus.ObjectPath("/org/freedesktop/Notifications")
+	notifyIface = "org.freedesktop.Notifications"
+)
+
+// Urgency levels defined by the desktop notification spec
+const (
+	UrgencyLow      byte = 0
+	UrgencyNormal   byte = 1
+	UrgencyCritical byte = 2
+)
+
+// DefaultBodyLimit is the default maximum length of a notification body
+const DefaultBodyLimit = 200
+
+// NotifyConfig holds configuration for the desktop notification sink
+type NotifyConfig struct {
+	// Address is the bus address; the session bus is used when empty
+	Address   string
+	AppName   string
+	BodyLimit int
+	// Expire is how long notifications stay visible; zero uses the server default
+	Expire time.Duration
+	// Open is called with the event URL when the default action is invoked
+	Open func(url string) error
+}
+
+// Notifier sends events as desktop notifications over D-Bus
+type Notifier struct {
+	config NotifyConfig
+	conn   *dbus.Conn
+
+	mu   sync.Mutex
+	urls map[uint32]string
+	done chan struct{}
+}
+
+// NewNotifier connects to the bus and creates a notification sink
+func NewNotifier(ctx context.Context, config NotifyConfig) (*Notifier, error) {
+	if config.AppName == "" {
+		config.AppName = "ghostio"
+	}
+	if config.BodyLimit == 0 {
+		config.BodyLimit = DefaultBodyLimit
+	}
+	if config.Open == nil {
+		config.Open = openURL
+	}
+	if config.Address == "" {
+		addr, err := dbus.SessionBusAddress()
+		if err != nil {
+			return nil, err
+		}
+		config.Address = addr
+	}
+
+	conn, err := dbus.Dial(ctx, config.Address)
+	if err != nil {
+		return nil, err
+	}
+	for _, member := range []string{"ActionInvoked", "NotificationClosed"} {
+		rule := fmt.Sprintf("type='signal',interface='%s',member='%s'", notifyIface, member)
+		if err := conn.AddMatch(ctx, rule); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to subscribe to %s: %w", member, err)
+		}
+	}
+
+	n := &Notifier{
+		config: config,
+		conn:   conn,
+		urls:   make(map[uint32]string),
+		done:   make(chan struct{}),
+	}
+	go n.handleSignals()
+	return n, nil
+}
+
+// Send shows the event as a desktop notification
+func (n *Notifier) Send(e watcher.Event) error {
+	summary, body := notificationText(e, n.config.BodyLimit)
+	url := e.HTMLURL()
+
+	actions := []string{}
+	if url != "" {
+		actions = append(actions, "default", "Open")
+	}
+	hints := map[string]dbus.Variant{
+		"urgency": {Sig: "y", Value: Urgency(e)},
+	}
+	expire := int32(-1)
+	if n.config.Expire > 0 {
+		expire = int32(n.config.Expire / time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reply, err := n.conn.Call(ctx, notifyDest, notifyPath, notifyIface, "Notify", "susssasa{sv}i",
+		n.config.AppName, uint32(0), "", summary, body, actions, hints, expire)
+	if err != nil {
+		return fmt.Errorf("notify failed: %w", err)
+	}
+
+	if id, ok := firstUint32(reply); ok && url != "" {
+		n.mu.Lock()
+		n.urls[id] = url
+		n.mu.Unlock()
+	}
+	return nil
+}
+
+// Close disconnects from the bus
+func (n *Notifier) Close() error {
+	err := n.conn.Close()
+	<-n.done
+	return err
+}
+
+// handleSignals opens URLs for invoked default actions
+func (n *Notifier) handleSignals() {
+	defer close(n.done)
+	for sig := range n.conn.Signals() {
+		if sig.Interface != notifyIface {
+			continue
+		}
+		id, ok := firstUint32(sig.Body)
+		if !ok {
+			continue
+		}
+
+		n.mu.Lock()
+		url := n.urls[id]
+		if sig.Member == "NotificationClosed" {
+			delete(n.urls, id)
+		}
+		n.mu.Unlock()
+
+		if sig.Member != "ActionInvoked" || len(sig.Body) < 2 || url == "" {
+			continue
+		}
+		if key, _ := sig.Body[1].(string); key == "default" {
+			if err := n.config.Open(url); err != nil {
+				log.Printf("notify: failed to open %s: %v", url, err)
+			}
+		}
+	}
+}
+
+// Urgency returns the notification urgency for an event
+func Urgency(e watcher.Event) byte {
+	for _, a := range e.Alerts {
+		if a.Severity == "critical" {
+			return UrgencyCritical
+		}
+	}
+	switch {
+	case e.Payload.Action == "review_requested":
+		return UrgencyCritical
+	case e.Type == "IssueCommentEvent" || e.Type == "PullRequestReviewCommentEvent":
+		return UrgencyLow
+	}
+	return UrgencyNormal
+}
+
+// notificationText splits a formatted event into a headline and a truncated description
+func notificationText(e watcher.Event, limit int) (string, string) {
+	headline, rest := SplitEvent(e)
+
+	// The URL is already reachable through the default action
+	if strings.HasPrefix(rest, "https://") || strings.HasPrefix(rest, "http://") {
+		_, rest, _ = strings.Cut(rest, "\n")
+	}
+	return headline, truncate(strings.TrimSpace(rest), limit)
+}
+
+// truncate shortens s to at most limit runes, marking the cut with an ellipsis
+func truncate(s string, limit int) string {
+	if limit <= 0 || utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:limit-1])) + "…"
+}
+
+func firstUint32(values []any) (uint32, bool) {
+	if len(values) == 0 {
+		return 0, false
+	}
+	id, ok := values[0].(uint32)
+	return id, ok
+}
+
+func openURL(url string) error {
+	return exec.Command("xdg-open", url).Start()
+}
@@ -0,0 +1,221 @@
+package sink
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/dbus"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// fakeBus is a private bus that also plays the notification server
+type fakeBus struct {
+	t     *testing.T
+	ln    net.Listener
+	addr  string
+	calls chan *dbus.Message
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func newFakeBus(t *testing.T) *fakeBus {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "bus")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	b := &fakeBus{
+		t:     t,
+		ln:    ln,
+		addr:  "unix:path=" + path,
+		calls: make(chan *dbus.Message, 16),
+	}
+	t.Cleanup(func() { ln.Close() })
+	go b.serve()
+	return b
+}
+
+func (b *fakeBus) serve() {
+	conn, err := b.ln.Accept()
+	if err != nil {
+		return
+	}
+	b.mu.Lock()
+	b.conn = conn
+	b.mu.Unlock()
+
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "\x00AUTH EXTERNAL ") {
+		conn.Close()
+		return
+	}
+	conn.Write([]byte("OK 0123456789abcdef\r\n"))
+	if line, err = r.ReadString('\n'); err != nil || line != "BEGIN\r\n" {
+		conn.Close()
+		return
+	}
+
+	var nextID uint32
+	for {
+		msg, err := dbus.ReadMessage(r)
+		if err != nil {
+			return
+		}
+		reply := &dbus.Message{
+			Type:        dbus.TypeMethodReturn,
+			Serial:      msg.Serial + 1000,
+			ReplySerial: msg.Serial,
+		}
+		switch msg.Member {
+		case "Hello":
+			reply.Signature = "s"
+			reply.Body = []any{":1.1"}
+		case "Notify":
+			nextID++
+			reply.Signature = "u"
+			reply.Body = []any{nextID}
+			b.calls <- msg
+		}
+		b.write(reply)
+	}
+}
+
+func (b *fakeBus) write(msg *dbus.Message) {
+	data, err := msg.Marshal()
+	if err != nil {
+		b.t.Errorf("failed to marshal: %v", err)
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.conn.Write(data)
+}
+
+func (b *fakeBus) emit(member, sig string, body ...any) {
+	b.write(&dbus.Message{
+		Type:      dbus.TypeSignal,
+		Serial:    9999,
+		Path:      notifyPath,
+		Interface: notifyIface,
+		Member:    member,
+		Signature: sig,
+		Body:      body,
+	})
+}
+
+func TestNotifier(t *testing.T) {
+	bus := newFakeBus(t)
+	opened := make(chan string, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	n, err := NewNotifier(ctx, NotifyConfig{
+		Address: bus.addr,
+		Open: func(url string) error {
+			opened <- url
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewNotifier() error: %v", err)
+	}
+	defer n.Close()
+
+	event := watcher.Event{
+		Type:  "PullRequestEvent",
+		Actor: watcher.Actor{Login: "developer"},
+		Payload: watcher.Payload{
+			Action: "review_requested",
+			PullRequest: &watcher.PullRequest{
+				Number:  99,
+				Title:   "Add feature",
+				Body:    strings.Repeat("x", 300),
+				HTMLURL: "https://github.com/owner/repo/pull/99",
+			},
+		},
+	}
+	if err := n.Send(event); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+
+	var call *dbus.Message
+	select {
+	case call = <-bus.calls:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for Notify call")
+	}
+
+	if call.Signature != "susssasa{sv}i" {
+		t.Fatalf("unexpected signature %q", call.Signature)
+	}
+	if summary := call.Body[3].(string); summary != `PR review_requested: #99 "Add feature" by @developer` {
+		t.Errorf("summary = %q", summary)
+	}
+	if body := call.Body[4].(string); len([]rune(body)) != DefaultBodyLimit || !strings.HasSuffix(body, "…") {
+		t.Errorf("expected body truncated to %d runes, got %q", DefaultBodyLimit, body)
+	}
+	if actions := call.Body[5].([]string); len(actions) != 2 || actions[0] != "default" {
+		t.Errorf("actions = %v, want default action", actions)
+	}
+	hints := call.Body[6].(map[string]any)
+	if urgency := hints["urgency"].(dbus.Variant).Value; urgency != UrgencyCritical {
+		t.Errorf("urgency = %v, want critical", urgency)
+	}
+
+	bus.emit("ActionInvoked", "us", uint32(1), "default")
+	select {
+	case url := <-opened:
+		if url != "https://github.com/owner/repo/pull/99" {
+			t.Errorf("opened %q", url)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for default action")
+	}
+}
+
+func TestUrgency(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    watcher.Event
+		expected byte
+	}{
+		{
+			name:     "review requested",
+			event:    watcher.Event{Type: "PullRequestEvent", Payload: watcher.Payload{Action: "review_requested"}},
+			expected: UrgencyCritical,
+		},
+		{
+			name:     "comment",
+			event:    watcher.Event{Type: "IssueCommentEvent", Payload: watcher.Payload{Action: "created"}},
+			expected: UrgencyLow,
+		},
+		{
+			name:     "issue opened",
+			event:    watcher.Event{Type: "IssuesEvent", Payload: watcher.Payload{Action: "opened"}},
+			expected: UrgencyNormal,
+		},
+		{
+			name: "critical alert on a comment",
+			event: watcher.Event{Type: "IssueCommentEvent", Payload: watcher.Payload{Action: "created"},
+				Alerts: []watcher.Alert{{Rule: "outage", Severity: "critical"}}},
+			expected: UrgencyCritical,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Urgency(tt.event); got != tt.expected {
+				t.Errorf("Urgency() = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ytnobody/ghostio/internal/term"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// Sink delivers events to an output destination
+type Sink interface {
+	Send(e watcher.Event) error
+	Close() error
+}
+
+// Writer writes formatted events to an io.Writer
+type Writer struct {
+	w     io.Writer
+	color bool
+	// BodyLines truncates bodies in terminal output to this many lines; zero shows them in full
+	BodyLines int
+}
+
+// NewWriter creates a Sink that prints events to w. On a terminal it uses colors, wraps to
+// the terminal width and truncates long bodies; otherwise it prints the plain format.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w, color: isTerminal(w), BodyLines: watcher.DefaultBodyLines}
+}
+
+// isTerminal reports whether w is a terminal that should get ANSI escapes; NO_COLOR turns them off
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return term.IsTerminal(f.Fd())
+}
+
+// terminalWidth returns the width of the terminal w writes to, or zero when unknown
+func terminalWidth(w io.Writer) int {
+	if f, ok := w.(*os.File); ok {
+		if width, _, err := term.Size(f.Fd()); err == nil {
+			return width
+		}
+	}
+	return 0
+}
+
+// Send prints the event followed by a separator; alerted events get a header line and highlighted matches
+func (s *Writer) Send(e watcher.Event) error {
+	_, err := fmt.Fprintf(s.w, "%s\n---\n", s.render(e, terminalWidth(s.w)))
+	return err
+}
+
+// render formats the event for width columns with its alert header and, in color, highlighted matches
+func (s *Writer) render(e watcher.Event, width int) string {
+	text := watcher.FormatEvent(e)
+	if s.color {
+		text = watcher.FormatTTY(e, watcher.TTYOptions{Width: width, BodyLines: s.BodyLines, Highlight: highlightFragments(e.Alerts)})
+	}
+	if len(e.Alerts) > 0 {
+		text = alertHeader(e.Alerts, s.color) + "\n" + text
+	}
+	return text
+}
+
+// Close does nothing; the underlying writer is owned by the caller
+func (s *Writer) Close() error {
+	return nil
+}
+
+// Multi fans events out to several sinks
+type Multi []Sink
+
+// Send delivers the event to every sink, collecting their errors
+func (m Multi) Send(e watcher.Event) error {
+	var errs []error
+	for _, s := range m {
+		if err := s.Send(e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every sink, collecting their errors
+func (m Multi) Close() error {
+	var errs []error
+	for _, s := range m {
+		if err := s.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ANSI escapes used for alerts and headers
+const (
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+	ansiAlert = "\x1b[1;31m"
+)
+
+// alertHeader names the matched rules, e.g. "[ALERT critical] outage, security"
+func alertHeader(alerts []watcher.Alert, color bool) string {
+	severity := alerts[0].Severity
+	names := make([]string, len(alerts))
+	for i, a := range alerts {
+		names[i] = a.Rule
+		if severityRank(a.Severity) > severityRank(severity) {
+			severity = a.Severity
+		}
+	}
+	header := fmt.Sprintf("[ALERT %s] %s", severity, strings.Join(names, ", "))
+	if color {
+		return ansiAlert + header + ansiReset
+	}
+	return header
+}
+
+func severityRank(severity string) int {
+	switch severity {
+	case "critical":
+		return 2
+	case "warning":
+		return 1
+	}
+	return 0
+}
+
+// highlightFragments collects the fragments of alerts that ask for highlighting
+func highlightFragments(alerts []watcher.Alert) []string {
+	var fragments []string
+	for _, a := range alerts {
+		if a.Highlight {
+			fragments = append(fragments, a.Fragments...)
+		}
+	}
+	return fragments
+}
+
+// SplitEvent splits a formatted event into its headline, without the timestamp, and the remaining text
+func SplitEvent(e watcher.Event) (string, string) {
+	headline, rest, _ := strings.Cut(watcher.FormatEvent(e), "\n")
+	if _, after, ok := strings.Cut(headline, "] "); ok && strings.HasPrefix(headline, "[") {
+		headline = after
+	}
+	return headline, rest
+}
+
+// filtered passes only matching events to a sink
+type filtered struct {
+	Sink
+	match func(watcher.Event) bool
+}
+
+// Filtered returns a sink that delivers only the events match accepts
+func Filtered(s Sink, match func(watcher.Event) bool) Sink {
+	return &filtered{Sink: s, match: match}
+}
+
+// Send delivers the event if it matches
+func (s *filtered) Send(e watcher.Event) error {
+	if !s.match(e) {
+		return nil
+	}
+	return s.Sink.Send(e)
+}
@@ -0,0 +1,100 @@
+package sink
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/sink/sinktest"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewWriter(&buf)
+
+	event := watcher.Event{
+		Type:      "WatchEvent",
+		Actor:     watcher.Actor{Login: "watcher"},
+		CreatedAt: time.Date(2024, 1, 15, 10, 30, 45, 0, time.UTC),
+	}
+	if err := s.Send(event); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+
+	expected := "[2024-01-15 10:30:45] WatchEvent by @watcher\n---\n"
+	if buf.String() != expected {
+		t.Errorf("output = %q, want %q", buf.String(), expected)
+	}
+}
+
+func TestMulti(t *testing.T) {
+	failing := &sinktest.Recorder{Err: errors.New("boom")}
+	ok := &sinktest.Recorder{}
+	m := Multi{failing, ok}
+
+	err := m.Send(watcher.Event{ID: "1"})
+	if err == nil {
+		t.Error("expected error from failing sink")
+	}
+	if len(ok.Events()) != 1 {
+		t.Errorf("expected healthy sink to receive the event, got %d events", len(ok.Events()))
+	}
+
+	if err := m.Close(); err != nil {
+		t.Errorf("Close() error: %v", err)
+	}
+	if !failing.Closed() || !ok.Closed() {
+		t.Error("expected all sinks to be closed")
+	}
+}
+
+func TestFiltered(t *testing.T) {
+	rec := &sinktest.Recorder{}
+	s := Filtered(rec, func(e watcher.Event) bool { return e.Type == "IssuesEvent" })
+	s.Send(watcher.Event{ID: "1", Type: "IssuesEvent"})
+	s.Send(watcher.Event{ID: "2", Type: "WatchEvent"})
+	if len(rec.Events()) != 1 || rec.Events()[0].ID != "1" {
+		t.Errorf("expected only event 1, got %v", rec.Events())
+	}
+	s.Close()
+	if !rec.Closed() {
+		t.Error("expected Close to reach the wrapped sink")
+	}
+}
+
+func TestWriterAlerts(t *testing.T) {
+	e := watcher.Event{
+		Type:    "IssuesEvent",
+		Actor:   watcher.Actor{Login: "alice"},
+		Payload: watcher.Payload{Action: "opened", Issue: &watcher.Issue{Number: 1, Title: "Outage in EU"}},
+		Alerts: []watcher.Alert{
+			{Rule: "incident", Severity: "critical", Fragments: []string{"outage"}, Highlight: true},
+			{Rule: "eu", Severity: "info"},
+		},
+	}
+
+	var plain bytes.Buffer
+	NewWriter(&plain).Send(e)
+	if !strings.HasPrefix(plain.String(), "[ALERT critical] incident, eu\n") || strings.Contains(plain.String(), "\x1b[") {
+		t.Errorf("unexpected plain output:\n%q", plain.String())
+	}
+
+	var color bytes.Buffer
+	w := NewWriter(&color)
+	w.color = true
+	w.Send(e)
+	if !strings.Contains(color.String(), "\x1b[1;7mOutage\x1b[0m\x1b[32m\x1b[1m in EU") {
+		t.Errorf("expected the fragment to be highlighted and the headline color restored:\n%q", color.String())
+	}
+
+	// Fragments never match inside the escapes that color the output
+	e.Alerts = []watcher.Alert{{Rule: "m", Fragments: []string{"m"}, Highlight: true}}
+	color.Reset()
+	w.Send(e)
+	if strings.Contains(color.String(), "\x1b[1;7m") {
+		t.Errorf("fragment matched inside an escape:\n%q", color.String())
+	}
+}
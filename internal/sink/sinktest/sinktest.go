@@ -0,0 +1,48 @@
+// Package sinktest provides a sink that records what it is given, for tests.
+package sinktest
+
+import (
+	"sync"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// Recorder is a sink keeping the events it receives. It is safe for concurrent use.
+type Recorder struct {
+	// Err is returned by every Send
+	Err error
+
+	mu     sync.Mutex
+	events []watcher.Event
+	closed bool
+}
+
+// Send records the event
+func (r *Recorder) Send(e watcher.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+	return r.Err
+}
+
+// Close marks the recorder closed
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	return nil
+}
+
+// Events returns a copy of the events received so far
+func (r *Recorder) Events() []watcher.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]watcher.Event(nil), r.events...)
+}
+
+// Closed reports whether Close was called
+func (r *Recorder) Closed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closed
+}
@@ -0,0 +1,202 @@
+package sink
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// Syslog facilities and severities used by the syslog sink
+const (
+	FacilityUser   = 1
+	FacilityDaemon = 3
+	FacilityLocal0 = 16
+
+	severityWarning = 4
+	severityNotice  = 5
+	severityInfo    = 6
+)
+
+// sdID is the structured data element ID; 32473 is the documentation enterprise number
+const sdID = "ghostio@32473"
+
+// SyslogConfig holds configuration for the syslog sink
+type SyslogConfig struct {
+	// Network is one of "unixgram", "unix", "udp" or "tcp"
+	Network  string
+	Address  string
+	Facility int
+	AppName  string
+	Hostname string
+}
+
+// ParseSyslogAddress parses addresses like unix:///dev/log, udp://host:514 or tcp://host:601
+func ParseSyslogAddress(addr string) (SyslogConfig, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return SyslogConfig{}, fmt.Errorf("invalid syslog address %q: %w", addr, err)
+	}
+	switch u.Scheme {
+	case "unix", "unixgram":
+		return SyslogConfig{Network: "unixgram", Address: u.Path}, nil
+	case "unixstream":
+		return SyslogConfig{Network: "unix", Address: u.Path}, nil
+	case "udp", "tcp":
+		if u.Host == "" {
+			return SyslogConfig{}, fmt.Errorf("invalid syslog address %q: missing host", addr)
+		}
+		host := u.Host
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "514")
+		}
+		return SyslogConfig{Network: u.Scheme, Address: host}, nil
+	}
+	return SyslogConfig{}, fmt.Errorf("unsupported syslog scheme %q", u.Scheme)
+}
+
+// Syslog writes events as RFC 5424 syslog messages
+type Syslog struct {
+	config SyslogConfig
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslog creates a syslog sink and connects to the server
+func NewSyslog(config SyslogConfig) (*Syslog, error) {
+	if config.Facility == 0 {
+		config.Facility = FacilityDaemon
+	}
+	if config.AppName == "" {
+		config.AppName = "ghostio"
+	}
+	if config.Hostname == "" {
+		config.Hostname, _ = os.Hostname()
+	}
+
+	s := &Syslog{config: config}
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Syslog) connect() error {
+	conn, err := net.DialTimeout(s.config.Network, s.config.Address, 5*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+	s.conn = conn
+	return nil
+}
+
+// Send writes the event to syslog, reconnecting once on failure
+func (s *Syslog) Send(e watcher.Event) error {
+	msg := s.format(e, time.Now())
+	if s.config.Network == "tcp" || s.config.Network == "unix" {
+		// RFC 6587 octet-counting framing for stream transports
+		msg = fmt.Sprintf("%d %s", len(msg), msg)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		if _, err := s.conn.Write([]byte(msg)); err == nil {
+			return nil
+		}
+		s.conn.Close()
+		s.conn = nil
+	}
+	if err := s.connect(); err != nil {
+		return err
+	}
+	if _, err := s.conn.Write([]byte(msg)); err != nil {
+		return fmt.Errorf("failed to write to syslog: %w", err)
+	}
+	return nil
+}
+
+// Close closes the connection to the server
+func (s *Syslog) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// format renders an RFC 5424 message with the event's fields as structured data
+func (s *Syslog) format(e watcher.Event, now time.Time) string {
+	pri := s.config.Facility*8 + severity(e)
+	headline, _ := SplitEvent(e)
+
+	var sd strings.Builder
+	sd.WriteString("[" + sdID)
+	for _, f := range eventFields(e) {
+		fmt.Fprintf(&sd, " %s=\"%s\"", strings.ToLower(strings.TrimPrefix(f.key, "GHOSTIO_")), escapeSDValue(f.value))
+	}
+	sd.WriteString("]")
+
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s %s %s",
+		pri,
+		now.UTC().Format(time.RFC3339Nano),
+		nilValue(s.config.Hostname),
+		nilValue(s.config.AppName),
+		os.Getpid(),
+		nilValue(e.Type),
+		sd.String(),
+		headline)
+}
+
+// severity maps the event's notification urgency to a syslog severity
+func severity(e watcher.Event) int {
+	switch Urgency(e) {
+	case UrgencyCritical:
+		return severityWarning
+	case UrgencyLow:
+		return severityInfo
+	}
+	return severityNotice
+}
+
+func escapeSDValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(s)
+}
+
+func nilValue(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return strings.ReplaceAll(s, " ", "_")
+}
+
+type field struct {
+	key   string
+	value string
+}
+
+// eventFields returns the structured fields recorded for every event
+func eventFields(e watcher.Event) []field {
+	fields := []field{
+		{"GHOSTIO_REPO", e.Repo.Name},
+		{"GHOSTIO_EVENT_TYPE", e.Type},
+		{"GHOSTIO_ACTOR", e.Actor.Login},
+		{"GHOSTIO_URL", e.HTMLURL()},
+	}
+	if e.Payload.Action != "" {
+		fields = append(fields, field{"GHOSTIO_ACTION", e.Payload.Action})
+	}
+	if e.ID != "" {
+		fields = append(fields, field{"GHOSTIO_EVENT_ID", e.ID})
+	}
+	return fields
+}
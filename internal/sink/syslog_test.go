@@ -0,0 +1,136 @@
+package sink
+
+import (
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+var syslogEvent = watcher.Event{
+	ID:    "12345",
+	Type:  "IssuesEvent",
+	Actor: watcher.Actor{Login: "username"},
+	Repo:  watcher.Repo{Name: "org/api"},
+	Payload: watcher.Payload{
+		Action: "opened",
+		Issue: &watcher.Issue{
+			Number:  42,
+			Title:   `Bug in "login"`,
+			Body:    "Login fails\nwith error 500",
+			HTMLURL: "https://github.com/org/api/issues/42",
+		},
+	},
+	CreatedAt: time.Date(2024, 1, 15, 10, 30, 45, 0, time.UTC),
+}
+
+func TestParseSyslogAddress(t *testing.T) {
+	tests := []struct {
+		addr    string
+		network string
+		address string
+		wantErr bool
+	}{
+		{"unix:///dev/log", "unixgram", "/dev/log", false},
+		{"unixstream:///var/run/syslog", "unix", "/var/run/syslog", false},
+		{"udp://logs.example.com", "udp", "logs.example.com:514", false},
+		{"tcp://logs.example.com:601", "tcp", "logs.example.com:601", false},
+		{"http://logs.example.com", "", "", true},
+		{"udp://", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			config, err := ParseSyslogAddress(tt.addr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSyslogAddress(%q) error = %v, wantErr %v", tt.addr, err, tt.wantErr)
+			}
+			if config.Network != tt.network || config.Address != tt.address {
+				t.Errorf("ParseSyslogAddress(%q) = %s %s, want %s %s",
+					tt.addr, config.Network, config.Address, tt.network, tt.address)
+			}
+		})
+	}
+}
+
+func TestSyslogFormat(t *testing.T) {
+	s := &Syslog{config: SyslogConfig{Facility: FacilityDaemon, AppName: "ghostio", Hostname: "ops1"}}
+	msg := s.format(syslogEvent, time.Date(2024, 1, 15, 10, 31, 0, 0, time.UTC))
+
+	pattern := `^<29>1 2024-01-15T10:31:00Z ops1 ghostio \d+ IssuesEvent ` +
+		`\[ghostio@32473 repo="org/api" event_type="IssuesEvent" actor="username" ` +
+		`url="https://github.com/org/api/issues/42" action="opened" event_id="12345"\] ` +
+		`Issue opened: #42 "Bug in "login"" by @username$`
+	if !regexp.MustCompile(pattern).MatchString(msg) {
+		t.Errorf("unexpected message:\n%s", msg)
+	}
+}
+
+func TestSyslogUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer pc.Close()
+
+	s, err := NewSyslog(SyslogConfig{Network: "udp", Address: pc.LocalAddr().String()})
+	if err != nil {
+		t.Fatalf("NewSyslog() error: %v", err)
+	}
+	defer s.Close()
+
+	if err := s.Send(syslogEvent); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+
+	buf := make([]byte, 4096)
+	pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("failed to read datagram: %v", err)
+	}
+	if msg := string(buf[:n]); !strings.HasPrefix(msg, "<29>1 ") || !strings.Contains(msg, `repo="org/api"`) {
+		t.Errorf("unexpected datagram: %s", msg)
+	}
+}
+
+func TestSyslogTCPFraming(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 4096)
+		n, _ := conn.Read(buf)
+		received <- string(buf[:n])
+	}()
+
+	s, err := NewSyslog(SyslogConfig{Network: "tcp", Address: ln.Addr().String()})
+	if err != nil {
+		t.Fatalf("NewSyslog() error: %v", err)
+	}
+	defer s.Close()
+	if err := s.Send(syslogEvent); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+
+	select {
+	case msg := <-received:
+		if !regexp.MustCompile(`^\d+ <29>1 `).MatchString(msg) {
+			t.Errorf("expected octet-counted frame, got %q", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+}
@@ -0,0 +1,102 @@
+package sink
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// threadIndent is the prefix of comments shown under their thread header
+const threadIndent = "    "
+
+// ThreadWriter writes formatted events grouped by issue or pull request conversation
+type ThreadWriter struct {
+	Writer
+	// thread is the conversation of the last event written
+	thread string
+}
+
+// NewThreadWriter creates a Sink that prints a header whenever the conversation changes
+// and indents comments underneath it
+func NewThreadWriter(w io.Writer) *ThreadWriter {
+	return &ThreadWriter{Writer: *NewWriter(w)}
+}
+
+// threadKey identifies the conversation an event belongs to, or "" when it has none
+func threadKey(e watcher.Event) string {
+	if n := e.Number(); n != 0 {
+		return fmt.Sprintf("%s#%d", e.Repo.Name, n)
+	}
+	return ""
+}
+
+// isComment reports whether the event is a comment or review in a conversation
+func isComment(e watcher.Event) bool {
+	switch e.Type {
+	case "IssueCommentEvent", "PullRequestReviewCommentEvent", "PullRequestReviewEvent":
+		return true
+	}
+	return false
+}
+
+// Send prints the event, preceded by a thread header when it starts a different conversation
+func (s *ThreadWriter) Send(e watcher.Event) error {
+	var b strings.Builder
+	width := terminalWidth(s.w)
+	key := threadKey(e)
+	if key == "" {
+		if s.thread != "" {
+			b.WriteString("\n")
+		}
+		s.thread = ""
+		fmt.Fprintf(&b, "%s\n---\n", s.render(e, width))
+		_, err := io.WriteString(s.w, b.String())
+		return err
+	}
+
+	if key != s.thread {
+		if s.thread != "" {
+			b.WriteString("\n")
+		}
+		b.WriteString(threadHeader(key, e.Title(), s.color) + "\n")
+		s.thread = key
+	}
+	var text string
+	if isComment(e) {
+		if width > 0 {
+			width -= len(threadIndent)
+		}
+		text = indent(s.render(e, width), threadIndent)
+	} else {
+		text = s.render(e, width)
+	}
+	fmt.Fprintf(&b, "%s\n\n", text)
+	_, err := io.WriteString(s.w, b.String())
+	return err
+}
+
+// threadHeader names a conversation, e.g. `=== owner/repo#12 "Fix the parser" ===`
+func threadHeader(key, title string, color bool) string {
+	header := "=== " + key
+	if title != "" {
+		header += fmt.Sprintf(" %q", title)
+	}
+	header += " ==="
+	if color {
+		return ansiBold + header + ansiReset
+	}
+	return header
+}
+
+// indent prefixes every non-empty line of text
+func indent(text, prefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,44 @@
+package sink
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+func TestThreadWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewThreadWriter(&buf)
+
+	pr := &watcher.PullRequest{Number: 12, Title: "Fix", HTMLURL: "https://github.com/owner/repo/pull/12"}
+	issue := &watcher.Issue{Number: 12, Title: "Fix", HTMLURL: "https://github.com/owner/repo/pull/12"}
+	repo := watcher.Repo{Name: "owner/repo"}
+	w.Send(watcher.Event{Type: "PullRequestEvent", Repo: repo, Actor: watcher.Actor{Login: "alice"}, Payload: watcher.Payload{Action: "opened", PullRequest: pr}})
+	w.Send(watcher.Event{Type: "IssueCommentEvent", Repo: repo, Actor: watcher.Actor{Login: "bob"}, Payload: watcher.Payload{Action: "created", Issue: issue, Comment: &watcher.Comment{Body: "Looks good"}}})
+	w.Send(watcher.Event{Type: "PullRequestReviewEvent", Repo: repo, Actor: watcher.Actor{Login: "dave"}, Payload: watcher.Payload{Action: "created", PullRequest: pr},
+		RawPayload: []byte(`{"action":"created","review":{"state":"CHANGES_REQUESTED","body":"Needs a test"}}`)})
+	w.Send(watcher.Event{Type: "PushEvent", Repo: repo, Actor: watcher.Actor{Login: "carol"}})
+
+	want := `=== owner/repo#12 "Fix" ===
+[0001-01-01 00:00:00] PR opened: #12 "Fix" by @alice
+https://github.com/owner/repo/pull/12
+
+    [0001-01-01 00:00:00] Comment on #12 "Fix" by @bob
+    https://github.com/owner/repo/pull/12
+
+    Looks good
+
+    [0001-01-01 00:00:00] PR changes requested: #12 "Fix" by @dave
+    https://github.com/owner/repo/pull/12
+
+    Needs a test
+
+
+[0001-01-01 00:00:00] PushEvent by @carol
+---
+`
+	if buf.String() != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
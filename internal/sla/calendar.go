@@ -0,0 +1,136 @@
+package sla
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/config"
+)
+
+// Calendar counts time during working hours only
+type Calendar struct {
+	loc      *time.Location
+	days     [7]bool
+	open     clock
+	close    clock
+	holidays map[string]bool
+}
+
+// clock is a time of day
+type clock struct{ hour, min int }
+
+func (c clock) minutes() int { return c.hour*60 + c.min }
+
+// on returns the clock's time on the day of t
+func (c clock) on(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, c.hour, c.min, 0, 0, t.Location())
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// NewCalendar validates a calendar configuration
+func NewCalendar(c config.CalendarConfig) (*Calendar, error) {
+	cal := &Calendar{loc: time.Local, holidays: map[string]bool{}}
+	if c.TimeZone != "" {
+		loc, err := time.LoadLocation(c.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone %q", c.TimeZone)
+		}
+		cal.loc = loc
+	}
+
+	days := c.Days
+	if len(days) == 0 {
+		days = []string{"mon", "tue", "wed", "thu", "fri"}
+	}
+	for _, d := range days {
+		name := strings.ToLower(strings.TrimSpace(d))
+		day, ok := weekdays[name]
+		if !ok && len(name) > 3 {
+			day, ok = weekdays[name[:3]]
+		}
+		if !ok {
+			return nil, fmt.Errorf("invalid day %q", d)
+		}
+		cal.days[day] = true
+	}
+
+	hours := c.Hours
+	if hours == "" {
+		hours = "09:00-17:00"
+	}
+	from, to, ok := strings.Cut(hours, "-")
+	var err1, err2 error
+	cal.open, err1 = parseClock(from)
+	cal.close, err2 = parseClock(to)
+	if !ok || err1 != nil || err2 != nil {
+		return nil, fmt.Errorf("invalid hours %q: want e.g. 09:00-17:00", hours)
+	}
+	if cal.close.minutes() <= cal.open.minutes() {
+		return nil, fmt.Errorf("invalid hours %q: the day must end after it starts", hours)
+	}
+
+	for _, h := range c.Holidays {
+		if _, err := time.Parse(time.DateOnly, h); err != nil {
+			return nil, fmt.Errorf("invalid holiday %q: want YYYY-MM-DD", h)
+		}
+		cal.holidays[h] = true
+	}
+	return cal, nil
+}
+
+func parseClock(s string) (clock, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		if strings.TrimSpace(s) == "24:00" {
+			return clock{24, 0}, nil
+		}
+		return clock{}, errors.New("invalid time")
+	}
+	return clock{t.Hour(), t.Minute()}, nil
+}
+
+// Day is the working time of a day
+func (c *Calendar) Day() time.Duration {
+	return time.Duration(c.close.minutes()-c.open.minutes()) * time.Minute
+}
+
+// working reports whether work happens on the day of t
+func (c *Calendar) working(t time.Time) bool {
+	return c.days[t.Weekday()] && !c.holidays[t.Format(time.DateOnly)]
+}
+
+// maxDays bounds the search for working time, for calendars that are almost all holidays
+const maxDays = 3660
+
+// Add returns the time at which d of working time has passed after t
+func (c *Calendar) Add(t time.Time, d time.Duration) time.Time {
+	if d <= 0 {
+		return t
+	}
+	t = t.In(c.loc)
+	for range maxDays {
+		if c.working(t) {
+			open, close := c.open.on(t), c.close.on(t)
+			if t.Before(open) {
+				t = open
+			}
+			if t.Before(close) {
+				left := close.Sub(t)
+				if d <= left {
+					return t.Add(d)
+				}
+				d -= left
+			}
+		}
+		y, m, day := t.Date()
+		t = time.Date(y, m, day+1, 0, 0, 0, 0, c.loc)
+	}
+	return t
+}
@@ -0,0 +1,141 @@
+// Package sla raises alerts when issues and pull requests wait too long for a response.
+// Deadlines start on matching events, stop on later responses from someone other than the
+// author, and can count business hours only.
+package sla
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/config"
+	"github.com/ytnobody/ghostio/internal/filter"
+	"github.com/ytnobody/ghostio/internal/rules"
+)
+
+// defaultStop is the stop condition of SLAs that do not give one: a comment or review
+const defaultStop = `type in ["IssueCommentEvent", "PullRequestReviewCommentEvent", "PullRequestReviewEvent"]`
+
+// SLA is a compiled deadline
+type SLA struct {
+	Name     string
+	Severity string
+	Actions  config.RuleActions
+	// Within is the deadline as configured, e.g. "4h" or "1d"
+	Within string
+	// Calendar names the business-hours calendar, empty when the clock always runs
+	Calendar string
+
+	start, stop *filter.Filter
+	within      time.Duration
+	calendar    *Calendar
+}
+
+// Compile validates and compiles SLA configurations and the calendars they use
+func Compile(configs []config.SLAConfig, calendars map[string]config.CalendarConfig) ([]*SLA, error) {
+	compiled := map[string]*Calendar{}
+	for name, c := range calendars {
+		cal, err := NewCalendar(c)
+		if err != nil {
+			return nil, fmt.Errorf("calendar %s: %w", name, err)
+		}
+		compiled[name] = cal
+	}
+
+	var slas []*SLA
+	seen := map[string]bool{}
+	for i, c := range configs {
+		s, err := compile(c, compiled)
+		if err != nil {
+			name := c.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i+1)
+			}
+			return nil, fmt.Errorf("sla %s: %w", name, err)
+		}
+		if seen[s.Name] {
+			return nil, fmt.Errorf("sla %s: defined twice", s.Name)
+		}
+		seen[s.Name] = true
+		slas = append(slas, s)
+	}
+	return slas, nil
+}
+
+func compile(c config.SLAConfig, calendars map[string]*Calendar) (*SLA, error) {
+	if c.Name == "" {
+		return nil, errors.New("missing name")
+	}
+	s := &SLA{Name: c.Name, Severity: c.Severity, Actions: c.Actions, Within: c.Within, Calendar: c.Calendar}
+	if s.Severity == "" {
+		s.Severity = "warning"
+	}
+	if !slices.Contains(rules.Severities, s.Severity) {
+		return nil, fmt.Errorf("invalid severity %q (want %s)", s.Severity, strings.Join(rules.Severities, ", "))
+	}
+
+	if c.Start == "" {
+		return nil, errors.New("missing start condition")
+	}
+	var err error
+	if s.start, err = filter.Parse(c.Start); err != nil {
+		return nil, fmt.Errorf("start: %w", err)
+	}
+	stop := c.Stop
+	if stop == "" {
+		stop = defaultStop
+	}
+	if s.stop, err = filter.Parse(stop); err != nil {
+		return nil, fmt.Errorf("stop: %w", err)
+	}
+
+	if c.Calendar != "" {
+		if s.calendar = calendars[c.Calendar]; s.calendar == nil {
+			return nil, fmt.Errorf("unknown calendar %q", c.Calendar)
+		}
+	}
+	if s.within, err = parseWithin(c.Within, s.calendar); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// parseWithin parses a deadline such as "4h" or "2d". A day is one working day of the
+// calendar, or 24 hours without one.
+func parseWithin(s string, cal *Calendar) (time.Duration, error) {
+	if s == "" {
+		return 0, errors.New("missing within")
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid within %q: want e.g. 4h or 1d", s)
+		}
+		if cal != nil {
+			return time.Duration(n) * cal.Day(), nil
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid within %q: want e.g. 4h or 1d", s)
+	}
+	return d, nil
+}
+
+// UsesDetails reports whether the start or stop condition refers to fields that only
+// enrichment provides
+func (s *SLA) UsesDetails() bool {
+	return s.start.UsesDetails() || s.stop.UsesDetails()
+}
+
+// Deadline returns when the SLA is missed for a clock started at t
+func (s *SLA) Deadline(t time.Time) time.Time {
+	if s.calendar == nil {
+		return t.Add(s.within)
+	}
+	return s.calendar.Add(t, s.within)
+}
@@ -0,0 +1,109 @@
+package sla
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/config"
+)
+
+func TestCalendarAdd(t *testing.T) {
+	cal, err := NewCalendar(config.CalendarConfig{
+		TimeZone: "Europe/Berlin",
+		Hours:    "09:00-17:00",
+		Holidays: []string{"2024-05-01"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	at := func(day, hour, min int) time.Time { return time.Date(2024, 4, day, hour, min, 0, 0, berlin) }
+
+	tests := []struct {
+		name  string
+		start time.Time
+		d     time.Duration
+		want  time.Time
+	}{
+		{"within the day", at(22, 10, 0), 4 * time.Hour, at(22, 14, 0)},
+		{"into the next day", at(22, 15, 0), 4 * time.Hour, at(23, 11, 0)},
+		{"before opening", at(22, 6, 30), time.Hour, at(22, 10, 0)},
+		{"after closing", at(22, 18, 0), time.Hour, at(23, 10, 0)},
+		{"over the weekend", at(26, 16, 0), 8 * time.Hour, at(29, 16, 0)},
+		{"on a Saturday", at(27, 12, 0), 30 * time.Minute, at(29, 9, 30)},
+		{"over a holiday", at(30, 16, 0), 2 * time.Hour, time.Date(2024, 5, 2, 10, 0, 0, 0, berlin)},
+		{"in UTC", time.Date(2024, 4, 22, 7, 0, 0, 0, time.UTC), time.Hour, at(22, 10, 0)},
+	}
+	for _, tt := range tests {
+		if got := cal.Add(tt.start, tt.d); !got.Equal(tt.want) {
+			t.Errorf("%s: Add(%v, %v) = %v, want %v", tt.name, tt.start, tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestCalendarErrors(t *testing.T) {
+	for _, c := range []config.CalendarConfig{
+		{TimeZone: "Mars/Olympus"},
+		{Days: []string{"funday"}},
+		{Hours: "9-5"},
+		{Hours: "17:00-09:00"},
+		{Holidays: []string{"25/12/2024"}},
+	} {
+		if _, err := NewCalendar(c); err == nil {
+			t.Errorf("NewCalendar(%+v) succeeded", c)
+		}
+	}
+	cal, err := NewCalendar(config.CalendarConfig{Days: []string{"Saturday", "sun"}, Hours: "10:00-14:30"})
+	if err != nil || !cal.days[time.Saturday] || cal.days[time.Monday] || cal.Day() != 270*time.Minute {
+		t.Errorf("NewCalendar = %+v, %v", cal, err)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	calendars := map[string]config.CalendarConfig{"office": {TimeZone: "UTC"}}
+	slas, err := Compile([]config.SLAConfig{
+		{Name: "bug", Start: `action == "opened" && "bug" in labels`, Within: "4h", Severity: "critical"},
+		{Name: "review", Start: `action == "review_requested"`, Stop: `type == "PullRequestReviewEvent"`, Within: "1d", Calendar: "office"},
+	}, calendars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slas[0].Severity != "critical" || slas[1].Severity != "warning" {
+		t.Errorf("severities = %s, %s", slas[0].Severity, slas[1].Severity)
+	}
+	friday := time.Date(2024, 4, 26, 15, 0, 0, 0, time.UTC)
+	if got := slas[0].Deadline(friday); !got.Equal(friday.Add(4 * time.Hour)) {
+		t.Errorf("around the clock deadline = %v", got)
+	}
+	if got := slas[1].Deadline(friday); !got.Equal(time.Date(2024, 4, 29, 15, 0, 0, 0, time.UTC)) {
+		t.Errorf("business day deadline = %v", got)
+	}
+
+	tests := []struct {
+		sla  config.SLAConfig
+		want string
+	}{
+		{config.SLAConfig{Start: "merged", Within: "1h"}, "sla #1: missing name"},
+		{config.SLAConfig{Name: "x", Within: "1h"}, "missing start"},
+		{config.SLAConfig{Name: "x", Start: "merged &&", Within: "1h"}, "start:"},
+		{config.SLAConfig{Name: "x", Start: "merged", Stop: "(", Within: "1h"}, "stop:"},
+		{config.SLAConfig{Name: "x", Start: "merged"}, "missing within"},
+		{config.SLAConfig{Name: "x", Start: "merged", Within: "soon"}, "invalid within"},
+		{config.SLAConfig{Name: "x", Start: "merged", Within: "1h", Calendar: "home"}, "unknown calendar"},
+		{config.SLAConfig{Name: "x", Start: "merged", Within: "1h", Severity: "panic"}, "invalid severity"},
+	}
+	for _, tt := range tests {
+		_, err := Compile([]config.SLAConfig{tt.sla}, calendars)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Compile(%+v) error = %v, want %q", tt.sla, err, tt.want)
+		}
+	}
+	twice := config.SLAConfig{Name: "x", Start: "merged", Within: "1h"}
+	if _, err := Compile([]config.SLAConfig{twice, twice}, nil); err == nil || !strings.Contains(err.Error(), "defined twice") {
+		t.Errorf("duplicate error = %v", err)
+	}
+	if _, err := Compile(nil, map[string]config.CalendarConfig{"bad": {Hours: "x"}}); err == nil || !strings.Contains(err.Error(), "calendar bad") {
+		t.Errorf("calendar error = %v", err)
+	}
+}
@@ -0,0 +1,472 @@
+package sla
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/sink"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// BreachType is the type of the events raised when a deadline passes
+const BreachType = "SLABreachEvent"
+
+// settledFor is how long a started clock is remembered after it stopped or fired, so
+// events seen again after a restart do not start it anew
+const settledFor = 90 * 24 * time.Hour
+
+// DefaultDir returns $XDG_STATE_HOME/ghostio/sla, falling back to ~/.local/state
+func DefaultDir() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "ghostio", "sla")
+}
+
+// Timer is a running clock of an SLA on an issue or pull request
+type Timer struct {
+	SLA      string    `json:"sla"`
+	Repo     string    `json:"repo"`
+	Number   int       `json:"number"`
+	Author   string    `json:"author,omitempty"`
+	Deadline time.Time `json:"deadline"`
+	// Event started the clock
+	Event watcher.Event `json:"event"`
+}
+
+func (t Timer) key() string {
+	return fmt.Sprintf("%s\x00%s#%d", t.SLA, t.Repo, t.Number)
+}
+
+// state is the contents of a repository's state file
+type state struct {
+	Timers []Timer `json:"timers"`
+	// Settled maps SLA and start event ID to when its clock stopped or fired
+	Settled map[string]time.Time `json:"settled,omitempty"`
+}
+
+// stateFile is where the clocks of a repository are kept, e.g. dir/owner/repo.json
+func stateFile(dir, repo string) string {
+	return filepath.Join(dir, filepath.FromSlash(repo)+".json")
+}
+
+// pending is a scheduled timer
+type pending struct {
+	Timer
+	timer *time.Timer
+	// resumed is set for clocks read from a state file, which may have been answered
+	// while ghostio was not running
+	resumed bool
+}
+
+// Config configures a watchdog
+type Config struct {
+	SLAs []*SLA
+	// Repos are the repositories whose clocks are kept; events of others are ignored.
+	// When empty every repository is watched and nothing is kept across restarts.
+	Repos []string
+	// Dir holds a state file per repository keeping its clocks across restarts, so
+	// processes watching different repositories do not overwrite each other's clocks;
+	// empty keeps them in memory
+	Dir string
+	// Recheck fetches the history of an issue or pull request. Resumed clocks are checked
+	// against it before they alert, since events while ghostio was not running were not seen.
+	Recheck func(repo string, number int) ([]watcher.Event, error)
+	// Bell receives the terminal bell of SLAs that ring it
+	Bell io.Writer
+	// OnExit is called once with the exit code of the first missed SLA that has one
+	OnExit func(code int)
+}
+
+// Watchdog is a sink that watches the events passing to next and delivers an alert event
+// to alerts whenever an SLA's deadline passes
+type Watchdog struct {
+	next, alerts sink.Sink
+	dir          string
+	repos        []string
+	recheck      func(repo string, number int) ([]watcher.Event, error)
+	bell         io.Writer
+	onExit       func(code int)
+
+	mu     sync.Mutex
+	slas   map[string]*SLA
+	timers map[string]*pending
+	// settled holds the settled clocks of each repository, see state.Settled
+	settled map[string]map[string]time.Time
+	closed  bool
+
+	// sendMu serializes deliveries from Send and the timers
+	sendMu   sync.Mutex
+	exitOnce sync.Once
+}
+
+// New creates a watchdog and resumes the clocks of its repositories kept in the state
+// files. Deadlines that passed while ghostio was not running are alerted at once.
+func New(next, alerts sink.Sink, config Config) (*Watchdog, error) {
+	w := &Watchdog{
+		next: next, alerts: alerts, repos: config.Repos, recheck: config.Recheck, bell: config.Bell, onExit: config.OnExit,
+		timers: map[string]*pending{}, settled: map[string]map[string]time.Time{},
+	}
+	if len(w.repos) > 0 {
+		w.dir = config.Dir
+	}
+	w.slas = byName(config.SLAs)
+
+	states := map[string]state{}
+	for _, repo := range w.repos {
+		if w.dir == "" {
+			break
+		}
+		path := stateFile(w.dir, repo)
+		data, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("failed to read SLA state: %w", err)
+		}
+		var st state
+		if err := json.Unmarshal(data, &st); err != nil {
+			return nil, fmt.Errorf("invalid SLA state %s: %w", path, err)
+		}
+		states[repo] = st
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for repo, st := range states {
+		for k, t := range st.Settled {
+			w.settledOf(repo)[k] = t
+		}
+		for _, t := range st.Timers {
+			if w.slas[t.SLA] != nil && t.Repo == repo {
+				w.schedule(t, true)
+			}
+		}
+	}
+	return w, nil
+}
+
+// settledOf returns the settled clocks of a repository; the caller holds mu
+func (w *Watchdog) settledOf(repo string) map[string]time.Time {
+	m := w.settled[repo]
+	if m == nil {
+		m = map[string]time.Time{}
+		w.settled[repo] = m
+	}
+	return m
+}
+
+// watches reports whether the watchdog keeps clocks for the repository
+func (w *Watchdog) watches(repo string) bool {
+	return len(w.repos) == 0 || slices.Contains(w.repos, repo)
+}
+
+func byName(slas []*SLA) map[string]*SLA {
+	m := map[string]*SLA{}
+	for _, s := range slas {
+		m[s.Name] = s
+	}
+	return m
+}
+
+// SetSLAs replaces the SLAs, e.g. after the configuration is reloaded. Running clocks of
+// SLAs that remain keep their deadlines; the others are dropped.
+func (w *Watchdog) SetSLAs(slas []*SLA) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.slas = byName(slas)
+	for k, p := range w.timers {
+		if w.slas[p.SLA] == nil {
+			p.timer.Stop()
+			delete(w.timers, k)
+		}
+	}
+	return w.saveAll()
+}
+
+// Pending returns the running clocks, earliest deadline first
+func (w *Watchdog) Pending() []Timer {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var timers []Timer
+	for _, p := range w.timers {
+		timers = append(timers, p.Timer)
+	}
+	sort.Slice(timers, func(i, j int) bool { return timers[i].Deadline.Before(timers[j].Deadline) })
+	return timers
+}
+
+// Send starts and stops clocks on the event and the events merged into it, then passes
+// it on to next
+func (w *Watchdog) Send(e watcher.Event) error {
+	events := append([]watcher.Event{e}, e.Coalesced...)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].CreatedAt.Before(events[j].CreatedAt) })
+
+	w.mu.Lock()
+	changed := map[string]bool{}
+	for _, ev := range events {
+		if w.observe(ev) {
+			changed[ev.Repo.Name] = true
+		}
+	}
+	var errs []error
+	for repo := range changed {
+		errs = append(errs, w.save(repo))
+	}
+	w.mu.Unlock()
+
+	w.sendMu.Lock()
+	defer w.sendMu.Unlock()
+	return errors.Join(append(errs, w.next.Send(e))...)
+}
+
+// observe updates the clocks of the event's issue or pull request and reports whether any changed
+func (w *Watchdog) observe(e watcher.Event) bool {
+	number := e.Number()
+	if number == 0 || len(w.slas) == 0 || !w.watches(e.Repo.Name) {
+		return false
+	}
+	changed := false
+	for _, s := range w.slas {
+		k := Timer{SLA: s.Name, Repo: e.Repo.Name, Number: number}.key()
+		if p := w.timers[k]; p != nil {
+			if stops(s, p.Timer, e) {
+				w.settle(k, p)
+				changed = true
+			}
+			continue
+		}
+		if closes(e) || !s.start.Match(e) {
+			continue
+		}
+		if _, done := w.settled[e.Repo.Name][settledKey(s.Name, e)]; done {
+			continue
+		}
+		w.schedule(Timer{
+			SLA:      s.Name,
+			Repo:     e.Repo.Name,
+			Number:   number,
+			Author:   author(e),
+			Deadline: s.Deadline(e.CreatedAt),
+			Event:    e,
+		}, false)
+		changed = true
+	}
+	return changed
+}
+
+// closes reports whether the event closes its issue or pull request
+func closes(e watcher.Event) bool {
+	return (e.Type == "IssuesEvent" || e.Type == "PullRequestEvent") && e.Payload.Action == "closed"
+}
+
+// stops reports whether the event stops the clock: it closes the issue or pull request,
+// or someone answers it
+func stops(s *SLA, t Timer, e watcher.Event) bool {
+	return closes(e) || (s.stop.Match(e) && responder(e, t.Author))
+}
+
+// responder reports whether the event's actor answers the author: someone else and not a bot
+func responder(e watcher.Event, author string) bool {
+	login := e.Actor.Login
+	return login != "" && login != author && !strings.HasSuffix(login, "[bot]")
+}
+
+// author returns who opened the event's issue or pull request
+func author(e watcher.Event) string {
+	switch {
+	case e.Payload.PullRequest != nil && e.Payload.PullRequest.User.Login != "":
+		return e.Payload.PullRequest.User.Login
+	case e.Payload.Issue != nil && e.Payload.Issue.User.Login != "":
+		return e.Payload.Issue.User.Login
+	}
+	return e.Actor.Login
+}
+
+func settledKey(sla string, e watcher.Event) string {
+	if e.ID == "" {
+		return ""
+	}
+	return sla + ":" + e.ID
+}
+
+// schedule runs the timer; the caller holds mu
+func (w *Watchdog) schedule(t Timer, resumed bool) {
+	p := &pending{Timer: t, resumed: resumed}
+	k := t.key()
+	w.timers[k] = p
+	p.timer = time.AfterFunc(time.Until(t.Deadline), func() {
+		if err := w.fire(k, p); err != nil {
+			log.Printf("sink error: %v", err)
+		}
+	})
+}
+
+// settle removes a timer that stopped or fired and remembers its start event; the caller holds mu
+func (w *Watchdog) settle(k string, p *pending) {
+	p.timer.Stop()
+	delete(w.timers, k)
+	if sk := settledKey(p.SLA, p.Event); sk != "" {
+		w.settledOf(p.Repo)[sk] = time.Now()
+	}
+}
+
+// fire delivers the alert of a timer that is still running. A resumed clock is rechecked
+// first and stops quietly if it was answered while ghostio was not running.
+func (w *Watchdog) fire(k string, p *pending) error {
+	var history []watcher.Event
+	if p.resumed && w.recheck != nil {
+		var err error
+		if history, err = w.recheck(p.Repo, p.Number); err != nil {
+			log.Printf("Warning: cannot recheck %s#%d for SLA %s: %v", p.Repo, p.Number, p.SLA, err)
+		}
+	}
+
+	w.mu.Lock()
+	s := w.slas[p.SLA]
+	if w.closed || w.timers[k] != p || s == nil {
+		w.mu.Unlock()
+		return nil
+	}
+	for _, e := range history {
+		if e.CreatedAt.After(p.Event.CreatedAt) && stops(s, p.Timer, e) {
+			w.settle(k, p)
+			err := w.save(p.Repo)
+			w.mu.Unlock()
+			return err
+		}
+	}
+	w.settle(k, p)
+	err := w.save(p.Repo)
+	w.mu.Unlock()
+
+	w.sendMu.Lock()
+	w.mu.Lock()
+	closed := w.closed
+	w.mu.Unlock()
+	if !closed {
+		err = errors.Join(err, w.alerts.Send(Breach(s, p.Timer)))
+	}
+	w.sendMu.Unlock()
+
+	if s.Actions.Bell && w.bell != nil {
+		w.bell.Write([]byte("\a"))
+	}
+	if s.Actions.Exit != nil && w.onExit != nil {
+		code := *s.Actions.Exit
+		w.exitOnce.Do(func() { w.onExit(code) })
+	}
+	return err
+}
+
+// Breach returns the alert event of a missed deadline: the event that started the clock,
+// retyped, with a summary and the SLA's alert
+func Breach(s *SLA, t Timer) watcher.Event {
+	e := t.Event
+	e.ID = fmt.Sprintf("sla-%s-%s-%d-%d", s.Name, t.Repo, t.Number, t.Deadline.Unix())
+	e.Type = BreachType
+	e.CreatedAt = t.Deadline
+	e.Coalesced = nil
+	kind := "issue"
+	if e.Payload.PullRequest != nil || (e.Payload.Issue != nil && e.Payload.Issue.PullRequest != nil) {
+		kind = "pull request"
+	}
+	e.Summary = fmt.Sprintf("SLA %s missed: %s #%d", s.Name, kind, t.Number)
+	if title := e.Title(); title != "" {
+		e.Summary += fmt.Sprintf(" %q", title)
+	}
+	e.Summary += " had no response within " + s.Within
+	if s.Calendar != "" {
+		e.Summary += fmt.Sprintf(" (%s business hours)", s.Calendar)
+	}
+	e.Alerts = []watcher.Alert{{Rule: s.Name, Severity: s.Severity, Sinks: s.Actions.Sinks}}
+	return e
+}
+
+// saveAll writes the state files of every repository; the caller holds mu
+func (w *Watchdog) saveAll() error {
+	var errs []error
+	for _, repo := range w.repos {
+		errs = append(errs, w.save(repo))
+	}
+	return errors.Join(errs...)
+}
+
+// save writes the state file of a repository atomically; the caller holds mu
+func (w *Watchdog) save(repo string) error {
+	if w.dir == "" {
+		return nil
+	}
+	path := stateFile(w.dir, repo)
+	st := state{Timers: []Timer{}, Settled: map[string]time.Time{}}
+	for _, p := range w.timers {
+		if p.Repo == repo {
+			st.Timers = append(st.Timers, p.Timer)
+		}
+	}
+	sort.Slice(st.Timers, func(i, j int) bool { return st.Timers[i].Deadline.Before(st.Timers[j].Deadline) })
+	for k, t := range w.settled[repo] {
+		if time.Since(t) > settledFor {
+			delete(w.settled[repo], k)
+			continue
+		}
+		st.Settled[k] = t
+	}
+	if len(st.Timers) == 0 && len(st.Settled) == 0 {
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+	}
+
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to save SLA state: %w", err)
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to save SLA state: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to save SLA state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save SLA state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save SLA state: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Close stops the timers, keeping them in the state files, and closes next
+func (w *Watchdog) Close() error {
+	w.sendMu.Lock()
+	defer w.sendMu.Unlock()
+	w.mu.Lock()
+	w.closed = true
+	for _, p := range w.timers {
+		p.timer.Stop()
+	}
+	err := w.saveAll()
+	w.mu.Unlock()
+	return errors.Join(err, w.next.Close())
+}
@@ -0,0 +1,283 @@
+package sla
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/config"
+	"github.com/ytnobody/ghostio/internal/sink/sinktest"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+func compileSLAs(t *testing.T, within string) []*SLA {
+	t.Helper()
+	exit := 3
+	slas, err := Compile([]config.SLAConfig{{
+		Name:     "bug-response",
+		Start:    `type == "IssuesEvent" && action == "opened" && "bug" in labels`,
+		Within:   within,
+		Severity: "critical",
+		Actions:  config.RuleActions{Sinks: []string{"notify"}, Bell: true, Exit: &exit},
+	}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return slas
+}
+
+func opened(id string, number int) watcher.Event {
+	return watcher.Event{
+		ID: id, Type: "IssuesEvent", Actor: watcher.Actor{Login: "alice"}, Repo: watcher.Repo{Name: "org/api"},
+		CreatedAt: time.Now(),
+		Payload: watcher.Payload{Action: "opened", Issue: &watcher.Issue{
+			Number: number, Title: "Crash", User: watcher.Actor{Login: "alice"}, Labels: []watcher.Label{{Name: "bug"}},
+		}},
+	}
+}
+
+func comment(actor string, number int) watcher.Event {
+	return watcher.Event{
+		Type: "IssueCommentEvent", Actor: watcher.Actor{Login: actor}, Repo: watcher.Repo{Name: "org/api"}, CreatedAt: time.Now(),
+		Payload: watcher.Payload{Action: "created", Issue: &watcher.Issue{Number: number, User: watcher.Actor{Login: "alice"}}},
+	}
+}
+
+// waitFor polls until cond holds or a second passes
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+func TestWatchdog(t *testing.T) {
+	next, alerts := &sinktest.Recorder{}, &sinktest.Recorder{}
+	var bell bytes.Buffer
+	var mu sync.Mutex
+	var exitCode int
+	w, err := New(next, alerts, Config{
+		SLAs: compileSLAs(t, "50ms"),
+		Bell: &bell,
+		OnExit: func(code int) {
+			mu.Lock()
+			exitCode = code
+			mu.Unlock()
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// Issue 1: the author and bots do not answer it
+	w.Send(opened("1", 1))
+	w.Send(comment("alice", 1))
+	w.Send(comment("dependabot[bot]", 1))
+	// Issue 2 is answered, issue 3 is closed
+	w.Send(opened("2", 2))
+	w.Send(comment("bob", 2))
+	w.Send(opened("3", 3))
+	closed := opened("4", 3)
+	closed.Payload.Action = "closed"
+	w.Send(closed)
+
+	if got := len(next.Events()); got != 7 {
+		t.Errorf("next received %d events, want 7", got)
+	}
+	waitFor(t, "the alert", func() bool { return len(alerts.Events()) > 0 })
+	time.Sleep(100 * time.Millisecond)
+	got := alerts.Events()
+	if len(got) != 1 {
+		t.Fatalf("alerts = %+v", got)
+	}
+	e := got[0]
+	if e.Type != BreachType || e.Number() != 1 || e.Summary != `SLA bug-response missed: issue #1 "Crash" had no response within 50ms` {
+		t.Errorf("alert = %s #%d %q", e.Type, e.Number(), e.Summary)
+	}
+	if len(e.Alerts) != 1 || e.Alerts[0].Rule != "bug-response" || e.Alerts[0].Severity != "critical" || e.Alerts[0].Sinks[0] != "notify" {
+		t.Errorf("alert rules = %+v", e.Alerts)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if bell.String() != "\a" || exitCode != 3 {
+		t.Errorf("bell = %q, exit code = %d", bell.String(), exitCode)
+	}
+	if len(w.Pending()) != 0 {
+		t.Errorf("pending = %+v", w.Pending())
+	}
+}
+
+func TestWatchdogCoalesced(t *testing.T) {
+	next, alerts := &sinktest.Recorder{}, &sinktest.Recorder{}
+	w, err := New(next, alerts, Config{SLAs: compileSLAs(t, "1h")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	merged := opened("1", 1)
+	merged.Coalesced = []watcher.Event{comment("bob", 1)}
+	merged.Coalesced[0].CreatedAt = merged.CreatedAt.Add(time.Second)
+	w.Send(merged)
+	if p := w.Pending(); len(p) != 0 {
+		t.Errorf("a response merged into the opening event should stop the clock: %+v", p)
+	}
+}
+
+func TestWatchdogState(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "sla")
+	slas := compileSLAs(t, "1h")
+	api := []string{"org/api"}
+
+	w, err := New(&sinktest.Recorder{}, &sinktest.Recorder{}, Config{SLAs: slas, Repos: api, Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Send(opened("1", 1))
+	w.Send(opened("2", 2))
+	w.Send(comment("bob", 2))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// After a restart the clock keeps its deadline and events seen again start nothing
+	w, err = New(&sinktest.Recorder{}, &sinktest.Recorder{}, Config{SLAs: slas, Repos: api, Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending := w.Pending()
+	if len(pending) != 1 || pending[0].Number != 1 || pending[0].Author != "alice" || pending[0].Event.Title() != "Crash" {
+		t.Fatalf("pending after restart = %+v", pending)
+	}
+	w.Send(opened("1", 1))
+	w.Send(opened("2", 2))
+	if got := len(w.Pending()); got != 1 {
+		t.Errorf("replayed events started clocks: %d pending", got)
+	}
+	w.Close()
+
+	// A deadline that passed while stopped is alerted at once
+	data, _ := json.Marshal(state{Timers: []Timer{{SLA: "bug-response", Repo: "org/api", Number: 7, Deadline: time.Now().Add(-time.Hour), Event: opened("7", 7)}}})
+	overdue := t.TempDir()
+	os.MkdirAll(filepath.Join(overdue, "org"), 0o755)
+	if err := os.WriteFile(stateFile(overdue, "org/api"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	alerts := &sinktest.Recorder{}
+	w, err = New(&sinktest.Recorder{}, alerts, Config{SLAs: slas, Repos: api, Dir: overdue})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the overdue alert", func() bool { return len(alerts.Events()) == 1 })
+	w.Close()
+
+	// SLAs removed from the configuration drop their clocks
+	w, err = New(&sinktest.Recorder{}, &sinktest.Recorder{}, Config{SLAs: slas, Repos: api, Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Send(opened("5", 5))
+	if err := w.SetSLAs(nil); err != nil || len(w.Pending()) != 0 {
+		t.Errorf("SetSLAs(nil) = %v, pending %+v", err, w.Pending())
+	}
+	w.Close()
+}
+
+// Clocks resumed after a restart are checked for responses that were not seen
+func TestWatchdogRecheck(t *testing.T) {
+	slas := compileSLAs(t, "1h")
+	start := opened("7", 7)
+	start.CreatedAt = time.Now().Add(-2 * time.Hour)
+	data, _ := json.Marshal(state{Timers: []Timer{{SLA: "bug-response", Repo: "org/api", Number: 7, Author: "alice", Deadline: time.Now().Add(-time.Hour), Event: start}}})
+
+	for _, tt := range []struct {
+		name    string
+		history []watcher.Event
+		alerted bool
+	}{
+		{"answered", []watcher.Event{start, comment("bob", 7)}, false},
+		{"only the author", []watcher.Event{start, comment("alice", 7)}, true},
+	} {
+		dir := t.TempDir()
+		os.MkdirAll(filepath.Join(dir, "org"), 0o755)
+		if err := os.WriteFile(stateFile(dir, "org/api"), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		alerts := &sinktest.Recorder{}
+		rechecked := make(chan string, 1)
+		w, err := New(&sinktest.Recorder{}, alerts, Config{SLAs: slas, Repos: []string{"org/api"}, Dir: dir,
+			Recheck: func(repo string, number int) ([]watcher.Event, error) {
+				rechecked <- fmt.Sprintf("%s#%d", repo, number)
+				return tt.history, nil
+			}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := <-rechecked; got != "org/api#7" {
+			t.Errorf("%s: rechecked %s", tt.name, got)
+		}
+		waitFor(t, "the clock to settle", func() bool { return len(w.Pending()) == 0 })
+		time.Sleep(20 * time.Millisecond)
+		if got := len(alerts.Events()) == 1; got != tt.alerted {
+			t.Errorf("%s: alerted = %v", tt.name, got)
+		}
+		w.Close()
+	}
+}
+
+// Processes watching different repositories share the state directory without
+// overwriting or resuming each other's clocks
+func TestWatchdogRepos(t *testing.T) {
+	dir := t.TempDir()
+	slas := compileSLAs(t, "1h")
+	api, err := New(&sinktest.Recorder{}, &sinktest.Recorder{}, Config{SLAs: slas, Repos: []string{"org/api"}, Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	web, err := New(&sinktest.Recorder{}, &sinktest.Recorder{}, Config{SLAs: slas, Repos: []string{"org/web"}, Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.Send(opened("1", 1))
+	other := opened("2", 2)
+	other.Repo.Name = "org/web"
+	api.Send(other)
+	web.Send(other)
+	if got := api.Pending(); len(got) != 1 || got[0].Repo != "org/api" {
+		t.Errorf("org/api watchdog pending = %+v", got)
+	}
+	api.Close()
+	web.Close()
+
+	for repo, want := range map[string]int{"org/api": 1, "org/web": 2} {
+		w, err := New(&sinktest.Recorder{}, &sinktest.Recorder{}, Config{SLAs: slas, Repos: []string{repo}, Dir: dir})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := w.Pending(); len(got) != 1 || got[0].Number != want {
+			t.Errorf("%s resumed %+v", repo, got)
+		}
+		w.Close()
+	}
+}
+
+func TestWatchdogInvalidState(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "org"), 0o755)
+	if err := os.WriteFile(stateFile(dir, "org/api"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(&sinktest.Recorder{}, &sinktest.Recorder{}, Config{Repos: []string{"org/api"}, Dir: dir}); err == nil || !strings.Contains(err.Error(), "invalid SLA state") {
+		t.Errorf("New error = %v", err)
+	}
+}
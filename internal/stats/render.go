@@ -0,0 +1,118 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// sparks are the bar heights of a sparkline, lowest first
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline draws values as a row of bars scaled to the largest
+func Sparkline(values []int) string {
+	peak := 0
+	for _, v := range values {
+		peak = max(peak, v)
+	}
+	var b strings.Builder
+	for _, v := range values {
+		if peak == 0 {
+			b.WriteRune(sparks[0])
+			continue
+		}
+		b.WriteRune(sparks[v*(len(sparks)-1)/peak])
+	}
+	return b.String()
+}
+
+// FormatDuration rounds a duration for reading, e.g. "45m", "3h12m" or "2d4h"
+func FormatDuration(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return d.Round(time.Second).String()
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dd%dh", int(d.Hours())/24, int(d.Hours())%24)
+	}
+}
+
+// maxBar is the width of the longest bar in the comments table
+const maxBar = 30
+
+// WriteTable writes the report as tables with sparklines, listing up to top contributors
+func (r Report) WriteTable(w io.Writer, top int) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s, %s to %s\n\n", r.Repo, r.From.Format("2006-01-02"), r.To.Format("2006-01-02"))
+
+	fmt.Fprintln(tw, "\tMEDIAN\tP90\tCOUNT\tWAITING")
+	for _, row := range []struct {
+		name string
+		s    *Summary
+	}{
+		{"First response (issues)", &r.FirstResponseIssues},
+		{"First response (pull requests)", &r.FirstResponsePulls},
+		{"Time to merge", &r.TimeToMerge},
+		{"Review turnaround", r.ReviewTurnaround},
+	} {
+		if row.s == nil {
+			fmt.Fprintf(tw, "%s\tn/a\tn/a\t-\t-\n", row.name)
+			continue
+		}
+		median, p90 := "-", "-"
+		if row.s.Count > 0 {
+			median, p90 = FormatDuration(row.s.Median), FormatDuration(row.s.P90)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\n", row.name, median, p90, row.s.Count, row.s.Pending)
+	}
+
+	if r.ReviewTurnaround == nil {
+		fmt.Fprintln(tw, "No reviews among the events, so review turnaround is unknown")
+	}
+
+	opened := make([]int, len(r.Weeks))
+	closed := make([]int, len(r.Weeks))
+	for i, wk := range r.Weeks {
+		opened[i], closed[i] = wk.Opened, wk.Closed
+	}
+	fmt.Fprintf(tw, "\nWeekly throughput, oldest first\n")
+	fmt.Fprintf(tw, "Opened\t%s\t%s\n", Sparkline(opened), joinInts(opened))
+	fmt.Fprintf(tw, "Closed\t%s\t%s\n", Sparkline(closed), joinInts(closed))
+
+	fmt.Fprintf(tw, "\nComments and reviews\n")
+	if len(r.Comments) == 0 {
+		fmt.Fprintln(tw, "None")
+	}
+	for i, c := range r.Comments {
+		if top > 0 && i == top {
+			fmt.Fprintf(tw, "… %d more\n", len(r.Comments)-top)
+			break
+		}
+		bar := max(1, c.Comments*maxBar/r.Comments[0].Comments)
+		fmt.Fprintf(tw, "@%s\t%d\t%s\n", c.Login, c.Comments, strings.Repeat("█", bar))
+	}
+	return tw.Flush()
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprint(v)
+	}
+	return strings.Join(parts, " ")
+}
+
+// JSON renders the report as indented JSON
+func (r Report) JSON() ([]byte, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,221 @@
+// Package stats measures how a repository's maintainers respond: time to first response,
+// time to merge and review turnaround, comment volume per contributor and weekly throughput.
+package stats
+
+import (
+	"encoding/json"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// week is the length of a throughput bucket
+const week = 7 * 24 * time.Hour
+
+// Config selects the repository and period to measure
+type Config struct {
+	Repo string
+	// From and To bound the period; events at From are included, events at To are not
+	From, To time.Time
+}
+
+// Report holds the statistics of a period
+type Report struct {
+	Repo string    `json:"repo"`
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	// FirstResponseIssues and FirstResponsePulls measure issues and pull requests opened in
+	// the period until someone other than the author, and not a bot, comments or reviews
+	FirstResponseIssues Summary `json:"first_response_issues"`
+	FirstResponsePulls  Summary `json:"first_response_pulls"`
+	// TimeToMerge measures pull requests merged in the period from when they were opened
+	TimeToMerge Summary `json:"time_to_merge"`
+	// ReviewTurnaround measures pull requests opened in the period until their first review
+	// by someone other than the author. It is nil when pull requests were opened but the
+	// events hold no reviews to measure it from, as with a history recorded without them.
+	ReviewTurnaround *Summary      `json:"review_turnaround"`
+	Comments         []Contributor `json:"comments"`
+	Weeks            []Week        `json:"weeks"`
+}
+
+// Summary describes a set of durations
+type Summary struct {
+	// Count is the number of durations measured
+	Count int
+	// Pending counts the items still waiting at the end of the period
+	Pending int
+	Median  time.Duration
+	P90     time.Duration
+}
+
+// MarshalJSON encodes the durations in seconds
+func (s Summary) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Count         int     `json:"count"`
+		Pending       int     `json:"pending"`
+		MedianSeconds float64 `json:"median_seconds"`
+		P90Seconds    float64 `json:"p90_seconds"`
+	}{s.Count, s.Pending, s.Median.Seconds(), s.P90.Seconds()})
+}
+
+// summarize computes the median and 90th percentile of durations
+func summarize(durations []time.Duration, pending int) Summary {
+	s := Summary{Count: len(durations), Pending: pending}
+	if len(durations) == 0 {
+		return s
+	}
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		s.Median = sorted[n/2]
+	} else {
+		s.Median = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	s.P90 = sorted[(n*9+9)/10-1]
+	return s
+}
+
+// Contributor is someone who commented or reviewed during the period
+type Contributor struct {
+	Login    string `json:"login"`
+	Comments int    `json:"comments"`
+}
+
+// Week counts the issues and pull requests opened and closed in a week of the period
+type Week struct {
+	Start  time.Time `json:"start"`
+	Opened int       `json:"opened"`
+	Closed int       `json:"closed"`
+}
+
+// isBot reports whether a login belongs to a GitHub App
+func isBot(login string) bool {
+	return strings.HasSuffix(login, "[bot]")
+}
+
+// isResponse reports whether an event answers an issue or pull request
+func isResponse(e watcher.Event) bool {
+	switch e.Type {
+	case "IssueCommentEvent", "PullRequestReviewCommentEvent", "PullRequestReviewEvent":
+		return true
+	}
+	return false
+}
+
+// thread tracks an issue or pull request opened in the period
+type thread struct {
+	pull     bool
+	author   string
+	opened   time.Time
+	response time.Time
+	review   time.Time
+}
+
+// Build computes the statistics of the period from the repository's events
+func Build(config Config, events []watcher.Event) Report {
+	r := Report{Repo: config.Repo, From: config.From, To: config.To, Comments: []Contributor{}, Weeks: []Week{}}
+	for start := config.From; start.Before(config.To); start = start.Add(week) {
+		r.Weeks = append(r.Weeks, Week{Start: start})
+	}
+
+	events = slices.Clone(events)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].CreatedAt.Before(events[j].CreatedAt) })
+	threads := map[int]*thread{}
+	var merges []time.Duration
+	comments := map[string]int{}
+	reviewed := false
+	for _, e := range events {
+		if e.Repo.Name != config.Repo || e.CreatedAt.Before(config.From) || !e.CreatedAt.Before(config.To) {
+			continue
+		}
+		bucket := &r.Weeks[int(e.CreatedAt.Sub(config.From)/week)]
+		action := e.Payload.Action
+		switch {
+		case (e.Type == "IssuesEvent" || e.Type == "PullRequestEvent") && action == "opened":
+			bucket.Opened++
+			author := e.Actor.Login
+			if pr := e.Payload.PullRequest; pr != nil && pr.User.Login != "" {
+				author = pr.User.Login
+			} else if issue := e.Payload.Issue; issue != nil && issue.User.Login != "" {
+				author = issue.User.Login
+			}
+			if _, seen := threads[e.Number()]; !seen && e.Number() != 0 {
+				threads[e.Number()] = &thread{pull: e.Type == "PullRequestEvent", author: author, opened: e.CreatedAt}
+			}
+		case (e.Type == "IssuesEvent" || e.Type == "PullRequestEvent") && action == "closed":
+			bucket.Closed++
+			if pr := e.Payload.PullRequest; pr != nil && pr.Merged && !pr.CreatedAt.IsZero() {
+				merges = append(merges, e.CreatedAt.Sub(pr.CreatedAt))
+			}
+		case isResponse(e):
+			login := e.Actor.Login
+			if login == "" || isBot(login) {
+				continue
+			}
+			comments[login]++
+			if e.Type == "PullRequestReviewEvent" {
+				reviewed = true
+			}
+			t := threads[e.Number()]
+			if t == nil || login == t.author {
+				continue
+			}
+			if t.response.IsZero() {
+				t.response = e.CreatedAt
+			}
+			if e.Type == "PullRequestReviewEvent" && t.review.IsZero() {
+				t.review = e.CreatedAt
+			}
+		}
+	}
+
+	var issueWaits, pullWaits, reviewWaits []time.Duration
+	var issuePending, pullPending, reviewPending int
+	pulls := false
+	for _, t := range threads {
+		if t.author != "" && isBot(t.author) {
+			continue
+		}
+		switch {
+		case t.response.IsZero() && t.pull:
+			pullPending++
+		case t.response.IsZero():
+			issuePending++
+		case t.pull:
+			pullWaits = append(pullWaits, t.response.Sub(t.opened))
+		default:
+			issueWaits = append(issueWaits, t.response.Sub(t.opened))
+		}
+		if t.pull {
+			pulls = true
+			if t.review.IsZero() {
+				reviewPending++
+			} else {
+				reviewWaits = append(reviewWaits, t.review.Sub(t.opened))
+			}
+		}
+	}
+	r.FirstResponseIssues = summarize(issueWaits, issuePending)
+	r.FirstResponsePulls = summarize(pullWaits, pullPending)
+	r.TimeToMerge = summarize(merges, 0)
+	if reviewed || !pulls {
+		s := summarize(reviewWaits, reviewPending)
+		r.ReviewTurnaround = &s
+	}
+
+	for login, n := range comments {
+		r.Comments = append(r.Comments, Contributor{Login: login, Comments: n})
+	}
+	sort.Slice(r.Comments, func(i, j int) bool {
+		a, b := r.Comments[i], r.Comments[j]
+		if a.Comments != b.Comments {
+			return a.Comments > b.Comments
+		}
+		return a.Login < b.Login
+	})
+	return r
+}
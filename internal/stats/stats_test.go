@@ -0,0 +1,139 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+var from = time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+func at(hours float64) time.Time {
+	return from.Add(time.Duration(hours * float64(time.Hour)))
+}
+
+func event(typ, action, actor string, number int, when time.Time) watcher.Event {
+	e := watcher.Event{Type: typ, Actor: watcher.Actor{Login: actor}, Repo: watcher.Repo{Name: "org/api"}, CreatedAt: when,
+		Payload: watcher.Payload{Action: action}}
+	switch typ {
+	case "PullRequestEvent", "PullRequestReviewEvent", "PullRequestReviewCommentEvent":
+		e.Payload.PullRequest = &watcher.PullRequest{Number: number, User: watcher.Actor{Login: "alice"}, CreatedAt: at(0)}
+	default:
+		e.Payload.Issue = &watcher.Issue{Number: number, User: watcher.Actor{Login: "alice"}}
+	}
+	return e
+}
+
+func TestBuild(t *testing.T) {
+	merged := event("PullRequestEvent", "closed", "bob", 2, at(50))
+	merged.Payload.PullRequest.Merged = true
+	events := []watcher.Event{
+		// Issue 1: the author and a bot do not count as a response
+		event("IssuesEvent", "opened", "alice", 1, at(1)),
+		event("IssueCommentEvent", "created", "alice", 1, at(2)),
+		event("IssueCommentEvent", "created", "renovate[bot]", 1, at(2)),
+		event("IssueCommentEvent", "created", "bob", 1, at(4)),
+		// Issue 3 is answered after 10 hours and closed the next week
+		event("IssuesEvent", "opened", "alice", 3, at(10)),
+		event("IssueCommentEvent", "created", "carol", 3, at(20)),
+		event("IssuesEvent", "closed", "carol", 3, at(200)),
+		// Issue 4 waits
+		event("IssuesEvent", "opened", "alice", 4, at(30)),
+		// Pull request 2 is commented on, reviewed and merged
+		event("PullRequestEvent", "opened", "alice", 2, at(0)),
+		event("PullRequestReviewCommentEvent", "created", "carol", 2, at(6)),
+		event("PullRequestReviewEvent", "created", "bob", 2, at(8)),
+		merged,
+		// Outside the period or another repository
+		event("IssueCommentEvent", "created", "bob", 1, at(-5)),
+		{Type: "IssueCommentEvent", Actor: watcher.Actor{Login: "zed"}, Repo: watcher.Repo{Name: "org/web"}, CreatedAt: at(5)},
+	}
+	r := Build(Config{Repo: "org/api", From: from, To: at(14 * 24)}, events)
+
+	if s := r.FirstResponseIssues; s.Count != 2 || s.Pending != 1 || s.Median != 390*time.Minute || s.P90 != 10*time.Hour {
+		t.Errorf("issue first response = %+v", s)
+	}
+	if s := r.FirstResponsePulls; s.Count != 1 || s.Median != 6*time.Hour {
+		t.Errorf("pull request first response = %+v", s)
+	}
+	if s := r.ReviewTurnaround; s == nil || s.Count != 1 || s.Median != 8*time.Hour || s.Pending != 0 {
+		t.Errorf("review turnaround = %+v", s)
+	}
+	if s := r.TimeToMerge; s.Count != 1 || s.Median != 50*time.Hour {
+		t.Errorf("time to merge = %+v", s)
+	}
+	if len(r.Weeks) != 2 || r.Weeks[0].Opened != 4 || r.Weeks[0].Closed != 1 || r.Weeks[1].Closed != 1 {
+		t.Errorf("weeks = %+v", r.Weeks)
+	}
+	if len(r.Comments) != 3 || r.Comments[0] != (Contributor{"bob", 2}) || r.Comments[1] != (Contributor{"carol", 2}) {
+		t.Errorf("comments = %+v", r.Comments)
+	}
+
+	var buf bytes.Buffer
+	r.WriteTable(&buf, 2)
+	for _, want := range []string{"First response (issues)         6h30m   10h0m  2      1", "Opened  █▁  4 0", "@bob", "… 1 more"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("table lacks %q:\n%s", want, buf.String())
+		}
+	}
+	data, _ := r.JSON()
+	var decoded map[string]any
+	json.Unmarshal(data, &decoded)
+	if got := decoded["time_to_merge"].(map[string]any)["median_seconds"]; got != float64(50*3600) {
+		t.Errorf("median_seconds = %v", got)
+	}
+}
+
+// Without any reviews in the events, review turnaround is unknown rather than pending
+func TestBuildWithoutReviews(t *testing.T) {
+	events := []watcher.Event{
+		event("PullRequestEvent", "opened", "alice", 2, at(0)),
+		event("PullRequestReviewCommentEvent", "created", "carol", 2, at(6)),
+	}
+	r := Build(Config{Repo: "org/api", From: from, To: at(7 * 24)}, events)
+	if r.ReviewTurnaround != nil {
+		t.Errorf("review turnaround = %+v", r.ReviewTurnaround)
+	}
+	var buf bytes.Buffer
+	r.WriteTable(&buf, 0)
+	if !strings.Contains(buf.String(), "Review turnaround               n/a") || !strings.Contains(buf.String(), "review turnaround is unknown") {
+		t.Errorf("table:\n%s", buf.String())
+	}
+	data, _ := r.JSON()
+	if !strings.Contains(string(data), `"review_turnaround": null`) {
+		t.Errorf("JSON:\n%s", data)
+	}
+
+	// No pull requests means nothing is missing
+	r = Build(Config{Repo: "org/api", From: from, To: at(7 * 24)}, events[1:])
+	if r.ReviewTurnaround == nil || r.ReviewTurnaround.Count != 0 {
+		t.Errorf("review turnaround without pull requests = %+v", r.ReviewTurnaround)
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	if got := Sparkline([]int{0, 1, 4, 8}); got != "▁▁▄█" {
+		t.Errorf("Sparkline = %q", got)
+	}
+	if got := Sparkline([]int{0, 0}); got != "▁▁" {
+		t.Errorf("Sparkline of zeros = %q", got)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		30 * time.Second:              "30s",
+		45 * time.Minute:              "45m",
+		3*time.Hour + 12*time.Minute:  "3h12m",
+		52*time.Hour + 30*time.Minute: "2d4h",
+		10 * 24 * time.Hour:           "10d0h",
+	} {
+		if got := FormatDuration(d); got != want {
+			t.Errorf("FormatDuration(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package stream
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// DefaultBufferSize is the default number of recent events kept for resuming clients
+const DefaultBufferSize = 500
+
+// subscriberQueue is how many events may wait for a slow client before it is dropped
+const subscriberQueue = 64
+
+// Filter selects the events a client receives; empty lists match everything
+type Filter struct {
+	Types   []string
+	Actions []string
+	Actors  []string
+	Repos   []string
+}
+
+// ParseFilter reads a filter from type, action, actor and repo query parameters
+func ParseFilter(q url.Values) Filter {
+	return Filter{
+		Types:   splitParam(q["type"]),
+		Actions: splitParam(q["action"]),
+		Actors:  splitParam(q["actor"]),
+		Repos:   splitParam(q["repo"]),
+	}
+}
+
+// splitParam accepts both repeated parameters and comma-separated values
+func splitParam(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
+// Match reports whether the event passes the filter
+func (f Filter) Match(e watcher.Event) bool {
+	return matchAny(f.Types, e.Type) &&
+		matchAny(f.Actions, e.Payload.Action) &&
+		matchAny(f.Actors, e.Actor.Login) &&
+		matchAny(f.Repos, e.Repo.Name)
+}
+
+func matchAny(allowed []string, value string) bool {
+	return len(allowed) == 0 || slices.Contains(allowed, value)
+}
+
+// Subscription delivers live events to one client
+type Subscription struct {
+	C      <-chan watcher.Event
+	ch     chan watcher.Event
+	filter Filter
+	hub    *Hub
+}
+
+// Close stops delivery to the subscription
+func (s *Subscription) Close() {
+	s.hub.unsubscribe(s)
+}
+
+// Hub fans events out to subscribers and remembers recent ones for resuming
+type Hub struct {
+	mu     sync.Mutex
+	ring   []watcher.Event
+	start  int
+	count  int
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// NewHub creates a hub that buffers up to size recent events
+func NewHub(size int) *Hub {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	return &Hub{
+		ring: make([]watcher.Event, size),
+		subs: make(map[*Subscription]struct{}),
+	}
+}
+
+// Send records the event and delivers it to matching subscribers
+func (h *Hub) Send(e watcher.Event) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.count < len(h.ring) {
+		h.ring[(h.start+h.count)%len(h.ring)] = e
+		h.count++
+	} else {
+		h.ring[h.start] = e
+		h.start = (h.start + 1) % len(h.ring)
+	}
+
+	for sub := range h.subs {
+		if !sub.filter.Match(e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			// The client cannot keep up; it may reconnect and resume
+			delete(h.subs, sub)
+			close(sub.ch)
+		}
+	}
+	return nil
+}
+
+// Close disconnects all subscribers
+func (h *Hub) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.subs {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+	return nil
+}
+
+// Subscribe returns buffered events after lastID that match the filter, and a subscription for new ones.
+// If lastID is empty nothing is replayed; if it is no longer buffered every buffered event is replayed.
+func (h *Hub) Subscribe(filter Filter, lastID string) ([]watcher.Event, *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var backlog []watcher.Event
+	if lastID != "" {
+		events := h.snapshot()
+		from := 0
+		for i, e := range events {
+			if e.ID == lastID {
+				from = i + 1
+				break
+			}
+		}
+		for _, e := range events[from:] {
+			if filter.Match(e) {
+				backlog = append(backlog, e)
+			}
+		}
+	}
+
+	ch := make(chan watcher.Event, subscriberQueue)
+	sub := &Subscription{C: ch, ch: ch, filter: filter, hub: h}
+	if h.closed {
+		close(ch)
+	} else {
+		h.subs[sub] = struct{}{}
+	}
+	return backlog, sub
+}
+
+// Recent returns the buffered events, oldest first
+func (h *Hub) Recent() []watcher.Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.snapshot()
+}
+
+func (h *Hub) snapshot() []watcher.Event {
+	events := make([]watcher.Event, h.count)
+	for i := range events {
+		events[i] = h.ring[(h.start+i)%len(h.ring)]
+	}
+	return events
+}
+
+func (h *Hub) unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
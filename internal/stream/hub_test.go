@@ -0,0 +1,107 @@
+package stream
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+func event(id, typ, action, actor, repo string) watcher.Event {
+	return watcher.Event{
+		ID:      id,
+		Type:    typ,
+		Actor:   watcher.Actor{Login: actor},
+		Repo:    watcher.Repo{Name: repo},
+		Payload: watcher.Payload{Action: action},
+	}
+}
+
+func ids(events []watcher.Event) []string {
+	var out []string
+	for _, e := range events {
+		out = append(out, e.ID)
+	}
+	return out
+}
+
+func TestParseFilter(t *testing.T) {
+	q, _ := url.ParseQuery("type=IssuesEvent,PullRequestEvent&action=opened&repo=org/api&repo=org/app")
+	f := ParseFilter(q)
+
+	tests := []struct {
+		event    watcher.Event
+		expected bool
+	}{
+		{event("1", "IssuesEvent", "opened", "alice", "org/api"), true},
+		{event("2", "PullRequestEvent", "opened", "bob", "org/app"), true},
+		{event("3", "IssuesEvent", "closed", "alice", "org/api"), false},
+		{event("4", "ReleaseEvent", "opened", "alice", "org/api"), false},
+		{event("5", "IssuesEvent", "opened", "alice", "org/web"), false},
+	}
+	for _, tt := range tests {
+		if got := f.Match(tt.event); got != tt.expected {
+			t.Errorf("Match(%s) = %v, want %v", tt.event.ID, got, tt.expected)
+		}
+	}
+}
+
+func TestHubRingBuffer(t *testing.T) {
+	h := NewHub(3)
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		h.Send(event(id, "IssuesEvent", "opened", "alice", "org/api"))
+	}
+	if got := ids(h.Recent()); len(got) != 3 || got[0] != "3" || got[2] != "5" {
+		t.Errorf("Recent() = %v, want [3 4 5]", got)
+	}
+}
+
+func TestHubResume(t *testing.T) {
+	h := NewHub(10)
+	for _, id := range []string{"1", "2", "3"} {
+		h.Send(event(id, "IssuesEvent", "opened", "alice", "org/api"))
+	}
+
+	tests := []struct {
+		name     string
+		lastID   string
+		expected []string
+	}{
+		{"no last event id", "", nil},
+		{"resume after known id", "1", []string{"2", "3"}},
+		{"up to date", "3", nil},
+		{"unknown id replays buffer", "999", []string{"1", "2", "3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backlog, sub := h.Subscribe(Filter{}, tt.lastID)
+			defer sub.Close()
+			got := ids(backlog)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("backlog = %v, want %v", got, tt.expected)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Fatalf("backlog = %v, want %v", got, tt.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestHubDropsSlowSubscriber(t *testing.T) {
+	h := NewHub(10)
+	_, sub := h.Subscribe(Filter{}, "")
+	for i := 0; i < subscriberQueue+1; i++ {
+		h.Send(event("x", "IssuesEvent", "opened", "alice", "org/api"))
+	}
+
+	n := 0
+	for range sub.C {
+		n++
+	}
+	if n != subscriberQueue {
+		t.Errorf("received %d events before disconnect, want %d", n, subscriberQueue)
+	}
+	sub.Close()
+}
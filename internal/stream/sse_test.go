@@ -0,0 +1,80 @@
+package stream
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// readSSE reads SSE messages until n events have been received
+func readSSE(t *testing.T, r *bufio.Reader, n int) ([]string, []watcher.Event, int) {
+	t.Helper()
+	var idsSeen []string
+	var events []watcher.Event
+	heartbeats := 0
+	for len(events) < n {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read stream: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			idsSeen = append(idsSeen, strings.TrimPrefix(line, "id: "))
+		case strings.HasPrefix(line, "data: "):
+			var e watcher.Event
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e); err != nil {
+				t.Fatalf("invalid data line %q: %v", line, err)
+			}
+			events = append(events, e)
+		case line == ": heartbeat":
+			heartbeats++
+		}
+	}
+	return idsSeen, events, heartbeats
+}
+
+func TestServeSSE(t *testing.T) {
+	hub := NewHub(10)
+	hub.Send(event("1", "IssuesEvent", "opened", "alice", "org/api"))
+	hub.Send(event("2", "ReleaseEvent", "published", "alice", "org/api"))
+	hub.Send(event("3", "IssuesEvent", "closed", "bob", "org/api"))
+
+	server := httptest.NewServer(NewHandler(hub, 20*time.Millisecond))
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL+"/events?type=IssuesEvent", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	r := bufio.NewReader(resp.Body)
+	got, _, _ := readSSE(t, r, 1)
+	if len(got) != 1 || got[0] != "3" {
+		t.Fatalf("backlog ids = %v, want [3]", got)
+	}
+
+	// Give the heartbeat a chance to fire before the live event
+	time.Sleep(50 * time.Millisecond)
+	hub.Send(event("4", "ReleaseEvent", "published", "alice", "org/api"))
+	hub.Send(event("5", "IssuesEvent", "opened", "carol", "org/api"))
+	got, events, heartbeats := readSSE(t, r, 1)
+	if len(got) != 1 || got[0] != "5" || events[0].Actor.Login != "carol" {
+		t.Errorf("live ids = %v, want [5]", got)
+	}
+	if heartbeats == 0 {
+		t.Error("expected at least one heartbeat")
+	}
+}
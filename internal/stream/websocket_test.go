@@ -0,0 +1,120 @@
+package stream
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+func TestWebsocketAccept(t *testing.T) {
+	// Example from RFC 6455 section 1.3
+	if got := websocketAccept("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("websocketAccept() = %q", got)
+	}
+}
+
+func readServerFrame(t *testing.T, r *bufio.Reader) (byte, []byte) {
+	t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		t.Fatalf("failed to read frame: %v", err)
+	}
+	length := int(head[1] & 0x7F)
+	if length == 126 {
+		var ext [2]byte
+		io.ReadFull(r, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		t.Fatalf("failed to read payload: %v", err)
+	}
+	return head[0] & 0x0F, payload
+}
+
+func writeClientFrame(conn net.Conn, opcode byte, payload []byte) {
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{0x80 | opcode, 0x80 | byte(len(payload))}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	conn.Write(frame)
+}
+
+func TestServeWebSocket(t *testing.T) {
+	hub := NewHub(10)
+	hub.Send(event("1", "IssuesEvent", "opened", "alice", "org/api"))
+	hub.Send(event("2", "IssuesEvent", "closed", "alice", "org/api"))
+
+	server := httptest.NewServer(NewHandler(hub, time.Hour))
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	key := base64.StdEncoding.EncodeToString(nonce)
+	fmt.Fprintf(conn, "GET /events?last_event_id=1 HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\n"+
+		"Connection: Upgrade\r\nSec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n\r\n", key)
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatalf("failed to read handshake: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != websocketAccept(key) {
+		t.Fatal("invalid Sec-WebSocket-Accept")
+	}
+
+	opcode, payload := readServerFrame(t, r)
+	var e watcher.Event
+	if opcode != opText || json.Unmarshal(payload, &e) != nil || e.ID != "2" {
+		t.Fatalf("expected resumed event 2, got opcode %d payload %s", opcode, payload)
+	}
+
+	writeClientFrame(conn, opPing, []byte("hi"))
+	if opcode, payload := readServerFrame(t, r); opcode != opPong || string(payload) != "hi" {
+		t.Errorf("expected pong, got opcode %d payload %q", opcode, payload)
+	}
+
+	hub.Send(event("3", "IssuesEvent", "reopened", "bob", "org/api"))
+	opcode, payload = readServerFrame(t, r)
+	if opcode != opText || json.Unmarshal(payload, &e) != nil || e.ID != "3" {
+		t.Errorf("expected live event 3, got opcode %d payload %s", opcode, payload)
+	}
+
+	writeClientFrame(conn, opClose, nil)
+	if opcode, _ := readServerFrame(t, r); opcode != opClose {
+		t.Errorf("expected close frame, got opcode %d", opcode)
+	}
+}
+
+func TestServeWebSocketRejectsPlainRequest(t *testing.T) {
+	hub := NewHub(10)
+	rec := httptest.NewRecorder()
+	NewHandler(hub, 0).ServeWebSocket(rec, httptest.NewRequest("GET", "/events", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,28 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package term
+
+import "errors"
+
+// State is a saved terminal mode
+type State struct{}
+
+// IsTerminal reports false; terminals are not supported on this platform
+func IsTerminal(fd uintptr) bool {
+	return false
+}
+
+// MakeRaw fails; raw mode is not supported on this platform
+func MakeRaw(fd uintptr) (*State, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// Restore does nothing
+func Restore(fd uintptr, state *State) error {
+	return nil
+}
+
+// Size fails; the terminal size is not available on this platform
+func Size(fd uintptr) (int, int, error) {
+	return 0, 0, errors.ErrUnsupported
+}
@@ -0,0 +1,21 @@
+package term
+
+import (
+	"os"
+	"testing"
+)
+
+func TestIsTerminal(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	if IsTerminal(r.Fd()) {
+		t.Error("a pipe is not a terminal")
+	}
+	if _, _, err := Size(w.Fd()); err == nil {
+		t.Error("a pipe has no size")
+	}
+}
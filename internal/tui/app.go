@@ -0,0 +1,177 @@
+// Package tui is a full-screen terminal interface for the event stream, drawn with plain ANSI escapes.
+package tui
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// Terminal is where the UI reads keys from and draws to; *TTY is the real one
+type Terminal interface {
+	io.Reader
+	io.Writer
+	// Size returns the width and height in columns and rows
+	Size() (int, int, error)
+}
+
+// Screen control sequences
+const (
+	enterScreen = "\x1b[?1049h\x1b[?25l"
+	leaveScreen = "\x1b[?25h\x1b[?1049l"
+)
+
+// refreshInterval is how often the screen is redrawn to update countdowns and pick up resizes
+const refreshInterval = time.Second
+
+// App runs the UI on a terminal and receives events as a sink
+type App struct {
+	term   Terminal
+	status func() []watcher.PollerStatus
+	model  *Model
+
+	events  chan watcher.Event
+	notices chan notice
+	done    chan struct{}
+}
+
+// notice is text written to the App while it runs
+type notice struct {
+	text string
+	bell bool
+}
+
+// New creates a UI on term; status supplies the poller states for the status bar and may be nil
+func New(term Terminal, status func() []watcher.PollerStatus) *App {
+	return &App{
+		term:    term,
+		status:  status,
+		model:   NewModel(80, 24),
+		events:  make(chan watcher.Event, 100),
+		notices: make(chan notice, 100),
+		done:    make(chan struct{}),
+	}
+}
+
+// Send adds the event to the list; events sent after the UI has quit are dropped
+func (a *App) Send(e watcher.Event) error {
+	select {
+	case a.events <- e:
+	case <-a.done:
+	}
+	return nil
+}
+
+// Write shows the last line of p on the status bar instead of writing over the screen, and
+// rings the bell for bell characters, so warnings can be logged to the App while it runs.
+// Text written after the UI has quit, or faster than it is drawn, is dropped.
+func (a *App) Write(p []byte) (int, error) {
+	text := string(p)
+	n := notice{bell: strings.Contains(text, "\a")}
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(text, "\a", "")), "\n")
+	n.text = strings.TrimSpace(lines[len(lines)-1])
+	select {
+	case a.notices <- n:
+	case <-a.done:
+	default:
+	}
+	return len(p), nil
+}
+
+// Close does nothing; Run restores the screen
+func (a *App) Close() error {
+	return nil
+}
+
+// Run draws the UI and handles keys until the user quits or ctx is canceled
+func (a *App) Run(ctx context.Context) error {
+	defer close(a.done)
+	io.WriteString(a.term, enterScreen)
+	defer io.WriteString(a.term, leaveScreen)
+
+	keys := make(chan []Key)
+	readErr := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 256)
+		for {
+			n, err := a.term.Read(buf)
+			if n > 0 {
+				select {
+				case keys <- DecodeKeys(buf[:n]):
+				case <-a.done:
+					return
+				}
+			}
+			if err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	for {
+		if err := a.draw(); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-readErr:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case e := <-a.events:
+			a.model.Add(e)
+			a.drain()
+		case n := <-a.notices:
+			if n.bell {
+				io.WriteString(a.term, "\a")
+			}
+			if n.text != "" {
+				a.model.Notify(n.text)
+			}
+		case pressed := <-keys:
+			for _, k := range pressed {
+				action := a.model.HandleKey(k)
+				if action.Quit {
+					return nil
+				}
+				if action.Copy != "" {
+					// OSC 52 asks the terminal to put the text on the clipboard
+					io.WriteString(a.term, "\x1b]52;c;"+base64.StdEncoding.EncodeToString([]byte(action.Copy))+"\a")
+				}
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain adds the events already queued so a burst is drawn once
+func (a *App) drain() {
+	for {
+		select {
+		case e := <-a.events:
+			a.model.Add(e)
+		default:
+			return
+		}
+	}
+}
+
+func (a *App) draw() error {
+	if w, h, err := a.term.Size(); err == nil && w > 0 && h > 0 {
+		a.model.Resize(w, h)
+	}
+	if a.status != nil {
+		a.model.SetStatus(a.status())
+	}
+	_, err := io.WriteString(a.term, a.model.Frame())
+	return err
+}
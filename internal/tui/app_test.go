@@ -0,0 +1,107 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTerminal feeds keys from a pipe and records what is drawn
+type fakeTerminal struct {
+	keys *io.PipeReader
+
+	mu  sync.Mutex
+	out bytes.Buffer
+}
+
+func (t *fakeTerminal) Read(p []byte) (int, error) { return t.keys.Read(p) }
+
+func (t *fakeTerminal) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.out.Write(p)
+}
+
+func (t *fakeTerminal) Size() (int, int, error) { return 80, 20, nil }
+
+func (t *fakeTerminal) output() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.out.String()
+}
+
+func TestApp(t *testing.T) {
+	r, w := io.Pipe()
+	term := &fakeTerminal{keys: r}
+	app := New(term, nil)
+
+	done := make(chan error, 1)
+	go func() { done <- app.Run(context.Background()) }()
+
+	app.Send(issueEvent("o/api", 1, "Login fails", ""))
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(term.output(), "Login fails") && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !strings.Contains(term.output(), "Login fails") {
+		t.Fatal("event was not drawn")
+	}
+
+	w.Write([]byte("o"))
+	w.Write([]byte("q"))
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("q did not quit")
+	}
+
+	out := term.output()
+	if !strings.HasPrefix(out, enterScreen) || !strings.HasSuffix(out, leaveScreen) {
+		t.Error("screen was not entered and restored")
+	}
+	if !strings.Contains(out, "\x1b]52;c;aHR0cHM6Ly9naXRodWIuY29tL28vYXBpL2lzc3Vlcy8x\a") {
+		t.Error("URL was not copied with OSC 52")
+	}
+
+	// Sending after quitting must not block
+	app.Send(issueEvent("o/api", 2, "Late", ""))
+}
+
+func TestAppWrite(t *testing.T) {
+	r, w := io.Pipe()
+	term := &fakeTerminal{keys: r}
+	app := New(term, nil)
+
+	// Logged before the UI starts, shown once it does
+	fmt.Fprintf(app, "sink error: %v\n", io.ErrUnexpectedEOF)
+	done := make(chan error, 1)
+	go func() { done <- app.Run(context.Background()) }()
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(term.output(), "sink error: unexpected EOF") && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !strings.Contains(term.output(), "sink error: unexpected EOF") {
+		t.Fatal("logged text was not shown on the status bar")
+	}
+
+	app.Write([]byte("\a"))
+	for !strings.Contains(term.output(), "\a") && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	w.Write([]byte("q"))
+	<-done
+	if out := term.output(); strings.Count(out, "\a") != 1 || strings.Contains(out, "EOF\n") {
+		t.Errorf("output = %q", out)
+	}
+
+	// Writing after quitting must not block
+	app.Write([]byte("late\n"))
+}
@@ -0,0 +1,87 @@
+package tui
+
+import "unicode/utf8"
+
+// Key is a key press: a printable rune, or a named key such as "up" or "enter"
+type Key struct {
+	Rune rune
+	Name string
+}
+
+// String returns the key's name, or its rune for printable keys
+func (k Key) String() string {
+	if k.Name != "" {
+		return k.Name
+	}
+	return string(k.Rune)
+}
+
+// escapes maps the escape sequences of special keys to their names
+var escapes = map[string]string{
+	"\x1b[A":  "up",
+	"\x1b[B":  "down",
+	"\x1b[C":  "right",
+	"\x1b[D":  "left",
+	"\x1bOA":  "up",
+	"\x1bOB":  "down",
+	"\x1bOC":  "right",
+	"\x1bOD":  "left",
+	"\x1b[H":  "home",
+	"\x1b[F":  "end",
+	"\x1b[1~": "home",
+	"\x1b[4~": "end",
+	"\x1b[5~": "pgup",
+	"\x1b[6~": "pgdown",
+	"\x1b[Z":  "shift-tab",
+}
+
+// controls names the control characters the UI uses
+var controls = map[byte]string{
+	'\r':   "enter",
+	'\n':   "enter",
+	'\t':   "tab",
+	0x7f:   "backspace",
+	0x08:   "backspace",
+	0x03:   "ctrl-c",
+	0x04:   "ctrl-d",
+	0x15:   "ctrl-u",
+	0x0c:   "ctrl-l",
+	'\x1b': "esc",
+}
+
+// DecodeKeys splits input read from the terminal into key presses.
+// An escape byte that does not start a known sequence is the escape key.
+func DecodeKeys(buf []byte) []Key {
+	var keys []Key
+	for len(buf) > 0 {
+		if buf[0] == '\x1b' && len(buf) > 1 {
+			if name, n := matchEscape(buf); n > 0 {
+				keys = append(keys, Key{Name: name})
+				buf = buf[n:]
+				continue
+			}
+		}
+		if name, ok := controls[buf[0]]; ok {
+			keys = append(keys, Key{Name: name})
+			buf = buf[1:]
+			continue
+		}
+		r, n := utf8.DecodeRune(buf)
+		buf = buf[n:]
+		if r == utf8.RuneError || r < ' ' {
+			continue
+		}
+		keys = append(keys, Key{Rune: r})
+	}
+	return keys
+}
+
+// matchEscape returns the name and length of the escape sequence at the start of buf
+func matchEscape(buf []byte) (string, int) {
+	for seq, name := range escapes {
+		if len(buf) >= len(seq) && string(buf[:len(seq)]) == seq {
+			return name, len(seq)
+		}
+	}
+	return "", 0
+}
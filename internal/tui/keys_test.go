@@ -0,0 +1,18 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeKeys(t *testing.T) {
+	keys := DecodeKeys([]byte("j\x1b[A\x1b[6~\x1b\r\x7fé\x03\x1b[Z"))
+	var got []string
+	for _, k := range keys {
+		got = append(got, k.String())
+	}
+	want := "j up pgdown esc enter backspace é ctrl-c shift-tab"
+	if strings.Join(got, " ") != want {
+		t.Errorf("DecodeKeys = %q, want %q", strings.Join(got, " "), want)
+	}
+}
@@ -0,0 +1,525 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/markdown"
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+// maxEntries bounds the events kept in the list; the oldest are dropped first
+const maxEntries = 1000
+
+// ANSI escapes used by the view
+const (
+	ansiReset   = "\x1b[0m"
+	ansiBold    = "\x1b[1m"
+	ansiReverse = "\x1b[7m"
+	ansiRed     = "\x1b[31m"
+	ansiCyan    = "\x1b[36m"
+)
+
+// entry is an event in the list
+type entry struct {
+	event watcher.Event
+	read  bool
+}
+
+// Action is what the caller must do after a key press
+type Action struct {
+	// Quit ends the UI
+	Quit bool
+	// Copy is a URL to put on the clipboard
+	Copy string
+}
+
+// Model is the state of the terminal UI. It has no I/O so it can be driven by tests.
+type Model struct {
+	width, height int
+	now           func() time.Time
+
+	entries []*entry
+	repos   []string
+	status  []watcher.PollerStatus
+
+	tab       int
+	cursor    int
+	offset    int
+	detail    int
+	filter    string
+	filtering bool
+	message   string
+}
+
+// NewModel creates an empty model for a terminal of the given size
+func NewModel(width, height int) *Model {
+	return &Model{width: width, height: height, now: time.Now}
+}
+
+// Resize sets the terminal size
+func (m *Model) Resize(width, height int) {
+	m.width, m.height = width, height
+	m.clamp()
+}
+
+// SetStatus sets the poller states shown in the status bar; their repositories get tabs
+func (m *Model) SetStatus(status []watcher.PollerStatus) {
+	m.status = status
+	for _, s := range status {
+		m.addRepo(s.Repo)
+	}
+}
+
+func (m *Model) addRepo(repo string) {
+	if repo == "" {
+		return
+	}
+	i := sort.SearchStrings(m.repos, repo)
+	if i < len(m.repos) && m.repos[i] == repo {
+		return
+	}
+	m.repos = append(m.repos, "")
+	copy(m.repos[i+1:], m.repos[i:])
+	m.repos[i] = repo
+}
+
+// Notify shows a message on the status bar until the next key press
+func (m *Model) Notify(msg string) {
+	m.message = msg
+}
+
+// Add appends a new, unread event. The selection stays on the same event.
+func (m *Model) Add(e watcher.Event) {
+	en := &entry{event: e}
+	m.entries = append(m.entries, en)
+	if len(m.entries) > maxEntries {
+		m.entries = m.entries[len(m.entries)-maxEntries:]
+	}
+	m.addRepo(e.Repo.Name)
+	if m.cursor > 0 && m.visibleEntry(en) {
+		m.cursor++
+		m.offset++
+	}
+	m.clamp()
+}
+
+// tabs returns the tab names; the first tab shows every repository
+func (m *Model) tabs() []string {
+	return append([]string{"All"}, m.repos...)
+}
+
+// visibleEntry reports whether the entry belongs to the current tab and matches the filter
+func (m *Model) visibleEntry(en *entry) bool {
+	if m.tab > 0 && en.event.Repo.Name != m.repos[m.tab-1] {
+		return false
+	}
+	if m.filter == "" {
+		return true
+	}
+	text := strings.Join([]string{headline(en.event), en.event.Actor.Login, en.event.Repo.Name, en.event.Body()}, " ")
+	return strings.Contains(strings.ToLower(text), strings.ToLower(m.filter))
+}
+
+// visible returns the entries shown in the list, newest first
+func (m *Model) visible() []*entry {
+	var list []*entry
+	for i := len(m.entries) - 1; i >= 0; i-- {
+		if m.visibleEntry(m.entries[i]) {
+			list = append(list, m.entries[i])
+		}
+	}
+	return list
+}
+
+// selected returns the entry under the cursor, or nil
+func (m *Model) selected() *entry {
+	list := m.visible()
+	if m.cursor < len(list) {
+		return list[m.cursor]
+	}
+	return nil
+}
+
+// unread counts the unread entries of a tab
+func (m *Model) unread(tab int) int {
+	n := 0
+	for _, en := range m.entries {
+		if !en.read && (tab == 0 || en.event.Repo.Name == m.repos[tab-1]) {
+			n++
+		}
+	}
+	return n
+}
+
+// layout returns the heights of the list and detail panes
+func (m *Model) layout() (int, int) {
+	rows := m.height - 3 // tab bar, divider, status bar
+	list := rows * 2 / 5
+	if list < 3 {
+		list = 3
+	}
+	detail := rows - list
+	if detail < 0 {
+		detail = 0
+	}
+	return list, detail
+}
+
+// clamp keeps the cursor and scroll positions in range
+func (m *Model) clamp() {
+	if m.tab >= len(m.tabs()) {
+		m.tab = 0
+	}
+	n := len(m.visible())
+	if m.cursor >= n {
+		m.cursor = n - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+	listHeight, _ := m.layout()
+	if m.offset > m.cursor {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+listHeight {
+		m.offset = m.cursor - listHeight + 1
+	}
+	if m.offset < 0 {
+		m.offset = 0
+	}
+}
+
+func (m *Model) move(delta int) {
+	m.cursor += delta
+	m.detail = 0
+	m.clamp()
+}
+
+func (m *Model) switchTab(tab int) {
+	tabs := len(m.tabs())
+	m.tab = (tab%tabs + tabs) % tabs
+	m.cursor, m.offset, m.detail = 0, 0, 0
+	m.clamp()
+}
+
+// HandleKey applies a key press
+func (m *Model) HandleKey(k Key) Action {
+	m.message = ""
+	if m.filtering {
+		switch k.Name {
+		case "enter":
+			m.filtering = false
+		case "esc":
+			m.filtering = false
+			m.filter = ""
+		case "backspace":
+			if r := []rune(m.filter); len(r) > 0 {
+				m.filter = string(r[:len(r)-1])
+			}
+		case "ctrl-u":
+			m.filter = ""
+		case "ctrl-c":
+			return Action{Quit: true}
+		case "":
+			m.filter += string(k.Rune)
+		}
+		m.cursor, m.offset, m.detail = 0, 0, 0
+		m.clamp()
+		return Action{}
+	}
+
+	listHeight, detailHeight := m.layout()
+	switch k.String() {
+	case "q", "ctrl-c":
+		return Action{Quit: true}
+	case "j", "down":
+		m.move(1)
+	case "k", "up":
+		m.move(-1)
+	case "g", "home":
+		m.move(-m.cursor)
+	case "G", "end":
+		m.move(len(m.visible()))
+	case "pgdown", "ctrl-d":
+		m.move(listHeight)
+	case "pgup", "ctrl-u":
+		m.move(-listHeight)
+	case "tab", "l", "right":
+		m.switchTab(m.tab + 1)
+	case "shift-tab", "h", "left":
+		m.switchTab(m.tab - 1)
+	case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+		if tab := int(k.Rune - '1'); tab < len(m.tabs()) {
+			m.switchTab(tab)
+		}
+	case "/":
+		m.filtering = true
+	case "esc":
+		m.filter = ""
+		m.clamp()
+	case "m":
+		if en := m.selected(); en != nil {
+			en.read = !en.read
+		}
+	case "M":
+		for _, en := range m.visible() {
+			en.read = true
+		}
+	case " ", "J":
+		m.detail += detailHeight - 1
+	case "K":
+		m.detail -= detailHeight - 1
+		if m.detail < 0 {
+			m.detail = 0
+		}
+	case "o", "enter":
+		en := m.selected()
+		if en == nil {
+			break
+		}
+		url := en.event.HTMLURL()
+		if url == "" {
+			m.message = "No URL for this event"
+			break
+		}
+		en.read = true
+		m.message = "Copied " + url
+		return Action{Copy: url}
+	}
+	return Action{}
+}
+
+// View renders the whole screen, one string per terminal row
+func (m *Model) View() []string {
+	if m.width < 20 || m.height < 6 {
+		return []string{clip("Terminal too small", m.width)}
+	}
+	listHeight, detailHeight := m.layout()
+	rows := []string{m.tabBar()}
+
+	list := m.visible()
+	for i := 0; i < listHeight; i++ {
+		idx := m.offset + i
+		if idx >= len(list) {
+			if idx == 0 && i == 0 {
+				if m.filter != "" {
+					rows = append(rows, " No matching events")
+				} else {
+					rows = append(rows, " No events yet")
+				}
+				continue
+			}
+			rows = append(rows, "")
+			continue
+		}
+		rows = append(rows, m.listRow(list[idx], idx == m.cursor))
+	}
+
+	rows = append(rows, strings.Repeat("─", m.width))
+	detail := m.detailLines()
+	if m.detail > len(detail)-1 {
+		m.detail = max(len(detail)-1, 0)
+	}
+	for i := 0; i < detailHeight; i++ {
+		if m.detail+i < len(detail) {
+			rows = append(rows, clip(detail[m.detail+i], m.width))
+		} else {
+			rows = append(rows, "")
+		}
+	}
+	rows = append(rows, m.statusBar())
+	return rows
+}
+
+// Frame renders the screen as terminal output that repaints it from the top-left corner
+func (m *Model) Frame() string {
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	for i, row := range m.View() {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(row + "\x1b[K")
+	}
+	b.WriteString("\x1b[J")
+	return b.String()
+}
+
+func (m *Model) tabBar() string {
+	var b strings.Builder
+	used := 0
+	for i, name := range m.tabs() {
+		label := " " + name + " "
+		if n := m.unread(i); n > 0 {
+			label = fmt.Sprintf(" %s (%d) ", name, n)
+		}
+		if used+markdown.Width(label) > m.width {
+			break
+		}
+		used += markdown.Width(label)
+		if i == m.tab {
+			label = ansiReverse + label + ansiReset
+		}
+		b.WriteString(label)
+	}
+	return b.String()
+}
+
+func (m *Model) listRow(en *entry, selected bool) string {
+	marker := " "
+	if !en.read {
+		marker = "●"
+	}
+	alert := " "
+	if len(en.event.Alerts) > 0 {
+		alert = "!"
+	}
+	text := fmt.Sprintf("%s%s %s %s", marker, alert, en.event.CreatedAt.Format("01-02 15:04"), headline(en.event))
+	if m.tab == 0 && len(m.repos) > 1 {
+		text = fmt.Sprintf("%s%s %s %s %s", marker, alert, en.event.CreatedAt.Format("01-02 15:04"), en.event.Repo.Name, headline(en.event))
+	}
+	text = pad(clip(text, m.width), m.width)
+	switch {
+	case selected:
+		return ansiReverse + text + ansiReset
+	case len(en.event.Alerts) > 0:
+		return ansiRed + text + ansiReset
+	case !en.read:
+		return ansiBold + text + ansiReset
+	}
+	return text
+}
+
+func (m *Model) detailLines() []string {
+	en := m.selected()
+	if en == nil {
+		return nil
+	}
+	e := en.event
+	lines := []string{ansiBold + headline(e) + ansiReset}
+	for _, a := range e.Alerts {
+		lines = append(lines, ansiRed+fmt.Sprintf("[ALERT %s] %s", a.Severity, a.Rule)+ansiReset)
+	}
+	if url := e.HTMLURL(); url != "" {
+		lines = append(lines, ansiCyan+url+ansiReset)
+	}
+	if body := strings.TrimSpace(e.Body()); body != "" {
+		lines = append(lines, "")
+		lines = append(lines, strings.Split(markdown.Render(body, markdown.Options{Width: m.width, Color: true}), "\n")...)
+	}
+	return lines
+}
+
+func (m *Model) statusBar() string {
+	left := " q quit  / filter  m read  o copy URL  tab repos"
+	switch {
+	case m.filtering:
+		left = " /" + m.filter + "_"
+	case m.message != "":
+		left = " " + m.message
+	case m.filter != "":
+		left = " filter: " + m.filter + "  (esc clears)"
+	}
+
+	var right []string
+	if n := m.unread(0); n > 0 {
+		right = append(right, fmt.Sprintf("%d unread", n))
+	}
+	right = append(right, m.pollStatus()...)
+	// The poller state wins over the key help when space runs out
+	text := left
+	r := strings.Join(right, " · ") + " "
+	if avail := m.width - markdown.Width(r); avail > 1 {
+		text = pad(clip(left, avail-1), avail) + r
+	}
+	return ansiReverse + pad(clip(text, m.width), m.width) + ansiReset
+}
+
+// pollStatus summarizes the pollers: the lowest known rate limit, the next poll and errors
+func (m *Model) pollStatus() []string {
+	var parts []string
+	rate := -1
+	var next time.Time
+	errors := 0
+	for _, s := range m.status {
+		if s.RateLimitRemaining >= 0 && (rate < 0 || s.RateLimitRemaining < rate) {
+			rate = s.RateLimitRemaining
+		}
+		if !s.NextPoll.IsZero() && (next.IsZero() || s.NextPoll.Before(next)) {
+			next = s.NextPoll
+		}
+		if s.ErrorStreak > 0 {
+			errors++
+		}
+	}
+	if rate >= 0 {
+		parts = append(parts, fmt.Sprintf("rate limit %d", rate))
+	}
+	if !next.IsZero() {
+		if d := next.Sub(m.now()); d > 0 {
+			parts = append(parts, "next poll in "+d.Round(time.Second).String())
+		} else {
+			parts = append(parts, "polling")
+		}
+	}
+	if errors > 0 {
+		parts = append(parts, fmt.Sprintf("%d failing", errors))
+	}
+	return parts
+}
+
+// headline is the formatted event's first line without its timestamp
+func headline(e watcher.Event) string {
+	line, _, _ := strings.Cut(watcher.FormatEvent(e), "\n")
+	if _, after, ok := strings.Cut(line, "] "); ok && strings.HasPrefix(line, "[") {
+		return after
+	}
+	return line
+}
+
+// clip cuts text to width columns, keeping ANSI escapes intact and ending with … when cut
+func clip(text string, width int) string {
+	if markdown.Width(text) <= width {
+		return text
+	}
+	var b strings.Builder
+	cols := 0
+	styled := false
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] == '\x1b' {
+			j := i
+			for j < len(runes) && runes[j] != 'm' {
+				j++
+			}
+			if j == len(runes) {
+				break
+			}
+			b.WriteString(string(runes[i : j+1]))
+			styled = true
+			i = j
+			continue
+		}
+		if cols == width-1 {
+			b.WriteString("…")
+			break
+		}
+		b.WriteRune(runes[i])
+		cols++
+	}
+	if styled {
+		b.WriteString(ansiReset)
+	}
+	return b.String()
+}
+
+// pad fills text with spaces to width columns
+func pad(text string, width int) string {
+	if n := width - markdown.Width(text); n > 0 {
+		return text + strings.Repeat(" ", n)
+	}
+	return text
+}
@@ -0,0 +1,139 @@
+package tui
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/watcher"
+)
+
+var ansi = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// screen returns the view without ANSI escapes
+func screen(m *Model) []string {
+	rows := m.View()
+	for i, row := range rows {
+		rows[i] = strings.TrimRight(ansi.ReplaceAllString(row, ""), " ")
+	}
+	return rows
+}
+
+func issueEvent(repo string, number int, title, body string) watcher.Event {
+	return watcher.Event{
+		Type:      "IssuesEvent",
+		Actor:     watcher.Actor{Login: "alice"},
+		Repo:      watcher.Repo{Name: repo},
+		CreatedAt: time.Date(2024, 5, 1, 10, number, 0, 0, time.UTC),
+		Payload: watcher.Payload{Action: "opened", Issue: &watcher.Issue{
+			Number: number, Title: title, Body: body, HTMLURL: "https://github.com/" + repo + "/issues/1",
+		}},
+	}
+}
+
+func keys(m *Model, input string) Action {
+	var last Action
+	for _, k := range DecodeKeys([]byte(input)) {
+		last = m.HandleKey(k)
+	}
+	return last
+}
+
+func TestModelView(t *testing.T) {
+	m := NewModel(60, 14)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	m.SetStatus([]watcher.PollerStatus{{Repo: "o/api", RateLimitRemaining: 4321, NextPoll: now.Add(42 * time.Second)}})
+	m.Add(issueEvent("o/api", 1, "Login fails", "## Steps\n\n- open the **app**"))
+	m.Add(issueEvent("o/web", 2, "Button color", ""))
+
+	rows := screen(m)
+	if len(rows) != 14 {
+		t.Fatalf("expected 14 rows, got %d", len(rows))
+	}
+	if rows[0] != " All (2)  o/api (1)  o/web (1)" {
+		t.Errorf("tab bar = %q", rows[0])
+	}
+	if !strings.HasPrefix(rows[1], "●  05-01 10:02 o/web Issue opened: #2") || !strings.HasPrefix(rows[2], "●  05-01 10:01 o/api Issue opened: #1") {
+		t.Errorf("unexpected list:\n%s\n%s", rows[1], rows[2])
+	}
+	if !strings.Contains(rows[13], "2 unread · rate limit 4321 · next poll in 42s") {
+		t.Errorf("status bar = %q", rows[13])
+	}
+
+	keys(m, "j")
+	rows = screen(m)
+	detail := strings.Join(rows[6:13], "\n")
+	if !strings.Contains(detail, "Issue opened: #1 \"Login fails\" by @alice") || !strings.Contains(detail, "Steps") || !strings.Contains(detail, "• open the app") {
+		t.Errorf("unexpected detail pane:\n%s", detail)
+	}
+}
+
+func TestModelKeys(t *testing.T) {
+	m := NewModel(60, 14)
+	m.Add(issueEvent("o/api", 1, "Login fails", ""))
+	m.Add(issueEvent("o/web", 2, "Button color", ""))
+	m.Add(issueEvent("o/api", 3, "Slow query", ""))
+
+	// Tabs narrow the list to one repository
+	keys(m, "\t")
+	if n := len(m.visible()); n != 2 {
+		t.Errorf("o/api tab shows %d events, want 2", n)
+	}
+	keys(m, "3")
+	if n := len(m.visible()); n != 1 || m.visible()[0].event.Repo.Name != "o/web" {
+		t.Errorf("o/web tab shows %d events", n)
+	}
+	keys(m, "1")
+
+	// Filtering narrows as you type and esc clears it
+	keys(m, "/log")
+	if !m.filtering || len(m.visible()) != 1 {
+		t.Errorf("filter %q shows %d events", m.filter, len(m.visible()))
+	}
+	keys(m, "\r")
+	if m.filtering || m.filter != "log" {
+		t.Error("enter should keep the filter and leave filter mode")
+	}
+	keys(m, "\x1b")
+	if m.filter != "" || len(m.visible()) != 3 {
+		t.Error("esc should clear the filter")
+	}
+
+	// Marking read and copying the URL
+	keys(m, "jm")
+	if !m.visible()[1].read || m.unread(0) != 2 {
+		t.Error("m should mark the selected event read")
+	}
+	keys(m, "M")
+	if m.unread(0) != 0 {
+		t.Error("M should mark every event read")
+	}
+	if a := keys(m, "o"); a.Copy != "https://github.com/o/web/issues/1" || m.message == "" {
+		t.Errorf("o copied %q", a.Copy)
+	}
+	if a := keys(m, "q"); !a.Quit {
+		t.Error("q should quit")
+	}
+}
+
+func TestModelKeepsSelection(t *testing.T) {
+	m := NewModel(60, 14)
+	m.Add(issueEvent("o/api", 1, "First", ""))
+	m.Add(issueEvent("o/api", 2, "Second", ""))
+	keys(m, "j")
+	m.Add(issueEvent("o/api", 3, "Third", ""))
+	if got := m.selected().event.Title(); got != "First" {
+		t.Errorf("selection moved to %q", got)
+	}
+}
+
+func TestClip(t *testing.T) {
+	if got := clip("\x1b[1mhello world\x1b[0m", 6); got != "\x1b[1mhello…\x1b[0m" {
+		t.Errorf("clip = %q", got)
+	}
+	if got := clip("short", 10); got != "short" {
+		t.Errorf("clip = %q", got)
+	}
+}
@@ -0,0 +1,48 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ytnobody/ghostio/internal/term"
+)
+
+// TTY is the controlling terminal in raw mode
+type TTY struct {
+	in, out *os.File
+	saved   *term.State
+}
+
+// Open puts the terminal on stdin into raw mode; Close restores it
+func Open() (*TTY, error) {
+	t := &TTY{in: os.Stdin, out: os.Stdout}
+	if !term.IsTerminal(t.in.Fd()) {
+		return nil, fmt.Errorf("the terminal UI needs stdin to be a terminal")
+	}
+	saved, err := term.MakeRaw(t.in.Fd())
+	if err != nil {
+		return nil, fmt.Errorf("failed to enter raw mode: %w", err)
+	}
+	t.saved = saved
+	return t, nil
+}
+
+// Read reads key presses
+func (t *TTY) Read(p []byte) (int, error) {
+	return t.in.Read(p)
+}
+
+// Write writes to the terminal
+func (t *TTY) Write(p []byte) (int, error) {
+	return t.out.Write(p)
+}
+
+// Size returns the terminal width and height
+func (t *TTY) Size() (int, int, error) {
+	return term.Size(t.out.Fd())
+}
+
+// Close restores the terminal mode saved by Open
+func (t *TTY) Close() error {
+	return term.Restore(t.in.Fd(), t.saved)
+}
@@ -0,0 +1,219 @@
+package watcher
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// issueEventsPageSize is the page size used when paging through issue events by hand
+const issueEventsPageSize = 100
+
+// IssueEvent is an entry of the issue events API: a change to an issue or pull request
+type IssueEvent struct {
+	ID        int64     `json:"id"`
+	Event     string    `json:"event"`
+	Actor     Actor     `json:"actor"`
+	CreatedAt time.Time `json:"created_at"`
+	Issue     Issue     `json:"issue"`
+}
+
+// IssueComment is a comment from the repository-wide comments API, which names its issue by URL
+type IssueComment struct {
+	Comment
+	IssueURL string `json:"issue_url"`
+}
+
+// number returns the issue number at the end of the comment's issue URL, or zero
+func (c IssueComment) number() int {
+	n, _ := strconv.Atoi(c.IssueURL[strings.LastIndex(c.IssueURL, "/")+1:])
+	return n
+}
+
+// PublishedRelease is a release from the releases API
+type PublishedRelease struct {
+	Release
+	ID          int64      `json:"id"`
+	Author      Actor      `json:"author"`
+	Draft       bool       `json:"draft"`
+	PublishedAt *time.Time `json:"published_at"`
+}
+
+// combine keeps the feed events since a time, oldest first, and adds backfilled events
+// for the range the feed does not reach: from since up to its oldest event
+func combine(feed []Event, since time.Time, backfill func(until time.Time) ([]Event, error)) ([]Event, error) {
+	var events []Event
+	for _, e := range feed {
+		if !e.CreatedAt.Before(since) {
+			events = append(events, e)
+		}
+	}
+	var until time.Time
+	for _, e := range feed {
+		if until.IsZero() || e.CreatedAt.Before(until) {
+			until = e.CreatedAt
+		}
+	}
+	if until.IsZero() || until.After(since) {
+		older, err := backfill(until)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, older...)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].CreatedAt.Before(events[j].CreatedAt)
+	})
+	return events, nil
+}
+
+// backfill fetches what happened between since and until, or until now when until is zero
+func (f *Fetcher) backfill(since, until time.Time) ([]Event, error) {
+	stamp := url.QueryEscape(since.UTC().Format(time.RFC3339))
+	var issues []Issue
+	if err := f.get(fmt.Sprintf("repos/%s/issues?state=all&since=%s&per_page=100", f.repo, stamp), true, &issues); err != nil {
+		return nil, err
+	}
+	var comments []IssueComment
+	if err := f.get(fmt.Sprintf("repos/%s/issues/comments?since=%s&per_page=100", f.repo, stamp), true, &comments); err != nil {
+		return nil, err
+	}
+	// Reviews are only listed per pull request; any reviewed since then was updated since then.
+	// Each pull request costs a request, so only the most recently updated are asked.
+	reviews := map[int][]Review{}
+	var prs []Issue
+	for _, issue := range issues {
+		if issue.IsPullRequest() {
+			prs = append(prs, issue)
+		}
+	}
+	sort.SliceStable(prs, func(i, j int) bool { return prs[i].UpdatedAt.After(prs[j].UpdatedAt) })
+	limit := f.ReviewLimit
+	if limit == 0 {
+		limit = DefaultReviewLimit
+	}
+	limit = max(limit, 0)
+	if len(prs) > limit {
+		log.Printf("backfill: fetching reviews of the %d most recently updated of %d pull requests in %s", limit, len(prs), f.repo)
+		prs = prs[:limit]
+	}
+	for _, issue := range prs {
+		var batch []Review
+		if err := f.get(fmt.Sprintf("repos/%s/pulls/%d/reviews?per_page=100", f.repo, issue.Number), true, &batch); err != nil {
+			return nil, err
+		}
+		reviews[issue.Number] = batch
+	}
+	var releases []PublishedRelease
+	if err := f.get(fmt.Sprintf("repos/%s/releases?per_page=100", f.repo), false, &releases); err != nil {
+		return nil, err
+	}
+
+	// Issue events cannot be asked for by time, so pages are read until they are older
+	var changes []IssueEvent
+	for page := 1; ; page++ {
+		var batch []IssueEvent
+		path := fmt.Sprintf("repos/%s/issues/events?per_page=%d&page=%d", f.repo, issueEventsPageSize, page)
+		if err := f.get(path, false, &batch); err != nil {
+			return nil, err
+		}
+		changes = append(changes, batch...)
+		if len(batch) < issueEventsPageSize || batch[len(batch)-1].CreatedAt.Before(since) {
+			break
+		}
+	}
+	return Backfill(f.repo, since, until, issues, changes, comments, reviews, releases), nil
+}
+
+// Backfill rebuilds the events between since and until (unbounded when zero) from the
+// issues, issue events, comments, reviews and releases APIs, oldest first: issues and pull
+// requests opened, closed, reopened and merged, comments, submitted reviews and published
+// releases. reviews holds each pull request's reviews by number.
+func Backfill(repo string, since, until time.Time, issues []Issue, changes []IssueEvent, comments []IssueComment, reviews map[int][]Review, releases []PublishedRelease) []Event {
+	within := func(t time.Time) bool {
+		return !t.Before(since) && (until.IsZero() || t.Before(until))
+	}
+
+	var events []Event
+	byNumber := map[int]Issue{}
+	for _, issue := range issues {
+		byNumber[issue.Number] = issue
+		if within(issue.CreatedAt) {
+			events = append(events, FromIssue(repo, issue))
+		}
+	}
+
+	// A merge closes the pull request too; the two become one merged event, as in the feed
+	merged := map[int]time.Time{}
+	for _, c := range changes {
+		if c.Event == "merged" {
+			merged[c.Issue.Number] = c.CreatedAt
+		}
+	}
+	for _, c := range changes {
+		if (c.Event != "closed" && c.Event != "reopened") || !within(c.CreatedAt) {
+			continue
+		}
+		e := FromIssue(repo, c.Issue)
+		e.ID = "issue-event-" + strconv.FormatInt(c.ID, 10)
+		e.Actor = c.Actor
+		e.CreatedAt = c.CreatedAt
+		e.Payload.Action = c.Event
+		if pr := e.Payload.PullRequest; pr != nil && c.Event == "closed" {
+			if at, ok := merged[c.Issue.Number]; ok && at.Sub(c.CreatedAt).Abs() < time.Minute {
+				pr.Merged = true
+				pr.MergedAt = &at
+			}
+		}
+		events = append(events, e)
+	}
+
+	for _, c := range comments {
+		if !within(c.CreatedAt) {
+			continue
+		}
+		issue, ok := byNumber[c.number()]
+		if !ok {
+			issue = Issue{Number: c.number()}
+		}
+		events = append(events, FromComment(repo, issue, c.Comment))
+	}
+
+	for number, batch := range reviews {
+		for _, r := range batch {
+			// Pending reviews have not been submitted yet
+			if r.SubmittedAt.IsZero() || !within(r.SubmittedAt) {
+				continue
+			}
+			issue, ok := byNumber[number]
+			if !ok {
+				issue = Issue{Number: number}
+			}
+			events = append(events, FromReview(repo, issue, r))
+		}
+	}
+
+	for _, r := range releases {
+		if r.Draft || r.PublishedAt == nil || !within(*r.PublishedAt) {
+			continue
+		}
+		release := r.Release
+		events = append(events, Event{
+			ID:        "release-" + strconv.FormatInt(r.ID, 10),
+			Type:      "ReleaseEvent",
+			Actor:     r.Author,
+			Repo:      Repo{Name: repo},
+			Payload:   Payload{Action: "published", Release: &release},
+			CreatedAt: *r.PublishedAt,
+		})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].CreatedAt.Before(events[j].CreatedAt)
+	})
+	return events
+}
@@ -0,0 +1,134 @@
+package watcher
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func describe(events []Event) string {
+	var parts []string
+	for _, e := range events {
+		s := e.Type + ":" + e.Payload.Action
+		if e.Payload.PullRequest != nil && e.Payload.PullRequest.Merged {
+			s += ":merged"
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestBackfill(t *testing.T) {
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return base.Add(time.Duration(h) * time.Hour) }
+	bug := Issue{Number: 3, Title: "Crash", User: Actor{Login: "alice"}, CreatedAt: at(1)}
+	pr := Issue{Number: 4, Title: "Fix crash", User: Actor{Login: "bob"}, CreatedAt: at(-5),
+		PullRequest: &PullRequestLink{HTMLURL: "https://github.com/o/r/pull/4"}}
+	published := at(6)
+
+	events := Backfill("o/r", base, at(10),
+		[]Issue{bug, pr},
+		[]IssueEvent{
+			{ID: 1, Event: "merged", Actor: Actor{Login: "carol"}, CreatedAt: at(4), Issue: pr},
+			{ID: 2, Event: "closed", Actor: Actor{Login: "carol"}, CreatedAt: at(4), Issue: pr},
+			{ID: 3, Event: "labeled", CreatedAt: at(2), Issue: bug},
+			{ID: 4, Event: "closed", Actor: Actor{Login: "dave"}, CreatedAt: at(5), Issue: bug},
+			{ID: 5, Event: "reopened", CreatedAt: at(12), Issue: bug},
+		},
+		[]IssueComment{
+			{Comment: Comment{ID: 7, Body: "Seen it", CreatedAt: at(2)}, IssueURL: "https://api.github.com/repos/o/r/issues/3"},
+			{Comment: Comment{ID: 8, Body: "Old", CreatedAt: at(-1)}, IssueURL: "https://api.github.com/repos/o/r/issues/3"},
+		},
+		map[int][]Review{4: {
+			{ID: 9, State: "APPROVED", User: Actor{Login: "dave"}, SubmittedAt: at(3)},
+			{ID: 10, State: "PENDING", User: Actor{Login: "erin"}},
+		}},
+		[]PublishedRelease{
+			{Release: Release{TagName: "v1"}, ID: 11, Author: Actor{Login: "carol"}, PublishedAt: &published},
+			{Release: Release{TagName: "v2"}, Draft: true},
+		},
+	)
+
+	want := "IssuesEvent:opened IssueCommentEvent:created PullRequestReviewEvent:created PullRequestEvent:closed:merged IssuesEvent:closed ReleaseEvent:published"
+	if got := describe(events); got != want {
+		t.Fatalf("Backfill = %s\nwant %s", got, want)
+	}
+	if c := events[1]; c.Title() != "Crash" || c.Body() != "Seen it" {
+		t.Errorf("comment lost its issue: %q %q", c.Title(), c.Body())
+	}
+	if r := events[2]; r.Number() != 4 || r.Actor.Login != "dave" || r.ID != "review-9" {
+		t.Errorf("unexpected review event: %+v", r)
+	} else if p, err := r.ReviewPayload(); err != nil || p.Review.State != "APPROVED" || p.PullRequest.Title != "Fix crash" {
+		t.Errorf("review payload = %+v, %v", p, err)
+	}
+	if e := events[3]; e.Actor.Login != "carol" || e.ID != "issue-event-2" || !e.CreatedAt.Equal(at(4)) {
+		t.Errorf("unexpected merge event: %+v", e)
+	}
+	if r := events[5]; r.ID != "release-11" {
+		t.Errorf("release ID = %q", r.ID)
+	}
+}
+
+func TestBackfillReviewLimit(t *testing.T) {
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	var asked []string
+	f := NewFetcher("o/r")
+	f.ReviewLimit = 1
+	f.Transport = Transport(func(path, etag string) Exchange {
+		asked = append(asked, path)
+		out := "[]"
+		if strings.HasPrefix(path, "repos/o/r/issues?") {
+			out = `[{"number":1,"updated_at":"2024-05-01T11:00:00Z","pull_request":{}},{"number":2,"updated_at":"2024-05-01T12:00:00Z","pull_request":{}}]`
+		}
+		return Exchange{Path: path, Output: "HTTP/2.0 200 OK\r\n\r\n" + out}
+	})
+	if _, err := f.backfill(base, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	var reviews []string
+	for _, path := range asked {
+		if strings.Contains(path, "/reviews") {
+			reviews = append(reviews, path)
+		}
+	}
+	if len(reviews) != 1 || !strings.HasPrefix(reviews[0], "repos/o/r/pulls/2/") {
+		t.Errorf("review requests = %v", reviews)
+	}
+}
+
+func TestCombine(t *testing.T) {
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	feed := []Event{
+		{ID: "3", CreatedAt: base.Add(3 * time.Hour)},
+		{ID: "2", CreatedAt: base.Add(2 * time.Hour)},
+	}
+	var asked []time.Time
+	backfill := func(until time.Time) ([]Event, error) {
+		asked = append(asked, until)
+		return []Event{{ID: "1", CreatedAt: base.Add(time.Hour)}}, nil
+	}
+	ids := func(events []Event) string {
+		var s []string
+		for _, e := range events {
+			s = append(s, e.ID)
+		}
+		return strings.Join(s, " ")
+	}
+
+	// The feed covers the window
+	events, _ := combine(feed, base.Add(150*time.Minute), backfill)
+	if ids(events) != "3" || len(asked) != 0 {
+		t.Errorf("got %q, backfilled %v", ids(events), asked)
+	}
+	// Older ranges are backfilled up to the oldest feed event
+	events, _ = combine(feed, base, backfill)
+	if ids(events) != "1 2 3" || len(asked) != 1 || !asked[0].Equal(base.Add(2*time.Hour)) {
+		t.Errorf("got %q, backfilled until %v", ids(events), asked)
+	}
+	// An empty feed backfills everything
+	asked = nil
+	combine(nil, base, backfill)
+	if len(asked) != 1 || !asked[0].IsZero() {
+		t.Errorf("backfilled until %v, want unbounded", asked)
+	}
+}
@@ -0,0 +1,157 @@
+package watcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// webhookTypes maps webhook event names (the X-GitHub-Event header) to events API types
+var webhookTypes = map[string]string{
+	"issues":                      "IssuesEvent",
+	"issue_comment":               "IssueCommentEvent",
+	"pull_request":                "PullRequestEvent",
+	"pull_request_review":         "PullRequestReviewEvent",
+	"pull_request_review_comment": "PullRequestReviewCommentEvent",
+	"release":                     "ReleaseEvent",
+	"push":                        "PushEvent",
+	"create":                      "CreateEvent",
+	"delete":                      "DeleteEvent",
+	"fork":                        "ForkEvent",
+	"watch":                       "WatchEvent",
+}
+
+// webhookPayload is the envelope of a webhook delivery around the event payload
+type webhookPayload struct {
+	Payload
+	Sender     Actor `json:"sender"`
+	Repository struct {
+		ID       int    `json:"id"`
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// FromWebhook converts a webhook delivery into an event. name is the X-GitHub-Event header and
+// id the X-GitHub-Delivery header; the event time is taken from the payload's subject.
+func FromWebhook(name, id string, body []byte) (Event, error) {
+	eventType, ok := webhookTypes[name]
+	if !ok {
+		return Event{}, fmt.Errorf("unsupported webhook event %q", name)
+	}
+	var p webhookPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return Event{}, fmt.Errorf("failed to parse %s webhook: %w", name, err)
+	}
+
+	e := Event{
+		ID:      id,
+		Type:    eventType,
+		Actor:   p.Sender,
+		Repo:    Repo{ID: p.Repository.ID, Name: p.Repository.FullName},
+		Payload: p.Payload,
+		// The delivery is the payload, with more fields than the events API sends
+		RawPayload: slices.Clone(json.RawMessage(body)),
+	}
+	switch {
+	case p.Comment != nil:
+		e.CreatedAt = latest(p.Comment.CreatedAt, p.Comment.UpdatedAt)
+	case p.PullRequest != nil:
+		e.CreatedAt = p.PullRequest.UpdatedAt
+	case p.Issue != nil:
+		e.CreatedAt = p.Issue.UpdatedAt
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now().UTC()
+	}
+	return e, nil
+}
+
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// FromIssue converts an issue or pull request from the REST API into the event that opened it.
+// Events converted from the REST API get IDs prefixed with what they were built from, so
+// they never collide with the numeric IDs of the events API.
+func FromIssue(repo string, issue Issue) Event {
+	e := Event{
+		ID:        fmt.Sprintf("issue-%d-opened", issue.Number),
+		Type:      "IssuesEvent",
+		Actor:     issue.User,
+		Repo:      Repo{Name: repo},
+		Payload:   Payload{Action: "opened", Issue: &issue},
+		CreatedAt: issue.CreatedAt,
+	}
+	if issue.IsPullRequest() {
+		e.Type = "PullRequestEvent"
+		e.Payload.Issue = nil
+		e.Payload.PullRequest = &PullRequest{
+			Number:            issue.Number,
+			Title:             issue.Title,
+			Body:              issue.Body,
+			State:             issue.State,
+			HTMLURL:           issue.HTMLURL,
+			User:              issue.User,
+			Labels:            issue.Labels,
+			Assignees:         issue.Assignees,
+			CreatedAt:         issue.CreatedAt,
+			UpdatedAt:         issue.UpdatedAt,
+			ClosedAt:          issue.ClosedAt,
+			AuthorAssociation: issue.AuthorAssociation,
+		}
+	}
+	return e
+}
+
+// FromReview converts a review from the REST API on the given pull request into a review event.
+// The payload carries the review as the events API does.
+func FromReview(repo string, issue Issue, r Review) Event {
+	if issue.PullRequest == nil {
+		issue.PullRequest = &PullRequestLink{}
+	}
+	issue.Body = ""
+	e := FromIssue(repo, issue)
+	e.ID = "review-" + strconv.Itoa(r.ID)
+	e.Type = "PullRequestReviewEvent"
+	e.Actor = r.User
+	e.Payload.Action = "created"
+	e.CreatedAt = r.SubmittedAt
+	e.RawPayload, _ = json.Marshal(ReviewPayload{Action: "created", Review: r, PullRequest: *e.Payload.PullRequest})
+	return e
+}
+
+// FromReviewComment converts a review comment from the REST API on the given pull request
+// into a review comment event
+func FromReviewComment(repo string, issue Issue, c Comment) Event {
+	if issue.PullRequest == nil {
+		issue.PullRequest = &PullRequestLink{}
+	}
+	issue.Body = ""
+	e := FromIssue(repo, issue)
+	e.ID = "review-comment-" + strconv.Itoa(c.ID)
+	e.Type = "PullRequestReviewCommentEvent"
+	e.Actor = c.User
+	e.Payload.Action = "created"
+	e.Payload.Comment = &c
+	e.CreatedAt = c.CreatedAt
+	return e
+}
+
+// FromComment converts a comment from the REST API on the given issue into a comment event
+func FromComment(repo string, issue Issue, c Comment) Event {
+	// The comment event carries the issue without its body, like the events API
+	issue.Body = ""
+	return Event{
+		ID:        "comment-" + strconv.Itoa(c.ID),
+		Type:      "IssueCommentEvent",
+		Actor:     c.User,
+		Repo:      Repo{Name: repo},
+		Payload:   Payload{Action: "created", Issue: &issue, Comment: &c},
+		CreatedAt: c.CreatedAt,
+	}
+}
@@ -0,0 +1,69 @@
+package watcher
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFromWebhook(t *testing.T) {
+	body := []byte(`{
+		"action": "closed",
+		"pull_request": {
+			"number": 12, "title": "Fix", "state": "closed", "merged": true, "draft": false,
+			"user": {"login": "alice"}, "assignees": [{"login": "bob"}],
+			"labels": [{"name": "bug"}],
+			"created_at": "2024-05-01T10:00:00Z", "updated_at": "2024-05-02T09:00:00Z",
+			"merged_at": "2024-05-02T09:00:00Z"
+		},
+		"repository": {"id": 7, "full_name": "owner/repo"},
+		"sender": {"id": 3, "login": "carol"}
+	}`)
+	e, err := FromWebhook("pull_request", "delivery-1", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.ID != "delivery-1" || e.Type != "PullRequestEvent" || e.Actor.Login != "carol" || e.Repo.Name != "owner/repo" {
+		t.Errorf("unexpected envelope: %+v", e)
+	}
+	pr := e.Payload.PullRequest
+	if pr == nil || !pr.Merged || pr.User.Login != "alice" || pr.Assignees[0].Login != "bob" || pr.MergedAt == nil {
+		t.Fatalf("unexpected pull request: %+v", pr)
+	}
+	if want := time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC); !e.CreatedAt.Equal(want) {
+		t.Errorf("CreatedAt = %v, want %v", e.CreatedAt, want)
+	}
+	if labels := e.Labels(); len(labels) != 1 || labels[0] != "bug" {
+		t.Errorf("Labels = %v", labels)
+	}
+	if v, ok := e.Lookup("payload.pull_request.merged_at"); !ok || v != "2024-05-02T09:00:00Z" {
+		t.Errorf("raw payload not kept: %v", v)
+	}
+
+	if _, err := FromWebhook("sponsorship", "x", body); err == nil {
+		t.Error("expected an error for an unsupported webhook")
+	}
+	if _, err := FromWebhook("issues", "x", []byte("{")); err == nil {
+		t.Error("expected an error for a malformed body")
+	}
+}
+
+func TestFromIssue(t *testing.T) {
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	issue := Issue{Number: 3, Title: "Crash", Body: "Steps", User: Actor{Login: "alice"}, CreatedAt: created}
+
+	e := FromIssue("owner/repo", issue)
+	if e.ID != "issue-3-opened" || e.Type != "IssuesEvent" || e.Payload.Action != "opened" || e.Actor.Login != "alice" || !e.CreatedAt.Equal(created) || e.Body() != "Steps" {
+		t.Errorf("unexpected issue event: %+v", e)
+	}
+
+	issue.PullRequest = &PullRequestLink{HTMLURL: "https://github.com/owner/repo/pull/3"}
+	e = FromIssue("owner/repo", issue)
+	if e.Type != "PullRequestEvent" || e.Payload.PullRequest == nil || e.Payload.Issue != nil || e.Number() != 3 {
+		t.Errorf("unexpected pull request event: %+v", e)
+	}
+
+	c := FromComment("owner/repo", issue, Comment{ID: 9, Body: "Same", User: Actor{Login: "bob"}, CreatedAt: created})
+	if c.ID != "comment-9" || c.Actor.Login != "bob" || c.Body() != "Same" || c.Payload.Issue.Body != "" || c.Title() != "Crash" {
+		t.Errorf("unexpected comment event: %+v", c)
+	}
+}
@@ -1,6 +1,7 @@
 package watcher
 
 import (
+	"encoding/json"
 	"slices"
 	"time"
 )
@@ -13,6 +14,86 @@ type Event struct {
 	Repo      Repo      `json:"repo"`
 	Payload   Payload   `json:"payload"`
 	CreatedAt time.Time `json:"created_at"`
+
+	// RawPayload is the payload exactly as GitHub sent it, with the fields Payload does not
+	// decode; when set, it is what the event's JSON form carries as the payload
+	RawPayload json.RawMessage `json:"-"`
+	// payload caches RawPayload decoded for Lookup; copies of the event share it
+	payload *payloadCache
+
+	// Details is the current state of the issue or pull request, attached by enrichment
+	Details *Details `json:"details,omitempty"`
+
+	// Alerts are the rules the event matched, attached before delivery
+	Alerts []Alert `json:"alerts,omitempty"`
+
+	// Summary describes a burst of related events merged into this one
+	Summary string `json:"summary,omitempty"`
+	// Coalesced are the other events merged into this one
+	Coalesced []Event `json:"coalesced,omitempty"`
+}
+
+// UnmarshalJSON decodes an event, keeping its raw payload
+func (e *Event) UnmarshalJSON(data []byte) error {
+	type plain Event
+	aux := struct {
+		*plain
+		Payload json.RawMessage `json:"payload"`
+	}{plain: (*plain)(e)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	e.Payload, e.RawPayload, e.payload = Payload{}, nil, nil
+	if len(aux.Payload) == 0 || string(aux.Payload) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(aux.Payload, &e.Payload); err != nil {
+		return err
+	}
+	e.RawPayload = slices.Clone(aux.Payload)
+	e.payload = &payloadCache{}
+	return nil
+}
+
+// MarshalJSON encodes an event with its raw payload when it has one
+func (e Event) MarshalJSON() ([]byte, error) {
+	type plain Event
+	if len(e.RawPayload) == 0 {
+		return json.Marshal(plain(e))
+	}
+	return json.Marshal(struct {
+		plain
+		Payload json.RawMessage `json:"payload"`
+	}{plain(e), e.RawPayload})
+}
+
+// Details is the full issue or pull request behind an event, which the events feed
+// reports only in part
+type Details struct {
+	State     string   `json:"state"`
+	Labels    []string `json:"labels"`
+	Assignees []string `json:"assignees"`
+	Milestone string   `json:"milestone,omitempty"`
+
+	// Pull requests only
+	Draft              bool     `json:"draft,omitempty"`
+	Merged             bool     `json:"merged,omitempty"`
+	RequestedReviewers []string `json:"requested_reviewers,omitempty"`
+	Additions          int      `json:"additions,omitempty"`
+	Deletions          int      `json:"deletions,omitempty"`
+	ChangedFiles       int      `json:"changed_files,omitempty"`
+}
+
+// Alert records an alerting rule matched by an event
+type Alert struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	// Fragments are the matched pieces of text, for highlighting
+	Fragments []string `json:"fragments,omitempty"`
+	// Highlight asks terminal output to highlight the fragments
+	Highlight bool `json:"highlight,omitempty"`
+	// Sinks are extra sinks the event is routed to
+	Sinks []string `json:"sinks,omitempty"`
 }
 
 // Actor represents the user who triggered the event
@@ -31,6 +112,9 @@ type Repo struct {
 type Payload struct {
 	Action string `json:"action"`
 
+	// Changes lists the fields modified by an "edited" action
+	Changes map[string]json.RawMessage `json:"changes,omitempty"`
+
 	// Issue events
 	Issue *Issue `json:"issue,omitempty"`
 
@@ -42,32 +126,75 @@ type Payload struct {
 
 	// Release events
 	Release *Release `json:"release,omitempty"`
+
+	// Create and delete events
+	Ref     string `json:"ref,omitempty"`
+	RefType string `json:"ref_type,omitempty"`
 }
 
 // Issue represents a GitHub issue
 type Issue struct {
-	Number  int    `json:"number"`
-	Title   string `json:"title"`
-	Body    string `json:"body"`
-	State   string `json:"state"`
+	Number    int        `json:"number"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	State     string     `json:"state"`
+	HTMLURL   string     `json:"html_url"`
+	User      Actor      `json:"user"`
+	Labels    []Label    `json:"labels,omitempty"`
+	Assignees []Actor    `json:"assignees,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	ClosedAt  *time.Time `json:"closed_at,omitempty"`
+	// AuthorAssociation is the author's relation to the repository, e.g. FIRST_TIME_CONTRIBUTOR
+	AuthorAssociation string `json:"author_association,omitempty"`
+
+	// PullRequest is set when the issue is a pull request, as the issues API reports them too
+	PullRequest *PullRequestLink `json:"pull_request,omitempty"`
+}
+
+// PullRequestLink marks an issue that is a pull request
+type PullRequestLink struct {
 	HTMLURL string `json:"html_url"`
 }
 
+// IsPullRequest reports whether the issue is a pull request
+func (i *Issue) IsPullRequest() bool {
+	return i.PullRequest != nil
+}
+
 // PullRequest represents a GitHub pull request
 type PullRequest struct {
-	Number  int    `json:"number"`
-	Title   string `json:"title"`
-	Body    string `json:"body"`
-	State   string `json:"state"`
-	HTMLURL string `json:"html_url"`
-	Merged  bool   `json:"merged"`
+	Number    int        `json:"number"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	State     string     `json:"state"`
+	HTMLURL   string     `json:"html_url"`
+	User      Actor      `json:"user"`
+	Merged    bool       `json:"merged"`
+	Draft     bool       `json:"draft"`
+	Labels    []Label    `json:"labels,omitempty"`
+	Assignees []Actor    `json:"assignees,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	ClosedAt  *time.Time `json:"closed_at,omitempty"`
+	MergedAt  *time.Time `json:"merged_at,omitempty"`
+	// AuthorAssociation is the author's relation to the repository, e.g. FIRST_TIME_CONTRIBUTOR
+	AuthorAssociation string `json:"author_association,omitempty"`
+}
+
+// Label represents a GitHub issue or pull request label
+type Label struct {
+	Name string `json:"name"`
 }
 
 // Comment represents a GitHub comment
 type Comment struct {
-	ID      int    `json:"id"`
-	Body    string `json:"body"`
-	HTMLURL string `json:"html_url"`
+	ID        int       `json:"id"`
+	Body      string    `json:"body"`
+	HTMLURL   string    `json:"html_url"`
+	User      Actor     `json:"user"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // Release represents a GitHub release
@@ -77,6 +204,97 @@ type Release struct {
 	HTMLURL string `json:"html_url"`
 }
 
+// HTMLURL returns the web URL of the event's subject, if any
+func (e Event) HTMLURL() string {
+	p := e.Payload
+	switch {
+	case p.Comment != nil && p.Comment.HTMLURL != "":
+		return p.Comment.HTMLURL
+	case p.Issue != nil:
+		return p.Issue.HTMLURL
+	case p.PullRequest != nil:
+		return p.PullRequest.HTMLURL
+	case p.Release != nil:
+		return p.Release.HTMLURL
+	}
+	return ""
+}
+
+// Number returns the issue or pull request number the event belongs to, or zero
+func (e Event) Number() int {
+	switch {
+	case e.Payload.Issue != nil:
+		return e.Payload.Issue.Number
+	case e.Payload.PullRequest != nil:
+		return e.Payload.PullRequest.Number
+	}
+	return 0
+}
+
+// Title returns the title of the issue or pull request the event belongs to
+func (e Event) Title() string {
+	switch {
+	case e.Payload.Issue != nil:
+		return e.Payload.Issue.Title
+	case e.Payload.PullRequest != nil:
+		return e.Payload.PullRequest.Title
+	}
+	return ""
+}
+
+// Body returns the comment body for comment events and the issue or pull request body otherwise
+func (e Event) Body() string {
+	switch {
+	case e.Payload.Comment != nil:
+		return e.Payload.Comment.Body
+	case e.Payload.Issue != nil:
+		return e.Payload.Issue.Body
+	case e.Payload.PullRequest != nil:
+		return e.Payload.PullRequest.Body
+	}
+	return ""
+}
+
+// Labels returns the label names of the issue or pull request the event belongs to,
+// preferring the enriched details
+func (e Event) Labels() []string {
+	if e.Details != nil {
+		return e.Details.Labels
+	}
+	var labels []Label
+	switch {
+	case e.Payload.Issue != nil:
+		labels = e.Payload.Issue.Labels
+	case e.Payload.PullRequest != nil:
+		labels = e.Payload.PullRequest.Labels
+	}
+	names := make([]string, 0, len(labels))
+	for _, l := range labels {
+		names = append(names, l.Name)
+	}
+	return names
+}
+
+// Assignees returns the logins assigned to the issue or pull request the event belongs to,
+// preferring the enriched details
+func (e Event) Assignees() []string {
+	if e.Details != nil {
+		return e.Details.Assignees
+	}
+	var assignees []Actor
+	switch {
+	case e.Payload.Issue != nil:
+		assignees = e.Payload.Issue.Assignees
+	case e.Payload.PullRequest != nil:
+		assignees = e.Payload.PullRequest.Assignees
+	}
+	logins := make([]string, 0, len(assignees))
+	for _, a := range assignees {
+		logins = append(logins, a.Login)
+	}
+	return logins
+}
+
 // TargetEventTypes are the event types we want to monitor
 var TargetEventTypes = []string{
 	"IssuesEvent",
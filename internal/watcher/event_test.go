@@ -0,0 +1,28 @@
+package watcher
+
+import (
+	"testing"
+)
+
+func TestEventSubject(t *testing.T) {
+	issue := Event{Payload: Payload{
+		Issue:   &Issue{Number: 42, Title: "Bug in login", HTMLURL: "https://github.com/owner/repo/issues/42"},
+		Comment: &Comment{HTMLURL: "https://github.com/owner/repo/issues/42#issuecomment-1"},
+	}}
+	if issue.Number() != 42 || issue.Title() != "Bug in login" {
+		t.Errorf("got #%d %q", issue.Number(), issue.Title())
+	}
+	if url := issue.HTMLURL(); url != "https://github.com/owner/repo/issues/42#issuecomment-1" {
+		t.Errorf("HTMLURL() = %q, want comment URL", url)
+	}
+
+	pr := Event{Payload: Payload{PullRequest: &PullRequest{Number: 7, Title: "Add feature"}}}
+	if pr.Number() != 7 || pr.Title() != "Add feature" {
+		t.Errorf("got #%d %q", pr.Number(), pr.Title())
+	}
+
+	release := Event{Payload: Payload{Release: &Release{HTMLURL: "https://github.com/owner/repo/releases/tag/v1"}}}
+	if release.Number() != 0 || release.HTMLURL() != "https://github.com/owner/repo/releases/tag/v1" {
+		t.Errorf("unexpected release subject")
+	}
+}
@@ -1,14 +1,31 @@
 package watcher
 
 import (
-	"encoding/json"
 	"fmt"
-	"os/exec"
+	"time"
 )
 
 // Fetcher fetches events from GitHub API using gh command
 type Fetcher struct {
 	repo string
+	// Transport performs the requests; nil uses GH
+	Transport Transport
+	// ReviewLimit caps the pull requests whose reviews a backfill fetches, one request each;
+	// zero uses DefaultReviewLimit and a negative limit fetches none
+	ReviewLimit int
+}
+
+// DefaultReviewLimit is how many of the most recently updated pull requests get their
+// reviews backfilled
+const DefaultReviewLimit = 30
+
+// get requests an API path through the fetcher's transport
+func (f *Fetcher) get(path string, paginate bool, v any) error {
+	t := f.Transport
+	if t == nil {
+		t = GH
+	}
+	return t.get(path, paginate, v)
 }
 
 // NewFetcher creates a new Fetcher for the given repository
@@ -16,28 +33,21 @@ func NewFetcher(repo string) *Fetcher {
 	return &Fetcher{repo: repo}
 }
 
-// FetchEvents retrieves recent events from the repository
-func (f *Fetcher) FetchEvents() ([]Event, error) {
-	cmd := exec.Command("gh", "api", fmt.Sprintf("repos/%s/events", f.repo))
-	output, err := cmd.Output()
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return nil, fmt.Errorf("gh api failed: %s", string(exitErr.Stderr))
-		}
-		return nil, fmt.Errorf("failed to execute gh command: %w", err)
-	}
-
-	var events []Event
-	if err := json.Unmarshal(output, &events); err != nil {
-		return nil, fmt.Errorf("failed to parse events: %w", err)
+// FetchEvents retrieves the events since a time, oldest first. The events feed only
+// reaches back 300 events or 90 days; older events are rebuilt by Backfill.
+func (f *Fetcher) FetchEvents(since time.Time) ([]Event, error) {
+	var feed []Event
+	if err := f.get(fmt.Sprintf("repos/%s/events?per_page=100", f.repo), true, &feed); err != nil {
+		return nil, err
 	}
-
-	return events, nil
+	return combine(feed, since, func(until time.Time) ([]Event, error) {
+		return f.backfill(since, until)
+	})
 }
 
 // FetchTargetEvents retrieves only the events we want to monitor
-func (f *Fetcher) FetchTargetEvents() ([]Event, error) {
-	events, err := f.FetchEvents()
+func (f *Fetcher) FetchTargetEvents(since time.Time) ([]Event, error) {
+	events, err := f.FetchEvents(since)
 	if err != nil {
 		return nil, err
 	}
@@ -51,3 +61,31 @@ func (f *Fetcher) FetchTargetEvents() ([]Event, error) {
 
 	return targetEvents, nil
 }
+
+// OpenPullRequest is an entry of the pull requests API with the reviews it waits for
+type OpenPullRequest struct {
+	PullRequest
+	RequestedReviewers []Actor `json:"requested_reviewers"`
+	RequestedTeams     []struct {
+		Slug string `json:"slug"`
+	} `json:"requested_teams"`
+}
+
+// FetchOpenPullRequests retrieves the repository's open pull requests
+func (f *Fetcher) FetchOpenPullRequests() ([]OpenPullRequest, error) {
+	var prs []OpenPullRequest
+	if err := f.get(fmt.Sprintf("repos/%s/pulls?state=open&per_page=100", f.repo), true, &prs); err != nil {
+		return nil, err
+	}
+	return prs, nil
+}
+
+// FetchIssueEvents retrieves the timeline events of an issue or pull request, such as
+// review requests
+func (f *Fetcher) FetchIssueEvents(number int) ([]IssueEvent, error) {
+	var events []IssueEvent
+	if err := f.get(fmt.Sprintf("repos/%s/issues/%d/events?per_page=100", f.repo, number), true, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
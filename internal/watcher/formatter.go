@@ -2,26 +2,78 @@ package watcher
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ytnobody/ghostio/internal/markdown"
 )
 
-// FormatEvent formats an event into a human-readable string
+// FormatEvent formats an event into a human-readable string. Enriched details follow the URL.
 func FormatEvent(e Event) string {
 	timestamp := e.CreatedAt.Format("2006-01-02 15:04:05")
 
-	switch e.Type {
-	case "IssuesEvent":
-		return formatIssueEvent(timestamp, e)
-	case "PullRequestEvent":
-		return formatPREvent(timestamp, e)
-	case "IssueCommentEvent":
-		return formatIssueCommentEvent(timestamp, e)
-	case "PullRequestReviewCommentEvent":
-		return formatPRCommentEvent(timestamp, e)
-	case "ReleaseEvent":
-		return formatReleaseEvent(timestamp, e)
+	var result string
+	switch {
+	case e.Summary != "":
+		result = formatSummary(timestamp, e)
+	case e.Type == "IssuesEvent":
+		result = formatIssueEvent(timestamp, e)
+	case e.Type == "PullRequestEvent":
+		result = formatPREvent(timestamp, e)
+	case e.Type == "IssueCommentEvent":
+		result = formatIssueCommentEvent(timestamp, e)
+	case e.Type == "PullRequestReviewCommentEvent":
+		result = formatPRCommentEvent(timestamp, e)
+	case e.Type == "PullRequestReviewEvent":
+		result = formatReviewEvent(timestamp, e)
+	case e.Type == "ReleaseEvent":
+		result = formatReleaseEvent(timestamp, e)
 	default:
 		return fmt.Sprintf("[%s] %s by @%s", timestamp, e.Type, e.Actor.Login)
 	}
+
+	details := formatDetails(e.Details)
+	if details == "" {
+		return result
+	}
+	head, body, hasBody := strings.Cut(result, "\n\n")
+	result = head + "\n" + details
+	if hasBody {
+		result += "\n\n" + body
+	}
+	return result
+}
+
+// formatDetails describes enriched details on one line, e.g.
+// "draft · labels: bug, ui · assignees: @bob · milestone: v2 · +120 -30 in 4 files"
+func formatDetails(d *Details) string {
+	if d == nil {
+		return ""
+	}
+	var parts []string
+	if d.Draft {
+		parts = append(parts, "draft")
+	}
+	if len(d.Labels) > 0 {
+		parts = append(parts, "labels: "+strings.Join(d.Labels, ", "))
+	}
+	if len(d.Assignees) > 0 {
+		parts = append(parts, "assignees: @"+strings.Join(d.Assignees, ", @"))
+	}
+	if d.Milestone != "" {
+		parts = append(parts, "milestone: "+d.Milestone)
+	}
+	if len(d.RequestedReviewers) > 0 {
+		parts = append(parts, "reviewers: @"+strings.Join(d.RequestedReviewers, ", @"))
+	}
+	if d.ChangedFiles > 0 {
+		files := "files"
+		if d.ChangedFiles == 1 {
+			files = "file"
+		}
+		parts = append(parts, fmt.Sprintf("+%d -%d in %d %s", d.Additions, d.Deletions, d.ChangedFiles, files))
+	}
+	return strings.Join(parts, " · ")
 }
 
 func formatIssueEvent(timestamp string, e Event) string {
@@ -31,8 +83,8 @@ func formatIssueEvent(timestamp string, e Event) string {
 	issue := e.Payload.Issue
 	result := fmt.Sprintf("[%s] Issue %s: #%d \"%s\" by @%s\n%s",
 		timestamp, e.Payload.Action, issue.Number, issue.Title, e.Actor.Login, issue.HTMLURL)
-	if issue.Body != "" {
-		result += fmt.Sprintf("\n\n%s", issue.Body)
+	if body := markdown.Plain(issue.Body); body != "" {
+		result += fmt.Sprintf("\n\n%s", body)
 	}
 	return result
 }
@@ -50,8 +102,8 @@ func formatPREvent(timestamp string, e Event) string {
 
 	result := fmt.Sprintf("[%s] PR %s: #%d \"%s\" by @%s\n%s",
 		timestamp, action, pr.Number, pr.Title, e.Actor.Login, pr.HTMLURL)
-	if pr.Body != "" {
-		result += fmt.Sprintf("\n\n%s", pr.Body)
+	if body := markdown.Plain(pr.Body); body != "" {
+		result += fmt.Sprintf("\n\n%s", body)
 	}
 	return result
 }
@@ -63,8 +115,8 @@ func formatIssueCommentEvent(timestamp string, e Event) string {
 	issue := e.Payload.Issue
 	result := fmt.Sprintf("[%s] Comment on #%d \"%s\" by @%s\n%s",
 		timestamp, issue.Number, issue.Title, e.Actor.Login, issue.HTMLURL)
-	if e.Payload.Comment != nil && e.Payload.Comment.Body != "" {
-		result += fmt.Sprintf("\n\n%s", e.Payload.Comment.Body)
+	if body := commentBody(e); body != "" {
+		result += fmt.Sprintf("\n\n%s", body)
 	}
 	return result
 }
@@ -76,12 +128,45 @@ func formatPRCommentEvent(timestamp string, e Event) string {
 	pr := e.Payload.PullRequest
 	result := fmt.Sprintf("[%s] PR comment on #%d \"%s\" by @%s\n%s",
 		timestamp, pr.Number, pr.Title, e.Actor.Login, pr.HTMLURL)
-	if e.Payload.Comment != nil && e.Payload.Comment.Body != "" {
-		result += fmt.Sprintf("\n\n%s", e.Payload.Comment.Body)
+	if body := commentBody(e); body != "" {
+		result += fmt.Sprintf("\n\n%s", body)
 	}
 	return result
 }
 
+func formatReviewEvent(timestamp string, e Event) string {
+	if e.Payload.PullRequest == nil {
+		return fmt.Sprintf("[%s] PR review by @%s", timestamp, e.Actor.Login)
+	}
+	pr := e.Payload.PullRequest
+	verdict, url := "reviewed", pr.HTMLURL
+	if p, err := e.ReviewPayload(); err == nil {
+		switch strings.ToUpper(p.Review.State) {
+		case "APPROVED":
+			verdict = "approved"
+		case "CHANGES_REQUESTED":
+			verdict = "changes requested"
+		}
+		if p.Review.HTMLURL != "" {
+			url = p.Review.HTMLURL
+		}
+	}
+	result := fmt.Sprintf("[%s] PR %s: #%d \"%s\" by @%s\n%s",
+		timestamp, verdict, pr.Number, pr.Title, e.Actor.Login, url)
+	if body := markdown.Plain(reviewBody(e)); body != "" {
+		result += fmt.Sprintf("\n\n%s", body)
+	}
+	return result
+}
+
+// reviewBody returns the markdown body of a review event's review, or ""
+func reviewBody(e Event) string {
+	if p, err := e.ReviewPayload(); err == nil {
+		return p.Review.Body
+	}
+	return ""
+}
+
 func formatReleaseEvent(timestamp string, e Event) string {
 	if e.Payload.Release == nil {
 		return fmt.Sprintf("[%s] Release %s by @%s", timestamp, e.Payload.Action, e.Actor.Login)
@@ -91,3 +176,36 @@ func formatReleaseEvent(timestamp string, e Event) string {
 		timestamp, release.TagName, e.Payload.Action, release.HTMLURL)
 	return result
 }
+
+// commentBody renders the comment's markdown body as plain text
+func commentBody(e Event) string {
+	if e.Payload.Comment == nil {
+		return ""
+	}
+	return markdown.Plain(e.Payload.Comment.Body)
+}
+
+func formatSummary(timestamp string, e Event) string {
+	result := fmt.Sprintf("[%s] %s", timestamp, e.Summary)
+	if url := e.HTMLURL(); url != "" {
+		result += "\n" + url
+	}
+	if body := summaryBody(e); body != "" {
+		result += "\n\n" + body
+	}
+	return result
+}
+
+// summaryBody lists the comments among a merged event and the events merged into it,
+// oldest first, each as "@author: body"
+func summaryBody(e Event) string {
+	events := append([]Event{e}, e.Coalesced...)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].CreatedAt.Before(events[j].CreatedAt) })
+	var parts []string
+	for _, c := range events {
+		if body := strings.TrimSpace(c.Body()); c.Payload.Comment != nil && body != "" {
+			parts = append(parts, fmt.Sprintf("@%s: %s", c.Actor.Login, body))
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -66,6 +66,24 @@ func TestFormatEvent(t *testing.T) {
 			},
 			expected: "[2024-01-15 10:30:45] PR opened: #99 \"Add feature\" by @developer\nhttps://github.com/owner/repo/pull/99\n\nThis PR adds a new feature",
 		},
+		{
+			name: "PR opened with template body",
+			event: Event{
+				Type:  "PullRequestEvent",
+				Actor: Actor{Login: "developer"},
+				Payload: Payload{
+					Action: "opened",
+					PullRequest: &PullRequest{
+						Number:  100,
+						Title:   "Add cache",
+						Body:    "<!-- Describe your change -->\n## Changes\n- [x] **Tests**\n![graph](https://example.com/g.png)",
+						HTMLURL: "https://github.com/owner/repo/pull/100",
+					},
+				},
+				CreatedAt: baseTime,
+			},
+			expected: "[2024-01-15 10:30:45] PR opened: #100 \"Add cache\" by @developer\nhttps://github.com/owner/repo/pull/100\n\nChanges\n\n☑ Tests\n[image: graph]",
+		},
 		{
 			name: "PR merged",
 			event: Event{
@@ -142,6 +160,30 @@ func TestFormatEvent(t *testing.T) {
 			},
 			expected: "[2024-01-15 10:30:45] PR comment on #456 \"Some PR\" by @reviewer\nhttps://github.com/owner/repo/pull/456\n\nLGTM",
 		},
+		{
+			name: "PR review approved",
+			event: Event{
+				Type:  "PullRequestReviewEvent",
+				Actor: Actor{Login: "reviewer"},
+				Payload: Payload{
+					Action:      "created",
+					PullRequest: &PullRequest{Number: 456, Title: "Some PR", HTMLURL: "https://github.com/owner/repo/pull/456"},
+				},
+				RawPayload: []byte(`{"action":"created","review":{"state":"approved","body":"Ship **it**","html_url":"https://github.com/owner/repo/pull/456#pullrequestreview-1"}}`),
+				CreatedAt:  baseTime,
+			},
+			expected: "[2024-01-15 10:30:45] PR approved: #456 \"Some PR\" by @reviewer\nhttps://github.com/owner/repo/pull/456#pullrequestreview-1\n\nShip it",
+		},
+		{
+			name: "PR review without a verdict",
+			event: Event{
+				Type:      "PullRequestReviewEvent",
+				Actor:     Actor{Login: "reviewer"},
+				Payload:   Payload{Action: "created", PullRequest: &PullRequest{Number: 456, Title: "Some PR", HTMLURL: "https://github.com/owner/repo/pull/456"}},
+				CreatedAt: baseTime,
+			},
+			expected: "[2024-01-15 10:30:45] PR reviewed: #456 \"Some PR\" by @reviewer\nhttps://github.com/owner/repo/pull/456",
+		},
 		{
 			name: "Release published",
 			event: Event{
@@ -158,6 +200,24 @@ func TestFormatEvent(t *testing.T) {
 			},
 			expected: "[2024-01-15 10:33:00] Release v1.0.0 published\nhttps://github.com/owner/repo/releases/tag/v1.0.0",
 		},
+		{
+			name: "PR with enriched details",
+			event: Event{
+				Type:  "PullRequestEvent",
+				Actor: Actor{Login: "dev"},
+				Payload: Payload{
+					Action:      "opened",
+					PullRequest: &PullRequest{Number: 7, Title: "Cache", Body: "Adds a cache", HTMLURL: "https://github.com/owner/repo/pull/7"},
+				},
+				Details: &Details{
+					Draft: true, Labels: []string{"perf"}, Assignees: []string{"bob"}, Milestone: "v2",
+					RequestedReviewers: []string{"carol"}, Additions: 120, Deletions: 30, ChangedFiles: 4,
+				},
+				CreatedAt: baseTime,
+			},
+			expected: "[2024-01-15 10:30:45] PR opened: #7 \"Cache\" by @dev\nhttps://github.com/owner/repo/pull/7\n" +
+				"draft · labels: perf · assignees: @bob · milestone: v2 · reviewers: @carol · +120 -30 in 4 files\n\nAdds a cache",
+		},
 		{
 			name: "Issue event without issue data",
 			event: Event{
@@ -168,6 +228,23 @@ func TestFormatEvent(t *testing.T) {
 			},
 			expected: `[2024-01-15 10:30:45] Issue opened by @user`,
 		},
+		{
+			name: "Merged comments",
+			event: Event{
+				Type:      "IssueCommentEvent",
+				Actor:     Actor{Login: "alice"},
+				Summary:   "2 comments on #5 by @alice, @bob",
+				Payload:   Payload{Action: "created", Issue: &Issue{Number: 5}, Comment: &Comment{Body: "Seen it", HTMLURL: "https://github.com/owner/repo/issues/5#c1"}},
+				CreatedAt: baseTime,
+				Coalesced: []Event{{
+					Type:      "IssueCommentEvent",
+					Actor:     Actor{Login: "bob"},
+					Payload:   Payload{Action: "created", Issue: &Issue{Number: 5}, Comment: &Comment{Body: "This is an outage"}},
+					CreatedAt: baseTime.Add(time.Second),
+				}},
+			},
+			expected: "[2024-01-15 10:30:45] 2 comments on #5 by @alice, @bob\nhttps://github.com/owner/repo/issues/5#c1\n\n@alice: Seen it\n\n@bob: This is an outage",
+		},
 		{
 			name: "Unknown event type",
 			event: Event{
@@ -0,0 +1,127 @@
+package watcher
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/metrics"
+)
+
+// Poll results recorded in PollerMetrics
+const (
+	PollOK          = "200"
+	PollNotModified = "304"
+	PollError       = "error"
+)
+
+// PollerMetrics records poller activity in a metrics registry.
+// A nil *PollerMetrics records nothing.
+type PollerMetrics struct {
+	polls        *metrics.CounterVec
+	requests     *metrics.CounterVec
+	latency      *metrics.HistogramVec
+	events       *metrics.CounterVec
+	gaps         *metrics.CounterVec
+	rateLimit    *metrics.Gauge
+	sinceSuccess *metrics.GaugeVec
+	queueDepth   *metrics.Gauge
+
+	mu          sync.Mutex
+	lastSuccess map[string]time.Time
+	queue       func() int
+}
+
+// NewPollerMetrics registers the poller metrics in reg
+func NewPollerMetrics(reg *metrics.Registry) *PollerMetrics {
+	m := &PollerMetrics{
+		polls:        reg.Counter("ghostio_polls_total", "Polls of the events API by result.", "repo", "result"),
+		requests:     reg.Counter("ghostio_api_requests_total", "Other GitHub API requests, such as thread and enrichment fetches, by result.", "result"),
+		latency:      reg.Histogram("ghostio_poll_duration_seconds", "Latency of polls of the events API.", nil, "repo"),
+		events:       reg.Counter("ghostio_events_emitted_total", "Events emitted by the poller.", "repo", "type", "action"),
+		gaps:         reg.Counter("ghostio_poll_gaps_total", "Polls where the last seen event was no longer on the first page, so events may have been missed.", "repo"),
+		rateLimit:    reg.Gauge("ghostio_rate_limit_remaining", "Remaining GitHub API requests in the current rate limit window.").With(),
+		sinceSuccess: reg.Gauge("ghostio_seconds_since_last_successful_poll", "Seconds since the last successful poll.", "repo"),
+		queueDepth:   reg.Gauge("ghostio_queue_depth", "Events waiting to be delivered to sinks.").With(),
+		lastSuccess:  make(map[string]time.Time),
+	}
+	reg.OnCollect(m.collect)
+	return m
+}
+
+// TrackQueue reports the result of depth as the queue depth on every scrape
+func (m *PollerMetrics) TrackQueue(depth func() int) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queue = depth
+}
+
+func (m *PollerMetrics) collect() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for repo, t := range m.lastSuccess {
+		m.sinceSuccess.With(repo).Set(time.Since(t).Seconds())
+	}
+	if m.queue != nil {
+		m.queueDepth.Set(float64(m.queue()))
+	}
+}
+
+func (m *PollerMetrics) observePoll(repo, result string, elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+	m.polls.With(repo, result).Inc()
+	m.latency.With(repo).Observe(elapsed.Seconds())
+	if result != PollError {
+		m.mu.Lock()
+		m.lastSuccess[repo] = time.Now()
+		m.mu.Unlock()
+	}
+}
+
+// Instrument counts the requests t makes besides polls and records the rate limit they report
+func (m *PollerMetrics) Instrument(t Transport) Transport {
+	if m == nil {
+		return t
+	}
+	return func(path, etag string) Exchange {
+		x := t(path, etag)
+		result := PollOK
+		switch {
+		case x.NotModified():
+			result = PollNotModified
+		case x.Error != "":
+			result = PollError
+		}
+		m.requests.With(result).Inc()
+		if n, err := strconv.Atoi(x.Header("x-ratelimit-remaining")); err == nil {
+			m.observeRateLimit(n)
+		}
+		return x
+	}
+}
+
+func (m *PollerMetrics) observeRateLimit(remaining int) {
+	if m == nil || remaining < 0 {
+		return
+	}
+	m.rateLimit.Set(float64(remaining))
+}
+
+func (m *PollerMetrics) observeEvent(e Event) {
+	if m == nil {
+		return
+	}
+	m.events.With(e.Repo.Name, e.Type, e.Payload.Action).Inc()
+}
+
+func (m *PollerMetrics) observeGap(repo string) {
+	if m == nil {
+		return
+	}
+	m.gaps.With(repo).Inc()
+}
@@ -0,0 +1,74 @@
+package watcher
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ytnobody/ghostio/internal/metrics"
+)
+
+func TestPollerMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	m := NewPollerMetrics(reg)
+	m.observePoll("org/api", PollOK, 200*time.Millisecond)
+	m.observePoll("org/api", PollNotModified, 100*time.Millisecond)
+	m.observePoll("org/api", PollError, time.Second)
+	m.observeRateLimit(4000)
+	m.observeRateLimit(-1)
+	m.observeEvent(Event{Type: "IssuesEvent", Repo: Repo{Name: "org/api"}, Payload: Payload{Action: "opened"}})
+	m.observeGap("org/api")
+	m.TrackQueue(func() int { return 3 })
+
+	var b strings.Builder
+	reg.WriteText(&b)
+	out := b.String()
+	for _, want := range []string{
+		`ghostio_polls_total{repo="org/api",result="200"} 1`,
+		`ghostio_polls_total{repo="org/api",result="304"} 1`,
+		`ghostio_polls_total{repo="org/api",result="error"} 1`,
+		`ghostio_poll_duration_seconds_count{repo="org/api"} 3`,
+		`ghostio_events_emitted_total{repo="org/api",type="IssuesEvent",action="opened"} 1`,
+		`ghostio_poll_gaps_total{repo="org/api"} 1`,
+		"ghostio_rate_limit_remaining 4000\n",
+		"ghostio_queue_depth 3\n",
+		`ghostio_seconds_since_last_successful_poll{repo="org/api"} `,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestInstrument(t *testing.T) {
+	reg := metrics.NewRegistry()
+	transport := NewPollerMetrics(reg).Instrument(func(path, etag string) Exchange {
+		if path == "fail" {
+			return Exchange{Error: "gh api failed"}
+		}
+		return Exchange{Output: "HTTP/2.0 200 OK\r\nX-RateLimit-Remaining: 4321\r\n\r\n{}"}
+	})
+	transport("repos/o/r/issues/1", "")
+	transport("fail", "")
+
+	var b strings.Builder
+	reg.WriteText(&b)
+	for _, want := range []string{
+		`ghostio_api_requests_total{result="200"} 1`,
+		`ghostio_api_requests_total{result="error"} 1`,
+		"ghostio_rate_limit_remaining 4321\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, b.String())
+		}
+	}
+}
+
+func TestNilPollerMetrics(t *testing.T) {
+	var m *PollerMetrics
+	m.observePoll("org/api", PollOK, time.Second)
+	m.observeEvent(Event{})
+	m.observeGap("org/api")
+	m.observeRateLimit(1)
+	m.TrackQueue(func() int { return 0 })
+}
@@ -0,0 +1,289 @@
+package watcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IssuesPayload is the payload of an IssuesEvent
+type IssuesPayload struct {
+	Action   string                     `json:"action"`
+	Issue    Issue                      `json:"issue"`
+	Changes  map[string]json.RawMessage `json:"changes,omitempty"`
+	Label    *Label                     `json:"label,omitempty"`
+	Assignee *Actor                     `json:"assignee,omitempty"`
+}
+
+// IssueCommentPayload is the payload of an IssueCommentEvent
+type IssueCommentPayload struct {
+	Action  string                     `json:"action"`
+	Issue   Issue                      `json:"issue"`
+	Comment Comment                    `json:"comment"`
+	Changes map[string]json.RawMessage `json:"changes,omitempty"`
+}
+
+// PullRequestPayload is the payload of a PullRequestEvent
+type PullRequestPayload struct {
+	Action            string                     `json:"action"`
+	Number            int                        `json:"number"`
+	PullRequest       PullRequest                `json:"pull_request"`
+	Changes           map[string]json.RawMessage `json:"changes,omitempty"`
+	Label             *Label                     `json:"label,omitempty"`
+	Assignee          *Actor                     `json:"assignee,omitempty"`
+	RequestedReviewer *Actor                     `json:"requested_reviewer,omitempty"`
+}
+
+// ReviewPayload is the payload of a PullRequestReviewEvent
+type ReviewPayload struct {
+	Action      string      `json:"action"`
+	Review      Review      `json:"review"`
+	PullRequest PullRequest `json:"pull_request"`
+}
+
+// Review is a pull request review
+type Review struct {
+	ID          int       `json:"id"`
+	Body        string    `json:"body"`
+	State       string    `json:"state"`
+	HTMLURL     string    `json:"html_url"`
+	CommitID    string    `json:"commit_id"`
+	User        Actor     `json:"user"`
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+// ReviewCommentPayload is the payload of a PullRequestReviewCommentEvent
+type ReviewCommentPayload struct {
+	Action      string      `json:"action"`
+	Comment     Comment     `json:"comment"`
+	PullRequest PullRequest `json:"pull_request"`
+}
+
+// PushPayload is the payload of a PushEvent
+type PushPayload struct {
+	PushID       int64    `json:"push_id"`
+	Ref          string   `json:"ref"`
+	Head         string   `json:"head"`
+	Before       string   `json:"before"`
+	Size         int      `json:"size"`
+	DistinctSize int      `json:"distinct_size"`
+	Commits      []Commit `json:"commits"`
+}
+
+// Commit is a commit in a push
+type Commit struct {
+	SHA     string `json:"sha"`
+	Message string `json:"message"`
+	Author  struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	} `json:"author"`
+	Distinct bool   `json:"distinct"`
+	URL      string `json:"url"`
+}
+
+// RefPayload is the payload of a CreateEvent or DeleteEvent
+type RefPayload struct {
+	Ref          string `json:"ref"`
+	RefType      string `json:"ref_type"`
+	MasterBranch string `json:"master_branch,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// ReleasePayload is the payload of a ReleaseEvent
+type ReleasePayload struct {
+	Action  string  `json:"action"`
+	Release Release `json:"release"`
+}
+
+// ForkPayload is the payload of a ForkEvent
+type ForkPayload struct {
+	Forkee struct {
+		FullName string `json:"full_name"`
+		HTMLURL  string `json:"html_url"`
+		Owner    Actor  `json:"owner"`
+	} `json:"forkee"`
+}
+
+// IssuesPayload decodes the payload of an IssuesEvent
+func (e Event) IssuesPayload() (*IssuesPayload, error) {
+	return decodeAs[IssuesPayload](e, "IssuesEvent")
+}
+
+// IssueCommentPayload decodes the payload of an IssueCommentEvent
+func (e Event) IssueCommentPayload() (*IssueCommentPayload, error) {
+	return decodeAs[IssueCommentPayload](e, "IssueCommentEvent")
+}
+
+// PullRequestPayload decodes the payload of a PullRequestEvent
+func (e Event) PullRequestPayload() (*PullRequestPayload, error) {
+	return decodeAs[PullRequestPayload](e, "PullRequestEvent")
+}
+
+// ReviewPayload decodes the payload of a PullRequestReviewEvent
+func (e Event) ReviewPayload() (*ReviewPayload, error) {
+	return decodeAs[ReviewPayload](e, "PullRequestReviewEvent")
+}
+
+// ReviewCommentPayload decodes the payload of a PullRequestReviewCommentEvent
+func (e Event) ReviewCommentPayload() (*ReviewCommentPayload, error) {
+	return decodeAs[ReviewCommentPayload](e, "PullRequestReviewCommentEvent")
+}
+
+// PushPayload decodes the payload of a PushEvent
+func (e Event) PushPayload() (*PushPayload, error) {
+	return decodeAs[PushPayload](e, "PushEvent")
+}
+
+// RefPayload decodes the payload of a CreateEvent or DeleteEvent
+func (e Event) RefPayload() (*RefPayload, error) {
+	return decodeAs[RefPayload](e, "CreateEvent", "DeleteEvent")
+}
+
+// ReleasePayload decodes the payload of a ReleaseEvent
+func (e Event) ReleasePayload() (*ReleasePayload, error) {
+	return decodeAs[ReleasePayload](e, "ReleaseEvent")
+}
+
+// ForkPayload decodes the payload of a ForkEvent
+func (e Event) ForkPayload() (*ForkPayload, error) {
+	return decodeAs[ForkPayload](e, "ForkEvent")
+}
+
+func decodeAs[T any](e Event, types ...string) (*T, error) {
+	if !slices.Contains(types, e.Type) {
+		return nil, fmt.Errorf("%s does not have a %s payload", e.Type, strings.Join(types, " or "))
+	}
+	var p T
+	if err := e.DecodePayload(&p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// DecodePayload decodes the raw payload into v; events built without one, such as those
+// converted from the REST API, decode from Payload instead
+func (e Event) DecodePayload(v any) error {
+	data, err := e.payloadJSON()
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode %s payload: %w", e.Type, err)
+	}
+	return nil
+}
+
+func (e Event) payloadJSON() ([]byte, error) {
+	if len(e.RawPayload) > 0 {
+		return e.RawPayload, nil
+	}
+	return json.Marshal(e.Payload)
+}
+
+// payloadCache holds the raw payload decoded into generic values, so filters and rules
+// evaluating several paths on the same event decode it once
+type payloadCache struct {
+	once  sync.Once
+	value any
+	err   error
+}
+
+// decodeGeneric decodes data into maps, slices and json.Number values
+func decodeGeneric(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// genericPayload returns the payload as generic values, decoding the raw payload only
+// once for events read from JSON
+func (e Event) genericPayload() (any, error) {
+	if e.payload == nil || len(e.RawPayload) == 0 {
+		data, err := e.payloadJSON()
+		if err != nil {
+			return nil, err
+		}
+		return decodeGeneric(data)
+	}
+	e.payload.once.Do(func() {
+		e.payload.value, e.payload.err = decodeGeneric(e.RawPayload)
+	})
+	return e.payload.value, e.payload.err
+}
+
+// Lookup returns the value at a path into the event's JSON form, such as
+// "payload.issue.labels[0].name". Objects are map[string]any, arrays []any and numbers
+// json.Number; ok is false when the path does not exist. Payload values are shared by
+// every copy of the event and must not be modified.
+func (e Event) Lookup(path string) (any, bool) {
+	keys, err := splitPath(path)
+	if err != nil || len(keys) == 0 {
+		return nil, false
+	}
+
+	var v any
+	if keys[0] == "payload" {
+		v, err = e.genericPayload()
+		keys = keys[1:]
+	} else {
+		var data []byte
+		if data, err = json.Marshal(e); err == nil {
+			v, err = decodeGeneric(data)
+		}
+	}
+	if err != nil {
+		return nil, false
+	}
+
+	for _, key := range keys {
+		switch k := key.(type) {
+		case string:
+			obj, ok := v.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			if v, ok = obj[k]; !ok {
+				return nil, false
+			}
+		case int:
+			arr, ok := v.([]any)
+			if !ok || k >= len(arr) {
+				return nil, false
+			}
+			v = arr[k]
+		}
+	}
+	return v, true
+}
+
+// splitPath splits "a.b[0].c" into object keys (strings) and array indexes (ints)
+func splitPath(path string) ([]any, error) {
+	var keys []any
+	for _, part := range strings.Split(path, ".") {
+		name, rest, _ := strings.Cut(part, "[")
+		if name == "" {
+			return nil, fmt.Errorf("invalid path %q", path)
+		}
+		keys = append(keys, name)
+		for rest != "" {
+			index, after, ok := strings.Cut(rest, "]")
+			n, err := strconv.Atoi(index)
+			if !ok || err != nil || n < 0 || (after != "" && after[0] != '[') {
+				return nil, fmt.Errorf("invalid path %q", path)
+			}
+			keys = append(keys, n)
+			rest = strings.TrimPrefix(after, "[")
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,115 @@
+package watcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const reviewEventJSON = `{"id":"42","type":"PullRequestReviewEvent","actor":{"id":1,"login":"alice"},"repo":{"id":2,"name":"org/api"},
+	"payload":{"action":"created","review":{"id":7,"state":"approved","body":"LGTM","user":{"login":"alice"},"submitted_at":"2024-01-15T10:00:00Z"},
+	"pull_request":{"number":12,"title":"Add cache","head":{"ref":"cache"},"labels":[{"name":"perf","color":"ff0000"}]}},
+	"created_at":"2024-01-15T10:00:00Z"}`
+
+func TestRawPayloadRoundTrip(t *testing.T) {
+	var e Event
+	if err := json.Unmarshal([]byte(reviewEventJSON), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Payload.PullRequest == nil || e.Payload.PullRequest.Number != 12 {
+		t.Fatalf("typed payload not decoded: %+v", e.Payload)
+	}
+
+	out, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"review":{"id":7`, `"head":{"ref":"cache"}`, `"color":"ff0000"`, `"id":"42"`} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("encoded event lost %s: %s", want, out)
+		}
+	}
+	if strings.Contains(string(out), "RawPayload") {
+		t.Errorf("raw payload encoded as a field: %s", out)
+	}
+
+	// Encoding compacts the payload but keeps everything in it
+	var compact bytes.Buffer
+	json.Compact(&compact, e.RawPayload)
+	var again Event
+	if err := json.Unmarshal(out, &again); err != nil || string(again.RawPayload) != compact.String() {
+		t.Errorf("raw payload not preserved: %v %s", err, again.RawPayload)
+	}
+}
+
+func TestTypedPayloads(t *testing.T) {
+	var e Event
+	if err := json.Unmarshal([]byte(reviewEventJSON), &e); err != nil {
+		t.Fatal(err)
+	}
+	review, err := e.ReviewPayload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if review.Review.State != "approved" || review.Review.User.Login != "alice" || review.PullRequest.Title != "Add cache" {
+		t.Errorf("unexpected review payload: %+v", review)
+	}
+	if _, err := e.PushPayload(); err == nil || !strings.Contains(err.Error(), "PushEvent") {
+		t.Errorf("PushPayload on a review event: %v", err)
+	}
+
+	// Events without a raw payload decode from the typed one
+	built := FromIssue("org/api", Issue{Number: 3, Title: "Crash", Labels: []Label{{Name: "bug"}}})
+	issues, err := built.IssuesPayload()
+	if err != nil || issues.Issue.Title != "Crash" || issues.Action != "opened" {
+		t.Errorf("IssuesPayload = %+v, %v", issues, err)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	var e Event
+	if err := json.Unmarshal([]byte(reviewEventJSON), &e); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want any
+		ok   bool
+	}{
+		{"payload.review.state", "approved", true},
+		{"payload.pull_request.labels[0].color", "ff0000", true},
+		{"payload.review.id", json.Number("7"), true},
+		{"payload.pull_request.labels[1].name", nil, false},
+		{"payload.review.state.x", nil, false},
+		{"actor.login", "alice", true},
+		{"payload.labels[x]", nil, false},
+		{"payload..state", nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := e.Lookup(tt.path)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("Lookup(%q) = %v, %v; want %v, %v", tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLookupDecodesOnce(t *testing.T) {
+	var e Event
+	if err := json.Unmarshal([]byte(reviewEventJSON), &e); err != nil {
+		t.Fatal(err)
+	}
+	copied := e
+	if _, ok := copied.Lookup("payload.review.state"); !ok {
+		t.Fatal("lookup failed")
+	}
+	if e.payload == nil || e.payload.value == nil {
+		t.Fatal("copies of the event do not share the decoded payload")
+	}
+	first, _ := e.Lookup("payload.review")
+	second, _ := e.Lookup("payload.review")
+	if fmt.Sprintf("%p", first) != fmt.Sprintf("%p", second) {
+		t.Error("payload was decoded again")
+	}
+}
@@ -3,9 +3,12 @@ package watcher
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os/exec"
+	"log"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -13,6 +16,12 @@ import (
 type PollerConfig struct {
 	Repo     string
 	Interval time.Duration
+	// EmitInitial delivers the events already on the first page instead of only newer ones
+	EmitInitial bool
+	// Metrics records poll activity when set
+	Metrics *PollerMetrics
+	// Transport performs the requests; nil uses GH
+	Transport Transport
 }
 
 // DefaultInterval is the default polling interval
@@ -23,6 +32,9 @@ type Poller struct {
 	config    PollerConfig
 	etag      string
 	lastSeenID string
+
+	mu     sync.Mutex
+	status PollerStatus
 }
 
 // NewPoller creates a new Poller with the given configuration
@@ -30,7 +42,7 @@ func NewPoller(config PollerConfig) *Poller {
 	if config.Interval == 0 {
 		config.Interval = DefaultInterval
 	}
-	return &Poller{config: config}
+	return &Poller{config: config, status: PollerStatus{Repo: config.Repo, Interval: config.Interval, RateLimitRemaining: -1}}
 }
 
 // PollResult represents the result of a poll
@@ -38,6 +50,8 @@ type PollResult struct {
 	Events     []Event
 	NotModified bool
 	Error      error
+	// RateLimitRemaining is the remaining API quota, or -1 when unknown
+	RateLimitRemaining int
 }
 
 // Start begins polling and sends events to the provided channel
@@ -45,6 +59,10 @@ func (p *Poller) Start(ctx context.Context, eventCh chan<- Event) error {
 	ticker := time.NewTicker(p.config.Interval)
 	defer ticker.Stop()
 
+	p.mu.Lock()
+	p.status.Started = time.Now()
+	p.mu.Unlock()
+
 	// Initial fetch
 	if err := p.poll(eventCh); err != nil {
 		return fmt.Errorf("initial poll failed: %w", err)
@@ -57,26 +75,37 @@ func (p *Poller) Start(ctx context.Context, eventCh chan<- Event) error {
 		case <-ticker.C:
 			if err := p.poll(eventCh); err != nil {
 				// Log error but continue polling
-				fmt.Printf("poll error: %v\n", err)
+				log.Printf("poll error: %v", err)
 			}
 		}
 	}
 }
 
 // poll fetches events and sends new ones to the channel
-func (p *Poller) poll(eventCh chan<- Event) error {
+func (p *Poller) poll(eventCh chan<- Event) (err error) {
+	m := p.config.Metrics
+	start := time.Now()
 	result := p.fetchWithETag()
+	defer func() { p.record(result.RateLimitRemaining, err) }()
+	m.observeRateLimit(result.RateLimitRemaining)
 	if result.Error != nil {
+		m.observePoll(p.config.Repo, PollError, time.Since(start))
 		return result.Error
 	}
 
 	if result.NotModified {
+		m.observePoll(p.config.Repo, PollNotModified, time.Since(start))
 		return nil
 	}
+	m.observePoll(p.config.Repo, PollOK, time.Since(start))
 
 	// Send new events (in reverse order, oldest first)
 	newEvents := p.filterNewEvents(result.Events)
+	if p.lastSeenID != "" && len(result.Events) > 0 && len(newEvents) == len(result.Events) {
+		m.observeGap(p.config.Repo)
+	}
 	for i := len(newEvents) - 1; i >= 0; i-- {
+		m.observeEvent(newEvents[i])
 		eventCh <- newEvents[i]
 	}
 
@@ -88,29 +117,58 @@ func (p *Poller) poll(eventCh chan<- Event) error {
 	return nil
 }
 
+// record updates the status after a poll attempt
+func (p *Poller) record(rateLimitRemaining int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	p.status.LastPoll = now
+	p.status.NextPoll = now.Add(p.config.Interval)
+	p.status.ETag = p.etag
+	p.status.LastEventID = p.lastSeenID
+	if rateLimitRemaining >= 0 {
+		p.status.RateLimitRemaining = rateLimitRemaining
+	}
+	if err != nil {
+		p.status.ErrorStreak++
+		p.status.LastError = err.Error()
+		return
+	}
+	p.status.LastSuccess = now
+	p.status.ErrorStreak = 0
+	p.status.LastError = ""
+}
+
+// Status returns a snapshot of the poller's state
+func (p *Poller) Status() PollerStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.status
+}
+
 // fetchWithETag fetches events using ETag for caching
 func (p *Poller) fetchWithETag() PollResult {
-	args := []string{"api", fmt.Sprintf("repos/%s/events", p.config.Repo), "-i"}
-	if p.etag != "" {
-		args = append(args, "-H", fmt.Sprintf("If-None-Match: %s", p.etag))
+	transport := p.config.Transport
+	if transport == nil {
+		transport = GH
 	}
+	return p.parseExchange(transport(fmt.Sprintf("repos/%s/events", p.config.Repo), p.etag))
+}
 
-	cmd := exec.Command("gh", args...)
-	output, err := cmd.Output()
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			// Check if 304 Not Modified
-			if strings.Contains(string(output), "304") || strings.Contains(string(exitErr.Stderr), "304") {
-				return PollResult{NotModified: true}
-			}
-			return PollResult{Error: fmt.Errorf("gh api failed: %s", string(exitErr.Stderr))}
+// parseExchange turns the raw response to an events request into a poll result
+func (p *Poller) parseExchange(x Exchange) PollResult {
+	// Parse response (includes headers)
+	headers, body := parseResponse([]byte(x.Output))
+	remaining := rateLimitRemaining(headers)
+	if x.Error != "" {
+		// gh exits non-zero on 304 Not Modified
+		if strings.Contains(x.Output, "304") || strings.Contains(x.Error, "304") {
+			return PollResult{NotModified: true, RateLimitRemaining: remaining}
 		}
-		return PollResult{Error: fmt.Errorf("failed to execute gh command: %w", err)}
+		return PollResult{Error: errors.New(x.Error), RateLimitRemaining: -1}
 	}
 
-	// Parse response (includes headers)
-	headers, body := parseResponse(output)
-
 	// Update ETag
 	if etag := headers["etag"]; etag != "" {
 		p.etag = etag
@@ -118,15 +176,24 @@ func (p *Poller) fetchWithETag() PollResult {
 
 	// Check for 304 in headers
 	if strings.Contains(headers["status"], "304") {
-		return PollResult{NotModified: true}
+		return PollResult{NotModified: true, RateLimitRemaining: remaining}
 	}
 
 	var events []Event
 	if err := json.Unmarshal([]byte(body), &events); err != nil {
-		return PollResult{Error: fmt.Errorf("failed to parse events: %w", err)}
+		return PollResult{Error: fmt.Errorf("failed to parse events: %w", err), RateLimitRemaining: remaining}
 	}
 
-	return PollResult{Events: events}
+	return PollResult{Events: events, RateLimitRemaining: remaining}
+}
+
+// rateLimitRemaining reads the X-RateLimit-Remaining header, returning -1 when it is absent
+func rateLimitRemaining(headers map[string]string) int {
+	n, err := strconv.Atoi(headers["x-ratelimit-remaining"])
+	if err != nil {
+		return -1
+	}
+	return n
 }
 
 // parseResponse splits headers and body from gh api -i output
@@ -164,6 +231,9 @@ func parseResponse(output []byte) (map[string]string, string) {
 // filterNewEvents returns only events newer than the last seen
 func (p *Poller) filterNewEvents(events []Event) []Event {
 	if p.lastSeenID == "" {
+		if p.config.EmitInitial {
+			return events
+		}
 		return []Event{}
 	}
 
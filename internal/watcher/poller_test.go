@@ -133,6 +133,19 @@ func TestFilterNewEvents(t *testing.T) {
 			t.Errorf("expected 3 events, got %d", len(result))
 		}
 	})
+
+	t.Run("returns initial page when EmitInitial is set", func(t *testing.T) {
+		p := NewPoller(PollerConfig{Repo: "owner/repo", EmitInitial: true})
+		events := []Event{
+			{ID: "3"},
+			{ID: "2"},
+			{ID: "1"},
+		}
+		result := p.filterNewEvents(events)
+		if len(result) != 3 {
+			t.Errorf("expected 3 events on initial poll, got %d", len(result))
+		}
+	})
 }
 
 func TestPollerConfig(t *testing.T) {
@@ -152,3 +165,41 @@ func TestPollerConfig(t *testing.T) {
 		}
 	})
 }
+
+func TestRateLimitRemaining(t *testing.T) {
+	headers, _ := parseResponse([]byte("HTTP/2 200 OK\nX-RateLimit-Remaining: 4987\n\n[]"))
+	if got := rateLimitRemaining(headers); got != 4987 {
+		t.Errorf("expected 4987, got %d", got)
+	}
+	if got := rateLimitRemaining(map[string]string{}); got != -1 {
+		t.Errorf("expected -1 without the header, got %d", got)
+	}
+}
+
+func TestPollerTransport(t *testing.T) {
+	var requests []string
+	responses := []Exchange{
+		{Output: "HTTP/2.0 200 OK\r\nEtag: \"a\"\r\n\r\n[{\"id\":\"2\"},{\"id\":\"1\"}]"},
+		{Output: "HTTP/2.0 304 Not Modified\r\n\r\n"},
+		{Error: "gh api failed: HTTP 502"},
+	}
+	p := NewPoller(PollerConfig{Repo: "org/api", Transport: func(path, etag string) Exchange {
+		requests = append(requests, path+" "+etag)
+		x := responses[0]
+		responses = responses[1:]
+		return x
+	}})
+
+	if _, err := p.FetchOnce(); err != nil {
+		t.Fatal(err)
+	}
+	if events, err := p.FetchOnce(); err != nil || events != nil {
+		t.Errorf("not modified: got %v, %v", events, err)
+	}
+	if _, err := p.FetchOnce(); err == nil || err.Error() != "gh api failed: HTTP 502" {
+		t.Errorf("expected the transport error, got %v", err)
+	}
+	if requests[0] != "repos/org/api/events " || requests[1] != `repos/org/api/events "a"` {
+		t.Errorf("unexpected requests: %v", requests)
+	}
+}
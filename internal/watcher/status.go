@@ -0,0 +1,34 @@
+package watcher
+
+import "time"
+
+// stuckIntervals is how many polling intervals may pass without a poll before a poller counts as stuck
+const stuckIntervals = 3
+
+// PollerStatus is a snapshot of a poller's state
+type PollerStatus struct {
+	Repo        string        `json:"repo"`
+	Interval    time.Duration `json:"interval"`
+	Started     time.Time     `json:"started"`
+	LastPoll    time.Time     `json:"last_poll"`
+	LastSuccess time.Time     `json:"last_success"`
+	NextPoll    time.Time     `json:"next_poll"`
+	ETag        string        `json:"etag"`
+	LastEventID string        `json:"last_event_id"`
+	ErrorStreak int           `json:"error_streak"`
+	LastError   string        `json:"last_error,omitempty"`
+	// RateLimitRemaining is the remaining API quota, or -1 when unknown
+	RateLimitRemaining int `json:"rate_limit_remaining"`
+}
+
+// Stuck reports whether the poller has gone several intervals without completing a poll
+func (s PollerStatus) Stuck(now time.Time) bool {
+	last := s.LastPoll
+	if last.IsZero() {
+		last = s.Started
+	}
+	if last.IsZero() {
+		return false
+	}
+	return now.Sub(last) > stuckIntervals*s.Interval
+}
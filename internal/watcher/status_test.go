@@ -0,0 +1,58 @@
+package watcher
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPollerStatus(t *testing.T) {
+	p := NewPoller(PollerConfig{Repo: "owner/repo", Interval: time.Minute})
+	if s := p.Status(); s.Repo != "owner/repo" || s.RateLimitRemaining != -1 || !s.LastPoll.IsZero() {
+		t.Fatalf("unexpected initial status: %+v", s)
+	}
+
+	p.etag = `"abc"`
+	p.lastSeenID = "42"
+	p.record(4000, nil)
+	s := p.Status()
+	if s.ETag != `"abc"` || s.LastEventID != "42" || s.RateLimitRemaining != 4000 {
+		t.Errorf("unexpected status after success: %+v", s)
+	}
+	if s.LastSuccess.IsZero() || s.NextPoll.Sub(s.LastPoll) != time.Minute {
+		t.Errorf("expected last success and next poll to be set: %+v", s)
+	}
+
+	p.record(-1, errors.New("gh api failed"))
+	p.record(-1, errors.New("gh api failed again"))
+	s = p.Status()
+	if s.ErrorStreak != 2 || s.LastError != "gh api failed again" || s.RateLimitRemaining != 4000 {
+		t.Errorf("unexpected status after errors: %+v", s)
+	}
+
+	p.record(3999, nil)
+	if s := p.Status(); s.ErrorStreak != 0 || s.LastError != "" {
+		t.Errorf("expected a success to reset the error streak: %+v", s)
+	}
+}
+
+func TestPollerStatusStuck(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		status   PollerStatus
+		expected bool
+	}{
+		{"not started", PollerStatus{Interval: time.Minute}, false},
+		{"recent poll", PollerStatus{Interval: time.Minute, LastPoll: now.Add(-2 * time.Minute)}, false},
+		{"old poll", PollerStatus{Interval: time.Minute, LastPoll: now.Add(-4 * time.Minute)}, true},
+		{"first poll hanging", PollerStatus{Interval: time.Minute, Started: now.Add(-5 * time.Minute)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.status.Stuck(now); got != tt.expected {
+				t.Errorf("Stuck() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
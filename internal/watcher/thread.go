@@ -0,0 +1,194 @@
+package watcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseThreadRef parses an issue or pull request reference such as "owner/repo#123"
+func ParseThreadRef(ref string) (string, int, error) {
+	repo, num, ok := strings.Cut(ref, "#")
+	owner, name, slash := strings.Cut(repo, "/")
+	if !ok || !slash || owner == "" || name == "" || strings.Contains(name, "/") {
+		return "", 0, fmt.Errorf("invalid reference %q: want owner/repo#number", ref)
+	}
+	n, err := strconv.Atoi(num)
+	if err != nil || n <= 0 {
+		return "", 0, fmt.Errorf("invalid reference %q: want owner/repo#number", ref)
+	}
+	return repo, n, nil
+}
+
+// FetchThread retrieves the full history of one conversation through gh
+func FetchThread(repo string, number int) ([]Event, error) {
+	return Transport(GH).FetchThread(repo, number)
+}
+
+// Conversation is what the REST API lists for an issue or pull request
+type Conversation struct {
+	Issue    Issue
+	Comments []Comment
+	// Reviews and ReviewComments are only listed for pull requests
+	Reviews        []Review
+	ReviewComments []Comment
+}
+
+// FetchThread retrieves the full history of one conversation from the issues, comments and,
+// for pull requests, reviews APIs and the repository's events feed
+func (t Transport) FetchThread(repo string, number int) ([]Event, error) {
+	var c Conversation
+	if err := t.get(fmt.Sprintf("repos/%s/issues/%d", repo, number), false, &c.Issue); err != nil {
+		return nil, err
+	}
+	if err := t.get(fmt.Sprintf("repos/%s/issues/%d/comments?per_page=100", repo, number), true, &c.Comments); err != nil {
+		return nil, err
+	}
+	if c.Issue.IsPullRequest() {
+		if err := t.get(fmt.Sprintf("repos/%s/pulls/%d/reviews?per_page=100", repo, number), true, &c.Reviews); err != nil {
+			return nil, err
+		}
+		if err := t.get(fmt.Sprintf("repos/%s/pulls/%d/comments?per_page=100", repo, number), true, &c.ReviewComments); err != nil {
+			return nil, err
+		}
+	}
+	var events []Event
+	if err := t.get(fmt.Sprintf("repos/%s/events?per_page=100", repo), true, &events); err != nil {
+		return nil, err
+	}
+	return BuildThread(repo, c, events), nil
+}
+
+// BuildThread merges a conversation and the feed events that belong to it into one
+// chronological history. Feed events already covered by the API are dropped.
+func BuildThread(repo string, c Conversation, events []Event) []Event {
+	issue := c.Issue
+	thread := []Event{FromIssue(repo, issue)}
+	// seen holds the comments and reviews listed by the API, by event type and ID
+	seen := map[string]bool{}
+	for _, comment := range c.Comments {
+		seen["IssueCommentEvent:"+strconv.Itoa(comment.ID)] = true
+		thread = append(thread, FromComment(repo, issue, comment))
+	}
+	for _, r := range c.Reviews {
+		// Pending reviews have not been submitted yet
+		if r.SubmittedAt.IsZero() {
+			continue
+		}
+		seen["PullRequestReviewEvent:"+strconv.Itoa(r.ID)] = true
+		thread = append(thread, FromReview(repo, issue, r))
+	}
+	for _, comment := range c.ReviewComments {
+		seen["PullRequestReviewCommentEvent:"+strconv.Itoa(comment.ID)] = true
+		thread = append(thread, FromReviewComment(repo, issue, comment))
+	}
+	for _, e := range events {
+		if e.Repo.Name != repo || e.Number() != issue.Number || e.Payload.Action == "opened" {
+			continue
+		}
+		if seen[e.Type+":"+strconv.Itoa(feedID(e))] {
+			continue
+		}
+		thread = append(thread, e)
+	}
+
+	sort.SliceStable(thread, func(i, j int) bool {
+		return thread[i].CreatedAt.Before(thread[j].CreatedAt)
+	})
+	return thread
+}
+
+// feedID returns the ID of the comment or review a feed event carries, or zero
+func feedID(e Event) int {
+	if e.Type == "PullRequestReviewEvent" {
+		if p, err := e.ReviewPayload(); err == nil {
+			return p.Review.ID
+		}
+		return 0
+	}
+	if e.Payload.Comment != nil {
+		return e.Payload.Comment.ID
+	}
+	return 0
+}
+
+// get requests an API path through the transport and decodes the response into v. With
+// paginate the pages named by the Link header are requested too, and their arrays concatenated.
+func (t Transport) get(path string, paginate bool, v any) error {
+	var pages bytes.Buffer
+	for next := path; next != ""; {
+		x := t(next, "")
+		if x.Error != "" {
+			return errors.New(strings.TrimSpace(x.Error))
+		}
+		headers, body := parseResponse([]byte(x.Output))
+		pages.WriteString(body)
+		pages.WriteByte('\n')
+		next = ""
+		if paginate {
+			next = nextPage(headers["link"])
+		}
+	}
+	if err := decodePages(pages.Bytes(), v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// nextPage returns the API path of the rel="next" link in a Link header, or ""
+func nextPage(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		target, params, ok := strings.Cut(part, ";")
+		if !ok || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+		target = strings.Trim(strings.TrimSpace(target), "<>")
+		if i := strings.Index(target, "://"); i >= 0 {
+			// Drop the scheme and host: gh takes the path relative to the API root
+			target = target[i+3:]
+			if j := strings.Index(target, "/"); j >= 0 {
+				return target[j+1:]
+			}
+			return ""
+		}
+		return strings.TrimPrefix(target, "/")
+	}
+	return ""
+}
+
+// decodePages decodes one JSON value, or a sequence of arrays into a slice
+func decodePages(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var pages []json.RawMessage
+	for {
+		var page json.RawMessage
+		if err := dec.Decode(&page); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+		pages = append(pages, page)
+	}
+	if len(pages) == 1 {
+		return json.Unmarshal(pages[0], v)
+	}
+
+	var all []json.RawMessage
+	for _, page := range pages {
+		var items []json.RawMessage
+		if err := json.Unmarshal(page, &items); err != nil {
+			return err
+		}
+		all = append(all, items...)
+	}
+	merged, err := json.Marshal(all)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(merged, v)
+}
@@ -0,0 +1,115 @@
+package watcher
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseThreadRef(t *testing.T) {
+	repo, n, err := ParseThreadRef("owner/repo#123")
+	if err != nil || repo != "owner/repo" || n != 123 {
+		t.Errorf("ParseThreadRef = %q, %d, %v", repo, n, err)
+	}
+	for _, ref := range []string{"owner/repo", "repo#1", "owner/repo#x", "owner/repo#0", "a/b/c#1", "/repo#1"} {
+		if _, _, err := ParseThreadRef(ref); err == nil {
+			t.Errorf("ParseThreadRef(%q) succeeded", ref)
+		}
+	}
+}
+
+func TestBuildThread(t *testing.T) {
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	issue := Issue{
+		Number:      12,
+		Title:       "Fix the parser",
+		Body:        "It breaks.",
+		User:        Actor{Login: "alice"},
+		CreatedAt:   base,
+		PullRequest: &PullRequestLink{HTMLURL: "https://github.com/owner/repo/pull/12"},
+	}
+	comments := []Comment{
+		{ID: 2, Body: "LGTM", User: Actor{Login: "bob"}, CreatedAt: base.Add(2 * time.Minute)},
+	}
+	events := []Event{
+		{ID: "e1", Type: "PullRequestEvent", Repo: Repo{Name: "owner/repo"}, CreatedAt: base,
+			Payload: Payload{Action: "opened", PullRequest: &PullRequest{Number: 12}}},
+		{ID: "e2", Type: "IssueCommentEvent", Repo: Repo{Name: "owner/repo"}, CreatedAt: base.Add(2 * time.Minute),
+			Payload: Payload{Action: "created", Issue: &Issue{Number: 12}, Comment: &Comment{ID: 2}}},
+		{ID: "e3", Type: "PullRequestEvent", Repo: Repo{Name: "owner/repo"}, CreatedAt: base.Add(3 * time.Minute),
+			Payload: Payload{Action: "closed", PullRequest: &PullRequest{Number: 12, Merged: true}}},
+		{ID: "e4", Type: "PullRequestReviewCommentEvent", Repo: Repo{Name: "owner/repo"}, CreatedAt: base.Add(time.Minute),
+			Payload: Payload{Action: "created", PullRequest: &PullRequest{Number: 12}, Comment: &Comment{ID: 9}}},
+		{ID: "e5", Type: "IssuesEvent", Repo: Repo{Name: "owner/repo"}, CreatedAt: base.Add(time.Minute),
+			Payload: Payload{Action: "closed", Issue: &Issue{Number: 13}}},
+	}
+
+	reviews := []Review{
+		{ID: 4, State: "APPROVED", User: Actor{Login: "carol"}, SubmittedAt: base.Add(150 * time.Second)},
+		{ID: 5, State: "PENDING", User: Actor{Login: "dave"}},
+	}
+	reviewComments := []Comment{{ID: 9, Body: "Nit", User: Actor{Login: "carol"}, CreatedAt: base.Add(time.Minute)}}
+	events = append(events, Event{ID: "e6", Type: "PullRequestReviewEvent", Repo: Repo{Name: "owner/repo"}, CreatedAt: base.Add(150 * time.Second),
+		Payload: Payload{Action: "created", PullRequest: &PullRequest{Number: 12}}, RawPayload: []byte(`{"action":"created","review":{"id":4}}`)})
+
+	thread := BuildThread("owner/repo", Conversation{Issue: issue, Comments: comments, Reviews: reviews, ReviewComments: reviewComments}, events)
+	var got []string
+	for _, e := range thread {
+		got = append(got, e.Type+":"+e.Payload.Action)
+	}
+	want := []string{"PullRequestEvent:opened", "PullRequestReviewCommentEvent:created", "IssueCommentEvent:created", "PullRequestReviewEvent:created", "PullRequestEvent:closed"}
+	if len(got) != len(want) {
+		t.Fatalf("thread = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("thread[%d] = %s, want %s", i, got[i], want[i])
+		}
+	}
+	if thread[0].Body() != "It breaks." || thread[2].Body() != "LGTM" || thread[2].Title() != "Fix the parser" {
+		t.Errorf("unexpected bodies: %q / %q", thread[0].Body(), thread[2].Body())
+	}
+	if thread[1].Body() != "Nit" || thread[3].Actor.Login != "carol" {
+		t.Errorf("review comment %q, review by %s", thread[1].Body(), thread[3].Actor.Login)
+	}
+}
+
+func TestTransportGet(t *testing.T) {
+	pages := map[string]string{
+		"repos/o/r/issues/1/comments?per_page=100":             "HTTP/2.0 200 OK\r\nLink: <https://api.github.com/repositories/1/issues/1/comments?per_page=100&page=2>; rel=\"next\", <https://api.github.com/repositories/1/issues/1/comments?per_page=100&page=2>; rel=\"last\"\r\n\r\n" + `[{"id":1},{"id":2}]`,
+		"repositories/1/issues/1/comments?per_page=100&page=2": "HTTP/2.0 200 OK\r\n\r\n" + `[{"id":3}]`,
+	}
+	var asked []string
+	transport := Transport(func(path, etag string) Exchange {
+		asked = append(asked, path)
+		if out, ok := pages[path]; ok {
+			return Exchange{Path: path, Output: out}
+		}
+		return Exchange{Path: path, Error: "gh api failed: HTTP 404"}
+	})
+
+	var comments []Comment
+	if err := transport.get("repos/o/r/issues/1/comments?per_page=100", true, &comments); err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 3 || comments[2].ID != 3 || len(asked) != 2 {
+		t.Errorf("comments = %+v after %v", comments, asked)
+	}
+	if err := transport.get("repos/o/r/issues/2", false, &Issue{}); err == nil || err.Error() != "gh api failed: HTTP 404" {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestDecodePages(t *testing.T) {
+	var items []Comment
+	if err := decodePages([]byte(`[{"id":1},{"id":2}]`+"\n"+`[{"id":3}]`), &items); err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 || items[2].ID != 3 {
+		t.Errorf("unexpected items: %+v", items)
+	}
+
+	var issue Issue
+	if err := decodePages([]byte(`{"number":5}`), &issue); err != nil || issue.Number != 5 {
+		t.Errorf("single object: %+v, %v", issue, err)
+	}
+}
@@ -0,0 +1,85 @@
+package watcher
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Exchange is one API request and its raw response, as recorded and replayed
+type Exchange struct {
+	Time time.Time `json:"time"`
+	Path string    `json:"path"`
+	// ETag was sent as If-None-Match
+	ETag string `json:"etag,omitempty"`
+	// Output is the response as printed by gh api -i: status line, headers and body
+	Output string `json:"output"`
+	// Error describes a failed request
+	Error string `json:"error,omitempty"`
+}
+
+// Header returns a response header by case-insensitive name; "status" is the status line
+func (x Exchange) Header(name string) string {
+	headers, _ := parseResponse([]byte(x.Output))
+	return headers[strings.ToLower(name)]
+}
+
+// NotModified reports whether the response is 304 Not Modified
+func (x Exchange) NotModified() bool {
+	return strings.Contains(x.Header("status"), "304") || (x.Error != "" && strings.Contains(x.Error, "304"))
+}
+
+// Transport performs an API request for a path, sending etag as If-None-Match when set
+type Transport func(path, etag string) Exchange
+
+// GH performs requests through the gh command
+func GH(path, etag string) Exchange {
+	x := Exchange{Time: time.Now().UTC(), Path: path, ETag: etag}
+	args := []string{"api", path, "-i"}
+	if etag != "" {
+		args = append(args, "-H", fmt.Sprintf("If-None-Match: %s", etag))
+	}
+	output, err := exec.Command("gh", args...).Output()
+	x.Output = string(output)
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			x.Error = "gh api failed: " + string(exitErr.Stderr)
+		} else {
+			x.Error = fmt.Sprintf("failed to execute gh command: %v", err)
+		}
+	}
+	return x
+}
+
+// Response is the result of a conditional API request
+type Response struct {
+	Body []byte
+	// ETag identifies this version of the resource for the next request
+	ETag string
+	// NotModified is set when the resource still matches the ETag sent, leaving Body empty
+	NotModified bool
+}
+
+// Fetch performs a conditional request. Requests answered with 304 Not Modified do not
+// count against the rate limit.
+func (t Transport) Fetch(path, etag string) (Response, error) {
+	x := t(path, etag)
+	headers, body := parseResponse([]byte(x.Output))
+	if x.NotModified() {
+		return Response{ETag: etag, NotModified: true}, nil
+	}
+	if x.Error != "" {
+		return Response{}, errors.New(strings.TrimSpace(x.Error))
+	}
+	if headers["etag"] != "" {
+		etag = headers["etag"]
+	}
+	return Response{Body: []byte(body), ETag: etag}, nil
+}
+
+// FetchConditional performs a conditional request through gh
+func FetchConditional(path, etag string) (Response, error) {
+	return Transport(GH).Fetch(path, etag)
+}
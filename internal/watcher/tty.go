@@ -0,0 +1,149 @@
+package watcher
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ytnobody/ghostio/internal/markdown"
+)
+
+// DefaultBodyLines is how many body lines terminal output shows before truncating
+const DefaultBodyLines = 10
+
+// TTYOptions configures FormatTTY
+type TTYOptions struct {
+	// Width wraps output to this many columns; zero does not wrap
+	Width int
+	// BodyLines truncates bodies to this many lines; zero shows them in full
+	BodyLines int
+	// Highlight marks every case-insensitive occurrence of these fragments
+	Highlight []string
+}
+
+// ANSI colors used by FormatTTY
+const (
+	ttyReset  = "\x1b[0m"
+	ttyBold   = "\x1b[1m"
+	ttyDim    = "\x1b[2m"
+	ttyRed    = "\x1b[31m"
+	ttyGreen  = "\x1b[32m"
+	ttyYellow = "\x1b[33m"
+	ttyBlue   = "\x1b[34m"
+	ttyPurple = "\x1b[35m"
+	ttyMark   = "\x1b[1;7m"
+)
+
+// ttyIndent is the width of the icon column; the URL and body line up after it
+const ttyIndent = "  "
+
+// FormatTTY formats an event for a color terminal: an icon column, the headline colored by
+// event type and action, and the body rendered from markdown, wrapped to the terminal width
+// and truncated.
+func FormatTTY(e Event, o TTYOptions) string {
+	headline, rest, _ := strings.Cut(FormatEvent(e), "\n")
+	icon, color := eventStyle(e)
+	// Matches are marked in the plain text, before styling adds escapes they could land in
+	mark := highlighter(o.Highlight)
+	headline = mark(headline, color+ttyBold)
+	width := o.Width
+	if width > 0 {
+		width = max(width-len(ttyIndent), 20)
+	}
+
+	var b strings.Builder
+	for i, line := range markdown.Wrap(headline, width) {
+		if i == 0 {
+			b.WriteString(color + icon + ttyReset + " ")
+			if ts, after, ok := strings.Cut(line, "] "); ok && strings.HasPrefix(ts, "[") {
+				b.WriteString(ttyDim + ts + "]" + ttyReset + " ")
+				line = after
+			}
+		} else {
+			b.WriteString("\n" + ttyIndent)
+		}
+		b.WriteString(color + ttyBold + line + ttyReset)
+	}
+
+	// The rest is the URL and details followed by a blank line and the body
+	info, body, _ := strings.Cut(rest, "\n\n")
+	if info = strings.TrimSpace(info); info != "" {
+		for _, line := range markdown.Wrap(mark(info, ttyDim), width) {
+			b.WriteString("\n" + ttyIndent + ttyDim + line + ttyReset)
+		}
+	}
+	if body = strings.TrimSpace(body); body != "" {
+		// The plain format shows the body as text; render it again with styling and wrapping
+		source := e.Body()
+		switch {
+		case e.Summary != "":
+			source = summaryBody(e)
+		case e.Type == "PullRequestReviewEvent":
+			source = reviewBody(e)
+		}
+		lines := strings.Split(markdown.Render(mark(source, ""), markdown.Options{Width: width, Color: true}), "\n")
+		hidden := 0
+		if o.BodyLines > 0 && len(lines) > o.BodyLines {
+			hidden = len(lines) - o.BodyLines
+			lines = lines[:o.BodyLines]
+		}
+		b.WriteString("\n")
+		for _, line := range lines {
+			b.WriteString("\n" + strings.TrimRight(ttyIndent+line, " "))
+		}
+		if hidden > 0 {
+			fmt.Fprintf(&b, "\n%s%s… (+%d lines)%s", ttyIndent, ttyDim, hidden, ttyReset)
+		}
+	}
+	return b.String()
+}
+
+// highlighter returns a function that marks every case-insensitive occurrence of the
+// fragments in text, restoring style after each match
+func highlighter(fragments []string) func(text, style string) string {
+	var quoted []string
+	for _, f := range fragments {
+		if f != "" {
+			quoted = append(quoted, regexp.QuoteMeta(f))
+		}
+	}
+	if len(quoted) == 0 {
+		return func(text, _ string) string { return text }
+	}
+	// Longest first so that overlapping fragments highlight the full match
+	sort.Slice(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+	re := regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+	return func(text, style string) string {
+		return re.ReplaceAllStringFunc(text, func(m string) string {
+			return ttyMark + m + ttyReset + style
+		})
+	}
+}
+
+// eventStyle returns the icon and color of an event: green for opened, purple for merged,
+// red for closed
+func eventStyle(e Event) (string, string) {
+	icon := "·"
+	switch e.Type {
+	case "IssuesEvent":
+		icon = "●"
+	case "PullRequestEvent":
+		icon = "⇄"
+	case "IssueCommentEvent", "PullRequestReviewCommentEvent", "PullRequestReviewEvent":
+		return "✎", ttyBlue
+	case "ReleaseEvent":
+		return "★", ttyYellow
+	}
+
+	switch e.Payload.Action {
+	case "opened", "reopened", "created", "published":
+		return icon, ttyGreen
+	case "closed":
+		if e.Payload.PullRequest != nil && e.Payload.PullRequest.Merged {
+			return icon, ttyPurple
+		}
+		return icon, ttyRed
+	}
+	return icon, ""
+}
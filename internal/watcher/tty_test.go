@@ -0,0 +1,66 @@
+package watcher
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+func TestFormatTTY(t *testing.T) {
+	body := strings.Repeat("line\n", 14) + "last"
+	e := Event{
+		Type:      "PullRequestEvent",
+		Actor:     Actor{Login: "alice"},
+		CreatedAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Payload: Payload{Action: "closed", PullRequest: &PullRequest{
+			Number: 12, Title: "Fix the parser", Body: body, Merged: true, HTMLURL: "https://github.com/o/r/pull/12",
+		}},
+	}
+
+	got := FormatTTY(e, TTYOptions{Width: 40, BodyLines: 3})
+	if !strings.HasPrefix(got, ttyPurple+"⇄"+ttyReset) {
+		t.Errorf("merged PR should get a purple icon: %q", got)
+	}
+	plain := ansiEscape.ReplaceAllString(got, "")
+	want := strings.Join([]string{
+		"⇄ [2024-05-01 10:00:00] PR merged: #12",
+		"  \"Fix the parser\" by @alice",
+		"  https://github.com/o/r/pull/12",
+		"",
+		"  line",
+		"  line",
+		"  line",
+		"  … (+12 lines)",
+	}, "\n")
+	if plain != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", plain, want)
+	}
+
+	if full := FormatTTY(e, TTYOptions{}); strings.Contains(full, "(+") || !strings.Contains(full, "last") {
+		t.Error("zero BodyLines should show the whole body")
+	}
+}
+
+func TestEventStyle(t *testing.T) {
+	tests := []struct {
+		event Event
+		icon  string
+		color string
+	}{
+		{Event{Type: "IssuesEvent", Payload: Payload{Action: "opened"}}, "●", ttyGreen},
+		{Event{Type: "IssuesEvent", Payload: Payload{Action: "closed"}}, "●", ttyRed},
+		{Event{Type: "PullRequestEvent", Payload: Payload{Action: "closed", PullRequest: &PullRequest{}}}, "⇄", ttyRed},
+		{Event{Type: "IssueCommentEvent", Payload: Payload{Action: "created"}}, "✎", ttyBlue},
+		{Event{Type: "PullRequestReviewEvent", Payload: Payload{Action: "created"}}, "✎", ttyBlue},
+		{Event{Type: "PushEvent"}, "·", ""},
+	}
+	for _, tt := range tests {
+		icon, color := eventStyle(tt.event)
+		if icon != tt.icon || color != tt.color {
+			t.Errorf("eventStyle(%s %s) = %q %q, want %q %q", tt.event.Type, tt.event.Payload.Action, icon, color, tt.icon, tt.color)
+		}
+	}
+}